@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+)
+
+// * repeatable --arg key=value
+type argFlag map[string]string
+
+func (a argFlag) String() string {
+	pairs := make([]string, 0, len(a))
+	for k, v := range a {
+		pairs = append(pairs, k+"="+v)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (a argFlag) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	a[key] = val
+	return nil
+}
+
+// * generation params for run, unset flags keep the model entry and skill values
+func paramsFlags(fs *flag.FlagSet) *agentTypes.ParamsData {
+	params := &agentTypes.ParamsData{}
+	fs.Func("temperature", "sampling temperature, 0 to 2", func(value string) error {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		params.Temperature = &f
+		return nil
+	})
+	fs.Func("top-p", "nucleus sampling, 0 to 1", func(value string) error {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		params.TopP = &f
+		return nil
+	})
+	fs.Func("max-tokens", "maximum output tokens per response", func(value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		params.MaxTokens = n
+		return nil
+	})
+	fs.Func("seed", "sampling seed, ignored by providers without one", func(value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		params.Seed = &n
+		return nil
+	})
+	fs.Func("stop", "stop sequence, repeatable", func(value string) error {
+		params.Stop = append(params.Stop, value)
+		return nil
+	})
+	fs.StringVar(&params.ReasoningEffort, "reasoning", "", "reasoning effort: low, medium or high")
+	fs.IntVar(&params.ReasoningBudget, "reasoning-budget", 0, "reasoning token budget, over --reasoning where supported")
+	return params
+}
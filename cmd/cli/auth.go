@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	providerConfig "github.com/pardnchiu/agenvoy/internal/agents/provider/config"
+	"github.com/pardnchiu/agenvoy/internal/agents/provider/copilot"
+	"github.com/pardnchiu/agenvoy/internal/credential"
+
+	"github.com/manifoldco/promptui"
+)
+
+// * env var each provider type falls back to, copilot only has its device login
+var authEnv = map[string]string{
+	"openai":  "OPENAI_API_KEY",
+	"claude":  "ANTHROPIC_API_KEY",
+	"gemini":  "GEMINI_API_KEY",
+	"nvidia":  "NVIDIA_API_KEY",
+	"compat":  "COMPAT_API_KEY",
+	"azure":   "AZURE_OPENAI_API_KEY",
+	"bedrock": "AWS_BEARER_TOKEN_BEDROCK",
+	"vertex":  "VERTEX_ACCESS_TOKEN",
+	"copilot": "",
+}
+
+func runAuth(args []string) error {
+	if len(args) < 1 {
+		printAuthUsage()
+		return fmt.Errorf("missing subcommand")
+	}
+
+	switch args[0] {
+	case "login":
+		fs := flag.NewFlagSet("auth login", flag.ContinueOnError)
+		if err := fs.Parse(reorderArgs(fs, args[1:])); err != nil {
+			return err
+		}
+		if fs.NArg() < 1 {
+			printAuthUsage()
+			return fmt.Errorf("missing name")
+		}
+		name := fs.Arg(0)
+
+		// * copilot signs in with the device flow, the old token stays until the new one is stored
+		if providerConfig.TypeOf(name) == "copilot" {
+			if err := copilot.SignIn(name); err != nil {
+				return fmt.Errorf("copilot.SignIn: %w", err)
+			}
+			printOk("Login", name)
+			return nil
+		}
+
+		secret, err := readSecret(name)
+		if err != nil {
+			return fmt.Errorf("readSecret: %w", err)
+		}
+		if secret == "" {
+			return fmt.Errorf("empty secret")
+		}
+		if err := credential.Set(name, secret); err != nil {
+			return fmt.Errorf("credential.Set: %w", err)
+		}
+		printOk("Login", name)
+		return nil
+
+	case "logout":
+		if len(args) < 2 {
+			printAuthUsage()
+			return fmt.Errorf("missing name")
+		}
+		removed, err := credential.Delete(args[1])
+		if err != nil {
+			return fmt.Errorf("credential.Delete: %w", err)
+		}
+		if !removed {
+			printWarn("Logout", args[1]+" is not stored")
+			return nil
+		}
+		printOk("Logout", args[1])
+		return nil
+
+	case "status":
+		return printAuthStatus()
+
+	default:
+		printAuthUsage()
+		return fmt.Errorf("unknown subcommand: %s", args[0])
+	}
+}
+
+func printAuthUsage() {
+	fmt.Println("Usage:")
+	fmt.Println("  go run cmd/cli/main.go auth login <provider|instance|name>")
+	fmt.Println("  go run cmd/cli/main.go auth logout <provider|instance|name>")
+	fmt.Println("  go run cmd/cli/main.go auth status")
+}
+
+// * masked prompt on a terminal, otherwise the first line of stdin so keys can be piped in
+func readSecret(name string) (string, error) {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return "", fmt.Errorf("os.Stdin.Stat: %w", err)
+	}
+	if info.Mode()&os.ModeCharDevice == 0 {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("read stdin: %w", err)
+		}
+		return strings.TrimSpace(line), nil
+	}
+
+	prompt := promptui.Prompt{
+		Label: name + " secret",
+		Mask:  '*',
+	}
+	secret, err := prompt.Run()
+	if err != nil {
+		return "", fmt.Errorf("prompt.Run: %w", err)
+	}
+	return strings.TrimSpace(secret), nil
+}
+
+// * shows where each provider would take its key from, in the order providers resolve it
+func printAuthStatus() error {
+	status, err := credential.Status()
+	if err != nil {
+		return fmt.Errorf("credential.Status: %w", err)
+	}
+	switch status.KDF {
+	case "":
+		fmt.Printf("Store: %s (empty)\n\n", status.Path)
+	case "pbkdf2-sha256":
+		fmt.Printf("Store: %s (passphrase)\n\n", status.Path)
+	default:
+		fmt.Printf("Store: %s (key file)\n\n", status.Path)
+	}
+
+	stored, err := credential.List()
+	if err != nil {
+		printWarn("Store", err.Error())
+	}
+
+	configured := make(map[string]providerConfig.ProviderData)
+	names := make([]string, 0, len(authEnv))
+	for name := range authEnv {
+		names = append(names, name)
+	}
+	for _, p := range providerConfig.List() {
+		configured[p.Name] = p
+		if !slices.Contains(names, p.Name) {
+			names = append(names, p.Name)
+		}
+	}
+	for _, name := range stored {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		p, ok := configured[name]
+		if !ok {
+			p = providerConfig.ProviderData{Name: name, Type: name}
+		}
+
+		source := "not set"
+		env := authEnv[p.Type]
+		switch {
+		case strings.HasPrefix(p.APIKey, "env:") || strings.HasPrefix(p.APIKey, "keychain:"):
+			source = "config " + p.APIKey
+		case p.APIKey != "":
+			source = "config"
+		case slices.Contains(stored, name):
+			source = "store"
+		case env != "" && os.Getenv(env) != "":
+			source = "env " + env
+		}
+
+		label := name
+		if p.Type != name {
+			label = fmt.Sprintf("%s (%s)", name, p.Type)
+		}
+		fmt.Printf("  %-24s %s\n", label, source)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pardnchiu/agenvoy/internal/agents/provider/cassette"
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+)
+
+var (
+	cassetteMu     sync.Mutex
+	cassetteAgents = map[string]agentTypes.Agent{}
+)
+
+// * AGENVOY_CASSETTE=record|replay wraps every agent for offline testing
+func newAgent(name string, fn func(string) (agentTypes.Agent, error)) (agentTypes.Agent, error) {
+	mode := os.Getenv("AGENVOY_CASSETTE")
+	if mode == "" {
+		return fn(name)
+	}
+
+	dir := os.Getenv("AGENVOY_CASSETTE_DIR")
+	if dir == "" {
+		dir = filepath.Join("testdata", "cassettes")
+	}
+	path := filepath.Join(dir, cassette.FileName(name))
+
+	// * the selector and the registry may use the same model, one file needs one writer
+	cassetteMu.Lock()
+	defer cassetteMu.Unlock()
+	if a, ok := cassetteAgents[path]; ok {
+		return a, nil
+	}
+
+	// * machine specific paths would break the request hash across machines
+	cwd, _ := os.Getwd()
+	home, _ := os.UserHomeDir()
+	scrub := []string{cwd, home}
+
+	var a agentTypes.Agent
+	var err error
+	switch mode {
+	case "replay":
+		a, err = cassette.NewPlayer(path, scrub...)
+	case "record":
+		inner, innerErr := fn(name)
+		if innerErr != nil {
+			return nil, innerErr
+		}
+		a, err = cassette.NewRecorder(inner, path, scrub...)
+	default:
+		return fn(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	cassetteAgents[path] = a
+	return a, nil
+}
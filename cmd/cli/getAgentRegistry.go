@@ -6,25 +6,36 @@ import (
 	"strings"
 
 	"github.com/pardnchiu/agenvoy/internal/agents/exec"
+	"github.com/pardnchiu/agenvoy/internal/agents/provider/azure"
+	"github.com/pardnchiu/agenvoy/internal/agents/provider/bedrock"
 	"github.com/pardnchiu/agenvoy/internal/agents/provider/claude"
 	"github.com/pardnchiu/agenvoy/internal/agents/provider/compat"
+	providerConfig "github.com/pardnchiu/agenvoy/internal/agents/provider/config"
 	"github.com/pardnchiu/agenvoy/internal/agents/provider/copilot"
 	"github.com/pardnchiu/agenvoy/internal/agents/provider/gemini"
+	"github.com/pardnchiu/agenvoy/internal/agents/provider/mock"
 	"github.com/pardnchiu/agenvoy/internal/agents/provider/nvidia"
+	"github.com/pardnchiu/agenvoy/internal/agents/provider/ollama"
 	"github.com/pardnchiu/agenvoy/internal/agents/provider/openai"
+	"github.com/pardnchiu/agenvoy/internal/agents/provider/vertex"
 	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
 )
 
-func getAgentRegistry() agentTypes.AgentRegistry {
-	newFn := map[string]func(string) (agentTypes.Agent, error){
-		"copilot": func(m string) (agentTypes.Agent, error) { return copilot.New(m) },
-		"openai":  func(m string) (agentTypes.Agent, error) { return openai.New(m) },
-		"compat":  func(m string) (agentTypes.Agent, error) { return compat.New(m) },
-		"claude":  func(m string) (agentTypes.Agent, error) { return claude.New(m) },
-		"gemini":  func(m string) (agentTypes.Agent, error) { return gemini.New(m) },
-		"nvidia":  func(m string) (agentTypes.Agent, error) { return nvidia.New(m) },
-	}
+var newFn = map[string]func(string) (agentTypes.Agent, error){
+	"copilot": func(m string) (agentTypes.Agent, error) { return copilot.New(m) },
+	"openai":  func(m string) (agentTypes.Agent, error) { return openai.New(m) },
+	"compat":  func(m string) (agentTypes.Agent, error) { return compat.New(m) },
+	"ollama":  func(m string) (agentTypes.Agent, error) { return ollama.New(m) },
+	"claude":  func(m string) (agentTypes.Agent, error) { return claude.New(m) },
+	"gemini":  func(m string) (agentTypes.Agent, error) { return gemini.New(m) },
+	"nvidia":  func(m string) (agentTypes.Agent, error) { return nvidia.New(m) },
+	"azure":   func(m string) (agentTypes.Agent, error) { return azure.New(m) },
+	"bedrock": func(m string) (agentTypes.Agent, error) { return bedrock.New(m) },
+	"vertex":  func(m string) (agentTypes.Agent, error) { return vertex.New(m) },
+	"mock":    func(m string) (agentTypes.Agent, error) { return mock.New(m) },
+}
 
+func getAgentRegistry() agentTypes.AgentRegistry {
 	agentEntries := exec.GetAgentEntries()
 	// var fallback exec.Agent
 	// registry := make(map[string]exec.Agent, len(agentEntries))
@@ -36,11 +47,12 @@ func getAgentRegistry() agentTypes.AgentRegistry {
 	}
 	for _, e := range agentEntries {
 		provider := strings.SplitN(e.Name, "@", 2)[0]
-		fn, ok := newFn[provider]
+		// * named instances from the providers config map to their type
+		fn, ok := newFn[providerConfig.TypeOf(provider)]
 		if !ok {
 			continue
 		}
-		a, err := fn(e.Name)
+		a, err := newAgent(e.Name, fn)
 		if err != nil {
 			slog.Warn("failed to initialize agent", slog.String("name", e.Name), slog.String("error", err.Error()))
 			continue
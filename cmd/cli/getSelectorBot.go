@@ -0,0 +1,41 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/pardnchiu/agenvoy/internal/agents/exec"
+	providerConfig "github.com/pardnchiu/agenvoy/internal/agents/provider/config"
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+)
+
+// * nil selector means local selection, summary then falls back to the executing agent
+func getSelectorBot() agentTypes.Agent {
+	cfg := exec.GetSelectorConfig()
+	if cfg.Local {
+		return nil
+	}
+
+	bots := make([]agentTypes.Agent, 0, len(cfg.Models))
+	for _, name := range cfg.Models {
+		provider := strings.SplitN(name, "@", 2)[0]
+		// * named instances from the providers config map to their type
+		fn, ok := newFn[providerConfig.TypeOf(provider)]
+		if !ok {
+			slog.Warn("unknown selector provider", slog.String("name", name))
+			continue
+		}
+		a, err := newAgent(name, fn)
+		if err != nil {
+			slog.Warn("failed to initialize selector", slog.String("name", name), slog.String("error", err.Error()))
+			continue
+		}
+		bots = append(bots, a)
+	}
+
+	if len(bots) == 0 {
+		slog.Warn("no selector model available, using local selection")
+		return nil
+	}
+	return exec.NewSelectorChain(bots...)
+}
@@ -2,14 +2,14 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log/slog"
 	"os"
-	"slices"
 	"sort"
+	"strings"
 
 	"github.com/pardnchiu/agenvoy/internal/agents/exec"
-	"github.com/pardnchiu/agenvoy/internal/agents/provider/nvidia"
 	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
 	"github.com/pardnchiu/agenvoy/internal/skill"
 
@@ -26,65 +26,186 @@ func main() {
 	if len(os.Args) < 2 {
 		fmt.Println("Usage:")
 		fmt.Println("  go run cmd/cli/main.go list")
-		fmt.Println("  go run cmd/cli/main.go run <skill_name> <input> [--allow]")
+		fmt.Println("  go run cmd/cli/main.go skill install|update|remove|new ...")
+		fmt.Println("  go run cmd/cli/main.go skills doctor")
+		fmt.Println("  go run cmd/cli/main.go run [--skill <name>[,<name>...] [--arg k=v]... | --no-skill] [--compose merge|stages] [--agent <provider@model>] [--temperature n] [--top-p n] [--max-tokens n] [--seed n] [--stop s]... [--reasoning low|medium|high] [--reasoning-budget n] [--attach file]... [--json-schema file] [--show-reasoning] [--allow] <input>")
+		fmt.Println("  go run cmd/cli/main.go resume [--allow] [--show-reasoning]")
+		fmt.Println("  go run cmd/cli/main.go session export <session_id> [--format md|json|html]")
+		fmt.Println("  go run cmd/cli/main.go session import <file.json>")
+		fmt.Println("  go run cmd/cli/main.go auth login|logout <name> | auth status")
 		os.Exit(1)
 	}
 
+	if os.Args[1] == "auth" {
+		if err := runAuth(os.Args[2:]); err != nil {
+			slog.Error("failed to run auth command", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		return
+	}
+
+	if os.Args[1] == "session" {
+		if err := runSession(os.Args[2:]); err != nil {
+			slog.Error("failed to run session command", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		return
+	}
+
+	if os.Args[1] == "skill" || os.Args[1] == "skills" {
+		if err := runSkills(os.Args[2:]); err != nil {
+			slog.Error("failed to run skills command", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		return
+	}
+
 	if os.Args[1] == "list" {
 		scanner := skill.NewScanner()
+		names := scanner.List()
 
-		if len(scanner.Skills.ByName) == 0 {
+		if len(names) == 0 {
 			fmt.Println("No skills found")
 			fmt.Println("\nScanned paths:")
-			for _, path := range scanner.Skills.Paths {
+			for _, path := range scanner.Snapshot().Paths {
 				fmt.Printf("  - %s\n", path)
 			}
 			return
 		}
 
-		names := scanner.List()
 		sort.Strings(names)
 
 		fmt.Printf("Found %d skill(s):\n\n", len(names))
 		for _, name := range names {
-			s := scanner.Skills.ByName[name]
-			fmt.Printf("• %s\n", name)
+			s, _ := scanner.Get(name)
+			fmt.Printf("• %s (%s)\n", name, s.Scope)
 			if s.Description != "" {
 				fmt.Printf("  %s\n", s.Description)
 			}
+			if s.Version != "" {
+				fmt.Printf("  Version: %s\n", s.Version)
+			}
+			if s.Model != "" {
+				fmt.Printf("  Model: %s\n", s.Model)
+			}
+			if len(s.Tags) > 0 {
+				fmt.Printf("  Tags: %s\n", strings.Join(s.Tags, ", "))
+			}
+			if len(s.AllowedTools) > 0 {
+				fmt.Printf("  Tools: %s\n", strings.Join(s.AllowedTools, ", "))
+			}
+			for _, a := range s.Arguments {
+				required := ""
+				if a.Required {
+					required = " (required)"
+				}
+				fmt.Printf("  Arg: %s\n", strings.TrimSpace(a.Name+required+" "+a.Description))
+			}
+			for _, sc := range s.Scripts {
+				fmt.Printf("  Script: %s\n", strings.TrimSpace("script_"+sc.Name+" "+sc.Description))
+			}
 			fmt.Printf("  Path: %s\n\n", s.Path)
 		}
 		return
 	}
 
+	if os.Args[1] == "resume" {
+		fs := flag.NewFlagSet("resume", flag.ContinueOnError)
+		allowAll := fs.Bool("allow", false, "skip all tool confirmation prompts")
+		showReasoning := fs.Bool("show-reasoning", false, "print model reasoning before the answer")
+		if err := fs.Parse(os.Args[2:]); err != nil {
+			os.Exit(1)
+		}
+		if fs.NArg() > 0 {
+			fmt.Println("Usage: go run cmd/cli/main.go resume [--allow] [--show-reasoning]")
+			os.Exit(1)
+		}
+
+		agentRegistry := getAgentRegistry()
+		scanner := skill.NewScanner()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		selectorBot := getSelectorBot()
+
+		if err := runEvents(ctx, cancel, *showReasoning, func(ch chan<- agentTypes.Event) error {
+			return exec.Resume(ctx, selectorBot, agentRegistry, scanner, ch, *allowAll)
+		}); err != nil && ctx.Err() == nil {
+			slog.Error("failed to resume", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		exec.WaitSummary()
+		return
+	}
+
 	if os.Args[1] == "run" {
-		if len(os.Args) < 3 {
-			fmt.Println("Usage: go run cmd/cli/main.go run <input> [--allow]")
-			fmt.Println("       go run cmd/cli/main.go run <skill_name> <input> [--allow]")
+		fs := flag.NewFlagSet("run", flag.ContinueOnError)
+		skillName := fs.String("skill", "", "use this skill instead of auto selection, comma separated names compose several")
+		noSkill := fs.Bool("no-skill", false, "run without any skill")
+		agentName := fs.String("agent", "", "use this agent (provider@model) instead of auto selection")
+		allowAll := fs.Bool("allow", false, "skip all tool confirmation prompts")
+		showReasoning := fs.Bool("show-reasoning", false, "print model reasoning before the answer")
+		compose := fs.String("compose", "", "how several skills run: merge or stages, default from selector.compose")
+		skillArgs := argFlag{}
+		fs.Var(skillArgs, "arg", "skill argument as key=value, repeatable, requires --skill")
+		params := paramsFlags(fs)
+		var attachments []string
+		fs.Func("attach", "image or pdf sent along with the input, repeatable", func(value string) error {
+			attachments = append(attachments, value)
+			return nil
+		})
+		jsonSchema := fs.String("json-schema", "", "JSON schema file the answer must match, only the JSON is printed")
+		if err := fs.Parse(reorderArgs(fs, os.Args[2:])); err != nil {
 			os.Exit(1)
 		}
 
-		allowAll := slices.Contains(os.Args[3:], "--allow")
+		userInput := strings.TrimSpace(strings.Join(fs.Args(), " "))
+		if userInput == "" {
+			fmt.Println("Usage: go run cmd/cli/main.go run [--skill <name>[,<name>...] [--arg k=v]... | --no-skill] [--compose merge|stages] [--agent <provider@model>] [--temperature n] [--top-p n] [--max-tokens n] [--seed n] [--stop s]... [--reasoning low|medium|high] [--reasoning-budget n] [--attach file]... [--json-schema file] [--show-reasoning] [--allow] <input>")
+			os.Exit(1)
+		}
 
 		agentRegistry := getAgentRegistry()
 		scanner := skill.NewScanner()
 
-		userInput := os.Args[2]
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
-		selectorBot, err := nvidia.New("compat@qwen:8b")
-		if err != nil {
-			slog.Error("failed to initialize", slog.String("error", err.Error()))
-			os.Exit(1)
+		selectorBot := getSelectorBot()
+		override := exec.OverrideData{
+			Skill:       *skillName,
+			NoSkill:     *noSkill,
+			Agent:       *agentName,
+			Args:        skillArgs,
+			Compose:     *compose,
+			Params:      *params,
+			Attachments: attachments,
+		}
+		if *jsonSchema != "" {
+			schema, err := loadSchema(*jsonSchema)
+			if err != nil {
+				slog.Error("failed to load schema", slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+			override.Schema = schema
 		}
 
-		if err := runEvents(ctx, cancel, func(ch chan<- agentTypes.Event) error {
-			return exec.Run(ctx, selectorBot, agentRegistry, scanner, userInput, ch, allowAll)
-		}); err != nil && ctx.Err() == nil {
+		execute := func(ch chan<- agentTypes.Event) error {
+			return exec.RunWithOverride(ctx, selectorBot, agentRegistry, scanner, userInput, override, ch, *allowAll)
+		}
+		var err error
+		if override.Schema != nil {
+			err = runJSONEvents(execute)
+		} else {
+			err = runEvents(ctx, cancel, *showReasoning, execute)
+		}
+		if err != nil && ctx.Err() == nil {
 			slog.Error("failed to execute", slog.String("error", err.Error()))
 			os.Exit(1)
 		}
+		// * summary is written in background after the answer, wait before exit
+		exec.WaitSummary()
 		return
 	}
 }
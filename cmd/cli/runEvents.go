@@ -8,10 +8,11 @@ import (
 	"time"
 
 	"github.com/manifoldco/promptui"
+	"github.com/pardnchiu/agenvoy/internal/agents/exec"
 	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
 )
 
-func runEvents(_ context.Context, cancel context.CancelFunc, fn func(chan<- agentTypes.Event) error) error {
+func runEvents(_ context.Context, cancel context.CancelFunc, showReasoning bool, fn func(chan<- agentTypes.Event) error) error {
 	start := time.Now()
 	ch := make(chan agentTypes.Event, 16)
 	var execErr error
@@ -30,9 +31,9 @@ func runEvents(_ context.Context, cancel context.CancelFunc, fn func(chan<- agen
 		case agentTypes.EventSkillResult:
 			if ev.Text == "none" {
 				skillNone = true
-				fmt.Printf("\033[2K\r[*] Skill: none")
+				fmt.Printf("\033[2K\r[*] Skill: none%s", routeHint(ev))
 			} else {
-				fmt.Printf("\033[2K\r[*] Skill: %s\n", ev.Text)
+				fmt.Printf("\033[2K\r[*] Skill: %s%s\n", ev.Text, routeHint(ev))
 			}
 
 		case agentTypes.EventAgentSelect:
@@ -43,7 +44,13 @@ func runEvents(_ context.Context, cancel context.CancelFunc, fn func(chan<- agen
 			}
 
 		case agentTypes.EventAgentResult:
-			fmt.Printf("\033[2K\r[*] Agent: %s\n", ev.Text)
+			fmt.Printf("\033[2K\r[*] Agent: %s%s\n", ev.Text, routeHint(ev))
+
+		case agentTypes.EventReasoning:
+			// * hidden unless asked for, the answer follows as EventText anyway
+			if showReasoning {
+				printHint("[~] " + ev.Text)
+			}
 
 		case agentTypes.EventText:
 			fmt.Printf("[*] %s\n", ev.Text)
@@ -88,10 +95,33 @@ func runEvents(_ context.Context, cancel context.CancelFunc, fn func(chan<- agen
 			}
 
 		case agentTypes.EventDone:
-			fmt.Printf(" (%s)", time.Since(start).Round(time.Millisecond))
+			fmt.Printf(" (%s%s)", time.Since(start).Round(time.Millisecond), usageHint(ev.Usage))
 			fmt.Println()
 		}
 	}
 
 	return execErr
 }
+
+// * how the selection was decided, e.g. " (local 0.42)"
+func routeHint(ev agentTypes.Event) string {
+	switch ev.Source {
+	case "":
+		return ""
+	case exec.RouteOverride:
+		return " (override)"
+	}
+	return fmt.Sprintf(" (%s %.2f)", ev.Source, ev.Score)
+}
+
+// * token usage of the turn, e.g. " · 12000 in (9000 cached, 0 written) / 300 out"
+func usageHint(usage *agentTypes.UsageData) string {
+	if usage == nil || (usage.InputTokens == 0 && usage.OutputTokens == 0) {
+		return ""
+	}
+	cached := ""
+	if usage.CacheReadTokens > 0 || usage.CacheWriteTokens > 0 {
+		cached = fmt.Sprintf(" (%d cached, %d written)", usage.CacheReadTokens, usage.CacheWriteTokens)
+	}
+	return fmt.Sprintf(" · %d in%s / %d out", usage.InputTokens, cached, usage.OutputTokens)
+}
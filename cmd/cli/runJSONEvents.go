@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+)
+
+var schemaNameRegex = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// * the file name becomes the schema name, answer.schema.json is sent as answer
+func loadSchema(path string) (*agentTypes.SchemaData, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile: %w", err)
+	}
+
+	name, _, _ := strings.Cut(filepath.Base(path), ".")
+	name = schemaNameRegex.ReplaceAllString(name, "_")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	schema, err := agentTypes.NewSchema(data, name)
+	if err != nil {
+		return nil, fmt.Errorf("agentTypes.NewSchema: %w", err)
+	}
+	return schema, nil
+}
+
+// * scripted runs read stdout as the answer, progress stays off it and only the last text is printed
+func runJSONEvents(fn func(chan<- agentTypes.Event) error) error {
+	ch := make(chan agentTypes.Event, 16)
+	var execErr error
+
+	go func() {
+		defer close(ch)
+		execErr = fn(ch)
+	}()
+
+	answer := ""
+	for ev := range ch {
+		switch ev.Type {
+		case agentTypes.EventText:
+			answer = ev.Text
+
+		case agentTypes.EventToolConfirm:
+			// * no prompt to answer in a pipe, --allow lets tools run
+			fmt.Fprintf(os.Stderr, "[x] Denied: %s, run with --allow to permit tools\n", ev.ToolName)
+			ev.ReplyCh <- false
+
+		case agentTypes.EventError:
+			if ev.Err != nil {
+				fmt.Fprintf(os.Stderr, "[!] Error: %v\n", ev.Err)
+			}
+		}
+	}
+
+	if execErr != nil {
+		return execErr
+	}
+	fmt.Println(answer)
+	return nil
+}
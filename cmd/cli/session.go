@@ -0,0 +1,125 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pardnchiu/agenvoy/internal/session"
+)
+
+func runSession(args []string) error {
+	if len(args) < 1 {
+		printSessionUsage()
+		return fmt.Errorf("missing subcommand")
+	}
+
+	switch args[0] {
+	case "export":
+		fs := flag.NewFlagSet("session export", flag.ContinueOnError)
+		format := fs.String("format", "md", "output format: md, json or html")
+		output := fs.String("output", "", "write to file instead of stdout")
+		if err := fs.Parse(reorderArgs(fs, args[1:])); err != nil {
+			return err
+		}
+		if fs.NArg() < 1 {
+			printSessionUsage()
+			return fmt.Errorf("missing session id")
+		}
+
+		transcript, err := session.Load(fs.Arg(0))
+		if err != nil {
+			return fmt.Errorf("session.Load: %w", err)
+		}
+		data, err := session.Export(transcript, *format)
+		if err != nil {
+			return fmt.Errorf("session.Export: %w", err)
+		}
+
+		if *output == "" {
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(*output, data, 0644); err != nil {
+			return fmt.Errorf("os.WriteFile: %w", err)
+		}
+		printOk("Export", *output)
+		return nil
+
+	case "import":
+		fs := flag.NewFlagSet("session import", flag.ContinueOnError)
+		force := fs.Bool("force", false, "overwrite an existing session with the same id")
+		if err := fs.Parse(reorderArgs(fs, args[1:])); err != nil {
+			return err
+		}
+		if fs.NArg() < 1 {
+			printSessionUsage()
+			return fmt.Errorf("missing file")
+		}
+
+		var data []byte
+		var err error
+		if path := fs.Arg(0); path == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(path)
+		}
+		if err != nil {
+			return fmt.Errorf("read input: %w", err)
+		}
+
+		sessionID, err := session.Import(data, *force)
+		if err != nil {
+			return fmt.Errorf("session.Import: %w", err)
+		}
+		printOk("Import", sessionID+" (active)")
+		return nil
+
+	default:
+		printSessionUsage()
+		return fmt.Errorf("unknown subcommand: %s", args[0])
+	}
+}
+
+func printSessionUsage() {
+	fmt.Println("Usage:")
+	fmt.Println("  go run cmd/cli/main.go session export <session_id> [--format md|json|html] [--output <file>]")
+	fmt.Println("  go run cmd/cli/main.go session import <file.json|-> [--force]")
+}
+
+// * flag stops at the first positional, move flags in front so they may follow the id
+// * everything after -- stays positional, e.g. run -- --not-a-flag
+func reorderArgs(fs *flag.FlagSet, args []string) []string {
+	var flags, positionals []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			positionals = append(positionals, args[i+1:]...)
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			positionals = append(positionals, arg)
+			continue
+		}
+		flags = append(flags, arg)
+
+		name := arg[1:]
+		if name[0] == '-' {
+			name = name[1:]
+		}
+		if f := fs.Lookup(name); f != nil {
+			if bf, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && bf.IsBoolFlag() {
+				continue
+			}
+			if i+1 < len(args) {
+				i++
+				flags = append(flags, args[i])
+			}
+		}
+	}
+	if len(positionals) == 0 {
+		return flags
+	}
+	return append(append(flags, "--"), positionals...)
+}
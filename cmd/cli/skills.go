@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pardnchiu/agenvoy/internal/skill"
+)
+
+func runSkills(args []string) error {
+	if len(args) < 1 {
+		printSkillsUsage()
+		return fmt.Errorf("missing subcommand")
+	}
+
+	switch args[0] {
+	case "install":
+		fs := flag.NewFlagSet("skill install", flag.ContinueOnError)
+		project := fs.Bool("project", false, "install into ./.claude/skills instead of ~/.claude/skills")
+		force := fs.Bool("force", false, "overwrite an existing skill with the same name")
+		if err := fs.Parse(reorderArgs(fs, args[1:])); err != nil {
+			return err
+		}
+		if fs.NArg() < 1 {
+			printSkillsUsage()
+			return fmt.Errorf("missing source")
+		}
+
+		target, err := skill.GetTarget(*project)
+		if err != nil {
+			return fmt.Errorf("skill.GetTarget: %w", err)
+		}
+		s, err := skill.Install(context.Background(), fs.Arg(0), target, *force)
+		if err != nil {
+			return fmt.Errorf("skill.Install: %w", err)
+		}
+		printOk("Install", fmt.Sprintf("%s:%s → %s", s.Scope, s.Name, s.Path))
+		return nil
+
+	case "update":
+		fs := flag.NewFlagSet("skill update", flag.ContinueOnError)
+		project := fs.Bool("project", false, "update skills in ./.claude/skills instead of ~/.claude/skills")
+		force := fs.Bool("force", false, "overwrite local changes")
+		if err := fs.Parse(reorderArgs(fs, args[1:])); err != nil {
+			return err
+		}
+
+		target, err := skill.GetTarget(*project)
+		if err != nil {
+			return fmt.Errorf("skill.GetTarget: %w", err)
+		}
+		names := fs.Args()
+		if len(names) == 0 {
+			lock, err := skill.ReadLock(target.Lock)
+			if err != nil {
+				return fmt.Errorf("skill.ReadLock: %w", err)
+			}
+			names = lock.Names()
+		}
+		if len(names) == 0 {
+			fmt.Println("No installed skills")
+			return nil
+		}
+
+		var failed int
+		for _, name := range names {
+			s, changed, err := skill.Update(context.Background(), name, target, *force)
+			switch {
+			case err != nil:
+				failed++
+				printError("Update", err.Error())
+			case changed:
+				printOk("Update", s.Name)
+			default:
+				printOk("Up to date", s.Name)
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d skill(s) failed to update", failed)
+		}
+		return nil
+
+	case "remove":
+		fs := flag.NewFlagSet("skill remove", flag.ContinueOnError)
+		project := fs.Bool("project", false, "remove from ./.claude/skills instead of ~/.claude/skills")
+		if err := fs.Parse(reorderArgs(fs, args[1:])); err != nil {
+			return err
+		}
+		if fs.NArg() < 1 {
+			printSkillsUsage()
+			return fmt.Errorf("missing skill name")
+		}
+
+		target, err := skill.GetTarget(*project)
+		if err != nil {
+			return fmt.Errorf("skill.GetTarget: %w", err)
+		}
+		if err := skill.Remove(fs.Arg(0), target); err != nil {
+			return fmt.Errorf("skill.Remove: %w", err)
+		}
+		printOk("Remove", fs.Arg(0))
+		return nil
+
+	case "new":
+		fs := flag.NewFlagSet("skill new", flag.ContinueOnError)
+		project := fs.Bool("project", false, "create in ./.claude/skills instead of ~/.claude/skills")
+		if err := fs.Parse(reorderArgs(fs, args[1:])); err != nil {
+			return err
+		}
+		if fs.NArg() < 1 {
+			printSkillsUsage()
+			return fmt.Errorf("missing skill name")
+		}
+
+		target, err := skill.GetTarget(*project)
+		if err != nil {
+			return fmt.Errorf("skill.GetTarget: %w", err)
+		}
+		dest, err := skill.New(fs.Arg(0), target)
+		if err != nil {
+			return fmt.Errorf("skill.New: %w", err)
+		}
+		printOk("New", dest)
+		return nil
+
+	case "doctor":
+		scanner := skill.NewScanner()
+		list := scanner.Snapshot()
+
+		count := make(map[string]int, len(list.Paths))
+		for path := range list.ByPath {
+			count[filepath.Dir(filepath.Dir(path))]++
+		}
+
+		fmt.Println("Scanned paths (highest precedence first):")
+		for _, path := range list.Paths {
+			state := fmt.Sprintf("%d skill(s)", count[path])
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				state = "missing"
+			}
+			fmt.Printf("  [%s] %s — %s\n", skill.ScopeOf(path), path, state)
+		}
+
+		// * installed skills edited or deleted by hand no longer match their lockfile
+		modified := make(map[string]string)
+		for _, project := range []bool{true, false} {
+			target, err := skill.GetTarget(project)
+			if err != nil {
+				continue
+			}
+			lock, err := skill.ReadLock(target.Lock)
+			if err != nil {
+				modified[target.Lock] = err.Error()
+				continue
+			}
+			for name, problem := range lock.Verify(target.Dir) {
+				modified[target.Scope+":"+name] = problem
+			}
+		}
+
+		if len(list.Shadowed) == 0 && len(list.Invalid) == 0 && len(modified) == 0 {
+			fmt.Println("\nNo problems found")
+			return nil
+		}
+
+		if len(list.Shadowed) > 0 {
+			fmt.Printf("\nShadowed %d skill(s):\n", len(list.Shadowed))
+			for _, s := range list.Shadowed {
+				winner := list.ByName[s.Name]
+				fmt.Printf("• %s\n", s.Name)
+				fmt.Printf("  Used:    %s:%s  %s\n", winner.Scope, winner.Name, winner.Path)
+				fmt.Printf("  Ignored: %s:%s  %s\n", s.Scope, s.Name, s.Path)
+				if scoped := list.Scoped[s.Scope+":"+s.Name]; scoped != s {
+					fmt.Println("  Not reachable by scope either, rename one of them")
+				}
+			}
+		}
+
+		if len(list.Invalid) > 0 {
+			paths := make([]string, 0, len(list.Invalid))
+			for path := range list.Invalid {
+				paths = append(paths, path)
+			}
+			sort.Strings(paths)
+
+			fmt.Printf("\nInvalid %d skill(s):\n", len(list.Invalid))
+			for _, path := range paths {
+				fmt.Printf("• %s\n  %s\n", path, list.Invalid[path])
+			}
+		}
+
+		if len(modified) > 0 {
+			names := make([]string, 0, len(modified))
+			for name := range modified {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			fmt.Printf("\nLockfile mismatch %d skill(s):\n", len(modified))
+			for _, name := range names {
+				fmt.Printf("• %s — %s\n", name, modified[name])
+			}
+		}
+		return nil
+
+	default:
+		printSkillsUsage()
+		return fmt.Errorf("unknown subcommand: %s", args[0])
+	}
+}
+
+func printSkillsUsage() {
+	fmt.Println("Usage:")
+	fmt.Println("  go run cmd/cli/main.go skill install <path|git-url|tarball>[#subdir] [--project] [--force]")
+	fmt.Println("  go run cmd/cli/main.go skill update [name...] [--project] [--force]")
+	fmt.Println("  go run cmd/cli/main.go skill remove <name> [--project]")
+	fmt.Println("  go run cmd/cli/main.go skill new <name> [--project]")
+	fmt.Println("  go run cmd/cli/main.go skills doctor")
+}
@@ -0,0 +1,850 @@
+package exec_test
+
+import (
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pardnchiu/agenvoy/internal/agents/exec"
+	"github.com/pardnchiu/agenvoy/internal/agents/provider/mock"
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+	sessionStore "github.com/pardnchiu/agenvoy/internal/session"
+	"github.com/pardnchiu/agenvoy/internal/skill"
+	"github.com/pardnchiu/agenvoy/internal/tools/fake"
+)
+
+// newMock loads a script from testdata, must be called before chdir.
+func newMock(t *testing.T, script string) *mock.Agent {
+	t.Helper()
+	path, err := filepath.Abs(filepath.Join("testdata", script))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := mock.New("mock@" + path)
+	if err != nil {
+		t.Fatalf("mock.New(%s) error: %v", script, err)
+	}
+	return a
+}
+
+// sandbox isolates config, sessions and work dir for one test.
+func sandbox(t *testing.T) (home, work string) {
+	t.Helper()
+	home = t.TempDir()
+	work = t.TempDir()
+	t.Setenv("HOME", home)
+	t.Chdir(work)
+	return home, work
+}
+
+type result struct {
+	events []agentTypes.Event
+	err    error
+}
+
+func (r result) texts() []string {
+	var out []string
+	for _, ev := range r.events {
+		if ev.Type == agentTypes.EventText {
+			out = append(out, ev.Text)
+		}
+	}
+	return out
+}
+
+func (r result) count(typ agentTypes.EventType) int {
+	n := 0
+	for _, ev := range r.events {
+		if ev.Type == typ {
+			n++
+		}
+	}
+	return n
+}
+
+func run(t *testing.T, bot, agent agentTypes.Agent, input string, allowAll bool, confirm func(agentTypes.Event) bool) result {
+	t.Helper()
+
+	registry := agentTypes.AgentRegistry{
+		Registry: map[string]agentTypes.Agent{"mock@agent": agent},
+		Entries:  []agentTypes.AgentEntry{{Name: "mock@agent", Description: "scripted"}},
+		Fallback: agent,
+	}
+	scanner := &skill.Scanner{Skills: &skill.SkillList{ByName: map[string]*skill.Skill{}}}
+
+	events := make(chan agentTypes.Event, 16)
+	var r result
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for ev := range events {
+			if ev.Type == agentTypes.EventToolConfirm {
+				ev.ReplyCh <- confirm(ev)
+			}
+			r.events = append(r.events, ev)
+		}
+	}()
+
+	// * network tools are always faked, the harness never leaves the machine
+	override := exec.OverrideData{Tools: fake.Network()}
+	r.err = exec.RunWithOverride(context.Background(), bot, registry, scanner, input, override, events, allowAll)
+	close(events)
+	<-done
+	exec.WaitSummary()
+	return r
+}
+
+func sessionDir(t *testing.T, home string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(home, ".config", "agenvoy", "config.json"))
+	if err != nil {
+		t.Fatalf("read session index: %v", err)
+	}
+	var index struct {
+		SessionID string `json:"session_id"`
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(home, ".config", "agenvoy", "sessions", index.SessionID)
+}
+
+// ---------- tool loop + summary ----------
+
+func TestRun_ToolLoop(t *testing.T) {
+	bot := newMock(t, "selector.yaml")
+	agent := newMock(t, "tool_loop.yaml")
+	home, _ := sandbox(t)
+
+	r := run(t, bot, agent, "1+1?", true, nil)
+	if r.err != nil {
+		t.Fatalf("Run() error: %v", r.err)
+	}
+
+	for _, ev := range r.events {
+		if ev.Type == agentTypes.EventAgentResult && ev.Text != "mock@agent" {
+			t.Errorf("agent = %q, want mock@agent", ev.Text)
+		}
+		if ev.Type == agentTypes.EventSkillResult && ev.Text != "none" {
+			t.Errorf("skill = %q, want none", ev.Text)
+		}
+	}
+	if got := r.texts(); len(got) != 1 || got[0] != "2" {
+		t.Errorf("texts = %v, want [2]", got)
+	}
+	if r.count(agentTypes.EventToolResult) != 1 || r.count(agentTypes.EventDone) != 1 {
+		t.Errorf("events = %+v", r.events)
+	}
+
+	// calculate result must reach the model before the final answer
+	reqs := agent.Requests()
+	if len(reqs) != 2 {
+		t.Fatalf("agent requests = %d, want 2", len(reqs))
+	}
+	last := reqs[1][len(reqs[1])-1]
+	if content, _ := last.Content.(string); last.Role != "tool" || !strings.Contains(content, "[calculate] 2") {
+		t.Errorf("tool message = %+v", last)
+	}
+
+	dir := sessionDir(t, home)
+	summary, err := os.ReadFile(filepath.Join(dir, "summary.json"))
+	if err != nil {
+		t.Fatalf("summary.json not written: %v", err)
+	}
+	if !strings.Contains(string(summary), `"core_discussion":"1+1"`) {
+		t.Errorf("summary.json = %s", summary)
+	}
+	history, err := os.ReadFile(filepath.Join(dir, "history.json"))
+	if err != nil || !strings.Contains(string(history), "1+1?") {
+		t.Errorf("history.json = %s, err = %v", history, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "events.jsonl")); err != nil {
+		t.Errorf("events.jsonl not written: %v", err)
+	}
+}
+
+// ---------- multimodal ----------
+
+func TestRunWithOverride_Media(t *testing.T) {
+	bot := newMock(t, "selector.yaml")
+	agent := newMock(t, "media.yaml")
+	home, work := sandbox(t)
+
+	f, err := os.Create(filepath.Join(work, "shot.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	os.WriteFile(filepath.Join(work, "notes.txt"), []byte("plain text"), 0644)
+
+	registry := agentTypes.AgentRegistry{
+		Registry: map[string]agentTypes.Agent{"mock@agent": agent},
+		Entries:  []agentTypes.AgentEntry{{Name: "mock@agent"}},
+		Fallback: agent,
+	}
+	scanner := &skill.Scanner{Skills: &skill.SkillList{ByName: map[string]*skill.Skill{}}}
+
+	events := make(chan agentTypes.Event, 64)
+	bad := exec.OverrideData{NoSkill: true, Attachments: []string{"notes.txt"}}
+	if err := exec.RunWithOverride(context.Background(), bot, registry, scanner, "看圖", bad, events, true); err == nil {
+		t.Error("text attachment should fail")
+	}
+
+	override := exec.OverrideData{NoSkill: true, Agent: "mock@agent", Attachments: []string{"shot.png"}}
+	if err := exec.RunWithOverride(context.Background(), bot, registry, scanner, "看圖", override, events, true); err != nil {
+		t.Fatalf("RunWithOverride() error: %v", err)
+	}
+	close(events)
+	exec.WaitSummary()
+
+	reqs := agent.Requests()
+	if len(reqs) != 2 {
+		t.Fatalf("agent requests = %d, want 2", len(reqs))
+	}
+	parts := agentTypes.ContentParts(reqs[0][len(reqs[0])-1].Content)
+	if len(parts) != 2 || parts[1].Type != agentTypes.PartImage || parts[1].MediaType != "image/png" {
+		t.Errorf("user parts = %+v, want text and image", parts)
+	}
+
+	// read_image result carries the image, chat apis get it as a user message after the tool
+	tool := reqs[1][len(reqs[1])-1]
+	if !agentTypes.HasMedia(tool.Content) || !strings.Contains(agentTypes.ContentText(tool.Content), "2x2") {
+		t.Errorf("tool message = %+v", tool)
+	}
+	chat := agentTypes.ChatMessages(reqs[1])
+	last := chat[len(chat)-1]
+	if _, ok := chat[len(chat)-2].Content.(string); !ok || last.Role != "user" {
+		t.Errorf("chat tail = %+v", chat[len(chat)-2:])
+	}
+	if data, _ := json.Marshal(last.Content); !strings.Contains(string(data), "data:image/png;base64,") {
+		t.Errorf("chat media = %s", data)
+	}
+
+	history, err := os.ReadFile(filepath.Join(sessionDir(t, home), "history.json"))
+	if err != nil || !strings.Contains(string(history), "附件：shot.png") || strings.Contains(string(history), "base64") {
+		t.Errorf("history.json = %s, err = %v", history, err)
+	}
+}
+
+// ---------- reasoning ----------
+
+func TestRun_Reasoning(t *testing.T) {
+	bot := newMock(t, "selector.yaml")
+	agent := newMock(t, "reasoning.yaml")
+	home, _ := sandbox(t)
+
+	r := run(t, bot, agent, "1+1?", true, nil)
+	if r.err != nil {
+		t.Fatalf("Run() error: %v", r.err)
+	}
+	if got := r.count(agentTypes.EventReasoning); got != 2 {
+		t.Errorf("reasoning events = %d, want 2", got)
+	}
+	if got := r.texts(); len(got) != 1 || got[0] != "2" {
+		t.Errorf("texts = %v, want [2]", got)
+	}
+
+	// * reasoning of the tool call stays within the turn
+	reqs := agent.Requests()
+	if call := reqs[1][len(reqs[1])-2]; call.Reasoning != "need to compute first" {
+		t.Errorf("tool call message reasoning = %q", call.Reasoning)
+	}
+	history, err := os.ReadFile(filepath.Join(sessionDir(t, home), "history.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(history), "the tool said 2") {
+		t.Error("history.json must not keep reasoning")
+	}
+}
+
+// ---------- usage ----------
+
+func TestRun_Usage(t *testing.T) {
+	bot := newMock(t, "selector.yaml")
+	agent := newMock(t, "usage.yaml")
+	home, _ := sandbox(t)
+
+	r := run(t, bot, agent, "1+1?", true, nil)
+	if r.err != nil {
+		t.Fatalf("Run() error: %v", r.err)
+	}
+
+	want := agentTypes.UsageData{InputTokens: 6100, OutputTokens: 25, CacheReadTokens: 2900, CacheWriteTokens: 2900}
+	var done *agentTypes.UsageData
+	for _, ev := range r.events {
+		if ev.Type == agentTypes.EventDone {
+			done = ev.Usage
+		}
+	}
+	if done == nil || *done != want {
+		t.Errorf("done usage = %+v, want %+v", done, want)
+	}
+
+	data, err := os.ReadFile(filepath.Join(sessionDir(t, home), "events.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"cache_read_tokens":2900`) {
+		t.Error("turn_end record should carry the usage")
+	}
+}
+
+func TestOutput_Normalize(t *testing.T) {
+	var output agentTypes.Output
+	body := `{"choices":[{"message":{"role":"assistant","content":"hi"},"finish_reason":"length"}],
+		"usage":{"prompt_tokens":2000,"completion_tokens":10,"prompt_tokens_details":{"cached_tokens":1536}}}`
+	if err := json.Unmarshal([]byte(body), &output); err != nil {
+		t.Fatal(err)
+	}
+	output.Normalize()
+
+	if output.Choices[0].FinishReason != agentTypes.FinishLength {
+		t.Errorf("finish reason = %q", output.Choices[0].FinishReason)
+	}
+	if output.Usage.CacheReadTokens != 1536 || output.Usage.InputTokens != 2000 || output.Usage.PromptDetails != nil {
+		t.Errorf("usage = %+v", output.Usage)
+	}
+}
+
+// ---------- fake network tools ----------
+
+func TestRun_FakeNetwork(t *testing.T) {
+	bot := newMock(t, "selector.yaml")
+	agent := newMock(t, "network.yaml")
+	sandbox(t)
+
+	r := run(t, bot, agent, "what is agenvoy", true, nil)
+	if r.err != nil {
+		t.Fatalf("Run() error: %v", r.err)
+	}
+
+	var results []string
+	for _, ev := range r.events {
+		if ev.Type == agentTypes.EventToolResult {
+			results = append(results, ev.Result)
+		}
+	}
+	if len(results) != 2 || !strings.Contains(results[0], "Fake search result") || !strings.Contains(results[1], "Fake content") {
+		t.Errorf("tool results = %v", results)
+	}
+}
+
+// ---------- confirmation ----------
+
+func TestRun_ConfirmSkip(t *testing.T) {
+	bot := newMock(t, "selector.yaml")
+	agent := newMock(t, "confirm.yaml")
+	_, work := sandbox(t)
+
+	var asked []string
+	r := run(t, bot, agent, "write a file", false, func(ev agentTypes.Event) bool {
+		asked = append(asked, ev.ToolName)
+		return false
+	})
+	if r.err != nil {
+		t.Fatalf("Run() error: %v", r.err)
+	}
+
+	if len(asked) != 1 || asked[0] != "write_file" {
+		t.Errorf("confirm asked = %v, want [write_file]", asked)
+	}
+	if r.count(agentTypes.EventToolSkipped) != 1 {
+		t.Error("expected EventToolSkipped")
+	}
+	if _, err := os.Stat(filepath.Join(work, "out.txt")); !os.IsNotExist(err) {
+		t.Error("skipped write_file must not touch the work dir")
+	}
+
+	reqs := agent.Requests()
+	last := reqs[len(reqs)-1]
+	if msg := last[len(last)-1]; msg.ToolCallID != "call_write" || msg.Content != "Skipped by user" {
+		t.Errorf("skip message = %+v", msg)
+	}
+}
+
+func TestRun_ConfirmAllow(t *testing.T) {
+	bot := newMock(t, "selector.yaml")
+	agent := newMock(t, "confirm.yaml")
+	_, work := sandbox(t)
+
+	r := run(t, bot, agent, "write a file", false, func(agentTypes.Event) bool { return true })
+	if r.err != nil {
+		t.Fatalf("Run() error: %v", r.err)
+	}
+	if data, err := os.ReadFile(filepath.Join(work, "out.txt")); err != nil || string(data) != "hello" {
+		t.Errorf("out.txt = %q, err = %v", data, err)
+	}
+}
+
+// ---------- iteration limit ----------
+
+func TestRun_IterationLimit(t *testing.T) {
+	bot := newMock(t, "selector.yaml")
+	agent := newMock(t, "limit.yaml")
+	sandbox(t)
+
+	r := run(t, bot, agent, "loop forever", true, nil)
+	if r.err != nil {
+		t.Fatalf("Run() error: %v", r.err)
+	}
+
+	// limit tool rounds plus one forced summary without tools
+	if got := len(agent.Requests()); got != exec.MaxToolIterations+1 {
+		t.Errorf("agent requests = %d, want %d", got, exec.MaxToolIterations+1)
+	}
+	if got := r.texts(); len(got) != 1 || got[0] != "limit reached" {
+		t.Errorf("texts = %v, want [limit reached]", got)
+	}
+	// identical calls are answered from cache after the first execution
+	if got := r.count(agentTypes.EventToolResult); got != 1 {
+		t.Errorf("tool results = %d, want 1", got)
+	}
+}
+
+// ---------- truncated response ----------
+
+func TestRun_Continuation(t *testing.T) {
+	bot := newMock(t, "selector.yaml")
+
+	t.Run("stitched", func(t *testing.T) {
+		agent := newMock(t, "truncated.yaml")
+		sandbox(t)
+
+		r := run(t, bot, agent, "write a long answer", true, nil)
+		if r.err != nil {
+			t.Fatalf("Run() error: %v", r.err)
+		}
+		if got := r.texts(); len(got) != 1 || got[0] != "first half, second half, done" {
+			t.Errorf("texts = %q, want stitched answer", got)
+		}
+
+		reqs := agent.Requests()
+		if len(reqs) != 3 {
+			t.Fatalf("agent requests = %d, want 3", len(reqs))
+		}
+		last := reqs[2]
+		if last[len(last)-2].Content != "second half, " || last[len(last)-1].Role != "user" {
+			t.Errorf("continuation request ends with %+v", last[len(last)-2:])
+		}
+	})
+
+	t.Run("bounded", func(t *testing.T) {
+		agent := newMock(t, "truncated.yaml")
+		_, work := sandbox(t)
+		os.MkdirAll(filepath.Join(work, ".config", "agenvoy"), 0755)
+		os.WriteFile(filepath.Join(work, ".config", "agenvoy", "config.json"), []byte(`{"max_continuations": 1}`), 0644)
+
+		r := run(t, bot, agent, "write a long answer", true, nil)
+		if r.err != nil {
+			t.Fatalf("Run() error: %v", r.err)
+		}
+		if got := r.texts(); len(got) != 1 || got[0] != "first half, second half, " {
+			t.Errorf("texts = %q, want the truncated pieces", got)
+		}
+		if got := len(agent.Requests()); got != 2 {
+			t.Errorf("agent requests = %d, want 2", got)
+		}
+	})
+
+	t.Run("tool call", func(t *testing.T) {
+		agent := newMock(t, "truncated_tool.yaml")
+		sandbox(t)
+
+		r := run(t, bot, agent, "what is 1+1", true, nil)
+		if r.err != nil {
+			t.Fatalf("Run() error: %v", r.err)
+		}
+		// * the piece before the tool call is context, not part of the answer
+		if got := r.texts(); len(got) != 1 || got[0] != "2" {
+			t.Errorf("texts = %q, want the answer after the tool", got)
+		}
+		if got := len(agent.Requests()); got != 3 {
+			t.Errorf("agent requests = %d, want 3", got)
+		}
+	})
+}
+
+// ---------- empty response ----------
+
+func TestRun_EmptyResponse(t *testing.T) {
+	bot := newMock(t, "selector.yaml")
+	agent := newMock(t, "empty.yaml")
+	sandbox(t)
+
+	r := run(t, bot, agent, "anything", true, nil)
+	if r.err != nil {
+		t.Fatalf("Run() error: %v", r.err)
+	}
+	if got := len(agent.Requests()); got != 3 {
+		t.Errorf("agent requests = %d, want 3", got)
+	}
+	if r.count(agentTypes.EventText) != 1 || r.count(agentTypes.EventDone) != 1 {
+		t.Errorf("events = %+v", r.events)
+	}
+}
+
+// ---------- provider error ----------
+
+func TestRun_ProviderError(t *testing.T) {
+	bot := newMock(t, "selector.yaml")
+	agent := newMock(t, "error.yaml")
+	home, _ := sandbox(t)
+
+	r := run(t, bot, agent, "anything", true, nil)
+	if r.err == nil || !strings.Contains(r.err.Error(), "upstream unavailable") {
+		t.Fatalf("Run() error = %v, want upstream unavailable", r.err)
+	}
+	if _, err := os.Stat(filepath.Join(sessionDir(t, home), "summary.json")); !os.IsNotExist(err) {
+		t.Error("failed turn must not write a summary")
+	}
+}
+
+// ---------- resume ----------
+
+func TestResume_SchemaMedia(t *testing.T) {
+	bot := newMock(t, "selector.yaml")
+	agent := newMock(t, "schema.yaml")
+	home, _ := sandbox(t)
+
+	// * a schema turn with an image that stopped before its first answer
+	configDir := filepath.Join(home, ".config", "agenvoy")
+	dir := filepath.Join(configDir, "sessions", "s1")
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(configDir, "config.json"), []byte(`{"session_id":"s1"}`), 0644)
+	schema, err := agentTypes.NewSchema([]byte(`{"type":"object","properties":{"answer":{"type":"integer"}},"required":["answer"]}`), "math")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shot := agentTypes.ContentPart{Type: agentTypes.PartImage, MediaType: "image/png", Data: "aGVsbG8=", Name: "shot.png"}
+	user := agentTypes.Message{Role: "user", Content: []agentTypes.ContentPart{{Type: agentTypes.PartText, Text: "ts:1\n1+1?"}, shot}}
+	log, err := sessionStore.OpenLog(dir, "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Append(sessionStore.Record{
+		Type:        sessionStore.RecordTurnStart,
+		Agent:       "mock@agent",
+		Input:       "1+1?",
+		Context:     log.StoreContext([]agentTypes.Message{{Role: "system", Content: "prompt"}}),
+		Schema:      schema,
+		Attachments: []agentTypes.ContentPart{shot},
+	})
+	log.Append(sessionStore.Record{Type: sessionStore.RecordMessage, Message: &user})
+	log.Close()
+
+	registry := agentTypes.AgentRegistry{
+		Registry: map[string]agentTypes.Agent{"mock@agent": agent},
+		Fallback: agent,
+	}
+	scanner := &skill.Scanner{Skills: &skill.SkillList{ByName: map[string]*skill.Skill{}}}
+	events := make(chan agentTypes.Event, 64)
+	if err := exec.Resume(context.Background(), bot, registry, scanner, events, true); err != nil {
+		t.Fatalf("Resume() error: %v", err)
+	}
+	close(events)
+	exec.WaitSummary()
+
+	r := result{}
+	for ev := range events {
+		r.events = append(r.events, ev)
+	}
+	if texts := r.texts(); len(texts) != 1 || texts[0] != `{"answer":2}` {
+		t.Errorf("texts = %q, want the repaired json", texts)
+	}
+	if schemas := agent.Schemas(); len(schemas) == 0 || schemas[0] == nil {
+		t.Errorf("schemas = %v, want the logged schema", schemas)
+	}
+	parts := agentTypes.ContentParts(agent.Requests()[0][1].Content)
+	if len(parts) != 2 || parts[1].Data != "aGVsbG8=" {
+		t.Errorf("user parts = %+v, want the image back", parts)
+	}
+	history, _ := os.ReadFile(filepath.Join(dir, "history.json"))
+	if !strings.Contains(string(history), "shot.png") || strings.Contains(string(history), "aGVsbG8=") {
+		t.Errorf("history = %s, want attachment names only", history)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "events.jsonl")); strings.Contains(string(data), "aGVsbG8=") {
+		t.Errorf("events.jsonl keeps the attachment inline: %s", data)
+	}
+}
+
+// ---------- overrides ----------
+
+func TestRunWithOverride(t *testing.T) {
+	bot := newMock(t, "selector.yaml")
+	agent := newMock(t, "tool_loop.yaml")
+	sandbox(t)
+
+	registry := agentTypes.AgentRegistry{
+		Registry: map[string]agentTypes.Agent{"mock@agent": agent},
+		Entries:  []agentTypes.AgentEntry{{Name: "mock@agent", Description: "scripted"}},
+		Fallback: agent,
+	}
+	scanner := &skill.Scanner{Skills: &skill.SkillList{ByName: map[string]*skill.Skill{}}}
+
+	for _, override := range []exec.OverrideData{
+		{Skill: "missing"},
+		{Agent: "nope@model"},
+		{Skill: "missing", NoSkill: true},
+	} {
+		events := make(chan agentTypes.Event, 16)
+		if err := exec.RunWithOverride(context.Background(), bot, registry, scanner, "1+1?", override, events, true); err == nil {
+			t.Errorf("RunWithOverride(%+v) should fail", override)
+		}
+	}
+
+	events := make(chan agentTypes.Event, 64)
+	override := exec.OverrideData{NoSkill: true, Agent: "mock@agent"}
+	if err := exec.RunWithOverride(context.Background(), bot, registry, scanner, "1+1?", override, events, true); err != nil {
+		t.Fatalf("RunWithOverride() error: %v", err)
+	}
+	close(events)
+	exec.WaitSummary()
+
+	for ev := range events {
+		if (ev.Type == agentTypes.EventSkillResult || ev.Type == agentTypes.EventAgentResult) && ev.Source != exec.RouteOverride {
+			t.Errorf("selection event = %+v, want override", ev)
+		}
+	}
+	// * only the summarizer may reach the selector bot
+	for _, req := range bot.Requests() {
+		if system, _ := req[0].Content.(string); strings.Contains(system, "Selector") {
+			t.Errorf("selector called despite override: %.40s", system)
+		}
+	}
+}
+
+// ---------- skill frontmatter ----------
+
+func TestRunWithOverride_SkillFrontmatter(t *testing.T) {
+	bot := newMock(t, "selector.yaml")
+	agent := newMock(t, "confirm.yaml")
+	fallback := newMock(t, "empty.yaml")
+	_, work := sandbox(t)
+
+	registry := agentTypes.AgentRegistry{
+		Registry: map[string]agentTypes.Agent{"mock@agent": agent, "mock@fallback": fallback},
+		Entries:  []agentTypes.AgentEntry{{Name: "mock@fallback"}, {Name: "mock@agent"}},
+		Fallback: fallback,
+	}
+	scanner := &skill.Scanner{Skills: &skill.SkillList{ByName: map[string]*skill.Skill{
+		"calc": {
+			Name:         "calc",
+			Content:      "calculate only",
+			AllowedTools: []string{"calc*"},
+			Model:        "mock@agent",
+			Arguments:    []skill.Argument{{Name: "precision", Type: "number", Default: "2"}},
+		},
+	}}}
+
+	events := make(chan agentTypes.Event, 64)
+	override := exec.OverrideData{Skill: "calc"}
+	if err := exec.RunWithOverride(context.Background(), bot, registry, scanner, "write it", override, events, false); err != nil {
+		t.Fatalf("RunWithOverride() error: %v", err)
+	}
+	close(events)
+	exec.WaitSummary()
+
+	for ev := range events {
+		switch ev.Type {
+		case agentTypes.EventToolConfirm:
+			t.Fatal("tool outside allowed-tools must not ask for confirmation")
+		case agentTypes.EventAgentResult:
+			if ev.Text != "mock@agent" || ev.Source != exec.RouteSkill {
+				t.Errorf("agent result = %+v, want skill model", ev)
+			}
+		}
+	}
+	if _, err := os.Stat(filepath.Join(work, "out.txt")); !os.IsNotExist(err) {
+		t.Error("write_file is outside allowed-tools")
+	}
+
+	reqs := agent.Requests()
+	if system, _ := reqs[0][0].Content.(string); !strings.Contains(system, "- precision: 2") {
+		t.Error("resolved skill arguments missing from system prompt")
+	}
+	last := reqs[len(reqs)-1]
+	if content, _ := last[len(last)-1].Content.(string); !strings.Contains(content, "not available") {
+		t.Errorf("blocked tool message = %q", content)
+	}
+}
+
+// ---------- generation params ----------
+
+func TestRunWithOverride_Params(t *testing.T) {
+	bot := newMock(t, "selector.yaml")
+	agent := newMock(t, "tool_loop.yaml")
+	sandbox(t)
+
+	low, high, tooHigh, seed := 0.2, 0.9, 3.0, 7
+	registry := agentTypes.AgentRegistry{
+		Registry: map[string]agentTypes.Agent{"mock@agent": agent},
+		Entries: []agentTypes.AgentEntry{{
+			Name:   "mock@agent",
+			Params: agentTypes.ParamsData{Temperature: &low, MaxTokens: 100, Seed: &seed},
+		}},
+		Fallback: agent,
+	}
+	scanner := &skill.Scanner{Skills: &skill.SkillList{ByName: map[string]*skill.Skill{
+		"calc": {Name: "calc", Content: "calculate", Params: skill.Params{MaxTokens: 200, Stop: []string{"END"}}},
+	}}}
+
+	events := make(chan agentTypes.Event, 16)
+	bad := exec.OverrideData{Skill: "calc", Params: agentTypes.ParamsData{Temperature: &tooHigh}}
+	if err := exec.RunWithOverride(context.Background(), bot, registry, scanner, "1+1?", bad, events, true); err == nil {
+		t.Error("temperature out of range should fail")
+	}
+
+	events = make(chan agentTypes.Event, 64)
+	override := exec.OverrideData{Skill: "calc", Agent: "mock@agent", Params: agentTypes.ParamsData{Temperature: &high}}
+	if err := exec.RunWithOverride(context.Background(), bot, registry, scanner, "1+1?", override, events, true); err != nil {
+		t.Fatalf("RunWithOverride() error: %v", err)
+	}
+	close(events)
+	exec.WaitSummary()
+
+	params := agent.Params()
+	if len(params) == 0 {
+		t.Fatal("agent never called")
+	}
+	for _, p := range params {
+		if p.Temperature == nil || *p.Temperature != high {
+			t.Errorf("temperature = %v, want run override %g", p.Temperature, high)
+		}
+		if p.MaxTokens != 200 || len(p.Stop) != 1 {
+			t.Errorf("max_tokens = %d stop = %v, want skill params", p.MaxTokens, p.Stop)
+		}
+		if p.Seed == nil || *p.Seed != seed {
+			t.Errorf("seed = %v, want model entry %d", p.Seed, seed)
+		}
+	}
+	for _, p := range bot.Params() {
+		if p.Temperature != nil || p.MaxTokens != 0 {
+			t.Errorf("selector bot got agent params: %+v", p)
+		}
+	}
+}
+
+// ---------- structured output ----------
+
+func TestRunWithOverride_Schema(t *testing.T) {
+	bot := newMock(t, "selector.yaml")
+	agent := newMock(t, "schema.yaml")
+	retry := newMock(t, "schema.yaml")
+	sandbox(t)
+
+	schema, err := agentTypes.NewSchema([]byte(`{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"properties": {"answer": {"type": "integer"}},
+		"required": ["answer"],
+		"additionalProperties": false
+	}`), "math")
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := agentTypes.AgentRegistry{
+		Registry: map[string]agentTypes.Agent{"mock@agent": agent},
+		Entries:  []agentTypes.AgentEntry{{Name: "mock@agent", Description: "scripted"}},
+		Fallback: agent,
+	}
+	scanner := &skill.Scanner{Skills: &skill.SkillList{ByName: map[string]*skill.Skill{}}}
+
+	events := make(chan agentTypes.Event, 64)
+	override := exec.OverrideData{Schema: schema}
+	if err := exec.RunWithOverride(context.Background(), bot, registry, scanner, "1+1?", override, events, true); err != nil {
+		t.Fatalf("RunWithOverride() error: %v", err)
+	}
+	close(events)
+	exec.WaitSummary()
+
+	r := result{}
+	for ev := range events {
+		r.events = append(r.events, ev)
+	}
+	if got := r.texts(); len(got) != 1 || got[0] != `{"answer":2}` {
+		t.Errorf("texts = %v, want the repaired json", got)
+	}
+
+	// * the free text answer goes back once with the validation error
+	reqs := agent.Requests()
+	if len(reqs) != 2 {
+		t.Fatalf("agent requests = %d, want 2", len(reqs))
+	}
+	if system, _ := reqs[0][0].Content.(string); !strings.Contains(system, `"answer"`) {
+		t.Error("system prompt does not describe the schema")
+	}
+	if repair, _ := reqs[1][len(reqs[1])-1].Content.(string); !strings.Contains(repair, "JSON Schema") {
+		t.Errorf("repair message = %q", repair)
+	}
+	// * without tools every provider can constrain the repair natively
+	if tools := agent.Tools(); len(tools[0]) == 0 || len(tools[1]) != 0 {
+		t.Errorf("tools per request = %d, %d, want the repair without tools", len(tools[0]), len(tools[1]))
+	}
+	for _, s := range agent.Schemas() {
+		if s == nil || s.Name != "math" {
+			t.Errorf("agent schema = %+v, want math", s)
+		}
+	}
+	selected := false
+	for _, s := range bot.Schemas() {
+		selected = selected || (s != nil && s.Name == "agent_selection")
+	}
+	if !selected {
+		t.Error("agent selector did not ask for a schema answer")
+	}
+
+	// * still invalid after the repair fails the turn
+	strict, _ := agentTypes.NewSchema([]byte(`{"type":"object","properties":{"answer":{"type":"integer","maximum":1}}}`), "")
+	registry.Registry["mock@agent"], registry.Fallback = retry, retry
+	events = make(chan agentTypes.Event, 64)
+	err = exec.RunWithOverride(context.Background(), bot, registry, scanner, "1+1?", exec.OverrideData{Schema: strict}, events, true)
+	close(events)
+	if err == nil || !strings.Contains(err.Error(), "$.answer") {
+		t.Errorf("RunWithOverride() error = %v, want a validation error", err)
+	}
+}
+
+func TestRunWithOverride_SchemaLimit(t *testing.T) {
+	bot := newMock(t, "selector.yaml")
+	agent := newMock(t, "schema_limit.yaml")
+	sandbox(t)
+
+	schema, err := agentTypes.NewSchema([]byte(`{"type":"object","properties":{"answer":{"type":"integer"}},"required":["answer"]}`), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := agentTypes.AgentRegistry{
+		Registry: map[string]agentTypes.Agent{"mock@agent": agent},
+		Entries:  []agentTypes.AgentEntry{{Name: "mock@agent", Description: "scripted"}},
+		Fallback: agent,
+	}
+	scanner := &skill.Scanner{Skills: &skill.SkillList{ByName: map[string]*skill.Skill{}}}
+
+	events := make(chan agentTypes.Event, 128)
+	if err := exec.RunWithOverride(context.Background(), bot, registry, scanner, "loop forever", exec.OverrideData{Schema: schema}, events, true); err != nil {
+		t.Fatalf("RunWithOverride() error: %v", err)
+	}
+	close(events)
+	exec.WaitSummary()
+
+	r := result{}
+	for ev := range events {
+		r.events = append(r.events, ev)
+	}
+	// * the forced summary after the tool limit is validated and repaired like any answer
+	if got := r.texts(); len(got) != 1 || got[0] != `{"answer":6}` {
+		t.Errorf("texts = %v, want the repaired json", got)
+	}
+	if got := len(agent.Requests()); got != exec.MaxToolIterations+2 {
+		t.Errorf("agent requests = %d, want %d", got, exec.MaxToolIterations+2)
+	}
+}
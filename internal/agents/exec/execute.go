@@ -3,17 +3,18 @@ package exec
 import (
 	"context"
 	_ "embed"
-	"encoding/json"
 	"fmt"
 	"log/slog"
-	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
 	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+	sessionStore "github.com/pardnchiu/agenvoy/internal/session"
 	"github.com/pardnchiu/agenvoy/internal/skill"
 	"github.com/pardnchiu/agenvoy/internal/tools"
+	"github.com/pardnchiu/agenvoy/internal/tools/script"
+	toolTypes "github.com/pardnchiu/agenvoy/internal/tools/types"
 	"github.com/pardnchiu/agenvoy/internal/utils"
 )
 
@@ -26,49 +27,203 @@ var skillExtensionPrompt string
 const (
 	MaxToolIterations  = 16
 	MaxSkillIterations = 128
+
+	continuePrompt = "你的回覆因長度限制被截斷，請從中斷處直接接續輸出，不要重複已輸出的內容，也不要加上任何前言。"
 )
 
-func Execute(ctx context.Context, agent agentTypes.Agent, workDir string, skill *skill.Skill, userInput string, events chan<- agentTypes.Event, allowAll bool) error {
+type ExecData struct {
+	Bot         agentTypes.Agent // summarizer, falls back to Agent
+	Agent       agentTypes.Agent
+	AgentName   string
+	WorkDir     string
+	Skill       *skill.Skill
+	SkillArgs   map[string]string // validated skill arguments, nil lets the model infer them
+	UserInput   string
+	AllowAll    bool
+	Pending     *sessionStore.PendingTurn    // resume an interrupted turn instead of starting a new one
+	Params      agentTypes.ParamsData        // generation params for Agent, the summarizer keeps its own
+	Attachments []agentTypes.ContentPart     // images and files sent with UserInput, history keeps their names only
+	Schema      *agentTypes.SchemaData       // the final answer must be JSON matching it, nil allows free text
+	Stage       *StageData                   // set by runStages, nil for a single skill
+	Tools       map[string]toolTypes.Handler // replace tools by name, they win over built-ins and skill scripts
+}
+
+// * stages share the session, it only keeps the original input and the last answer
+type StageData struct {
+	Input string // the user input before the stage prompt was added
+	Final bool   // only the last stage writes history and the summary
+}
+
+// * the new user message takes the attachments as parts, history only notes their names
+func attachMedia(session *agentTypes.AgentSession, attachments []agentTypes.ContentPart) {
+	if len(attachments) == 0 || len(session.Messages) == 0 {
+		return
+	}
+
+	last := &session.Messages[len(session.Messages)-1]
+	parts := []agentTypes.ContentPart{{Type: agentTypes.PartText, Text: agentTypes.ContentText(last.Content)}}
+	last.Content = append(parts, attachments...)
+	noteAttachments(session, attachments)
+}
+
+// * the history entry of the input lists the attachment names instead of their data
+func noteAttachments(session *agentTypes.AgentSession, attachments []agentTypes.ContentPart) {
+	if len(attachments) == 0 {
+		return
+	}
+	names := make([]string, 0, len(attachments))
+	for _, a := range attachments {
+		names = append(names, a.Name)
+	}
+	if n := len(session.Histories); n > 0 {
+		history := &session.Histories[n-1]
+		history.Content = fmt.Sprintf("%s\n附件：%s", agentTypes.ContentText(history.Content), strings.Join(names, "、"))
+	}
+}
+
+func Execute(ctx context.Context, data ExecData, events chan<- agentTypes.Event) error {
+	skill := data.Skill
 	// if skill is empty, then treat as no skill
 	if skill != nil && skill.Content == "" {
 		skill = nil
 	}
 
+	agent := data.Agent
+	// * summary falls back to the executing agent when no selector bot is given
+	bot := data.Bot
+	if bot == nil {
+		bot = agent
+	}
+
 	configDir, err := utils.GetConfigDir("sessions")
 	if err != nil {
 		return fmt.Errorf("utils.ConfigDir: %w", err)
 	}
 
-	prompt := getSystemPrompt(workDir, skill)
-	session, err := getSession(prompt, userInput)
+	var session *agentTypes.AgentSession
+	userInput := data.UserInput
+	turnID := fmt.Sprintf("%d", time.Now().UnixNano())
+	if data.Pending != nil {
+		session, err = resumeSession(data.Pending)
+		if err != nil {
+			return fmt.Errorf("resumeSession: %w", err)
+		}
+		userInput = data.Pending.Input
+		turnID = data.Pending.Turn
+		// * the resumed input still carries its media, history gets the names back
+		if n := len(session.Histories); n > 0 && len(data.Attachments) > 0 {
+			history := &session.Histories[n-1]
+			history.Content = agentTypes.ContentText(history.Content)
+			noteAttachments(session, data.Attachments)
+		}
+	} else {
+		prompt := getSystemPrompt(data.WorkDir, skill, data.SkillArgs)
+		// * providers without native support only learn the schema from here
+		if data.Schema != nil {
+			prompt += "\n\n" + data.Schema.Prompt()
+		}
+		session, err = getSession(prompt, userInput)
+		if err != nil {
+			return fmt.Errorf("getSession: %w", err)
+		}
+		if data.Stage != nil {
+			userInput = data.Stage.Input
+			session.Histories[len(session.Histories)-1].Content = fmt.Sprintf("ts:%d\n%s", time.Now().Unix(), strings.TrimSpace(userInput))
+		}
+		attachMedia(session, data.Attachments)
+	}
+
+	log, err := sessionStore.OpenLog(filepath.Join(configDir.Home, session.ID), turnID)
 	if err != nil {
-		return fmt.Errorf("getSession: %w", err)
+		slog.Warn("failed to open session log",
+			slog.String("error", err.Error()))
 	}
+	defer log.Close()
+	start := time.Now()
 
-	exec, err := tools.NewExecutor(workDir, session.ID)
+	if data.Pending == nil {
+		skillName := ""
+		if skill != nil {
+			skillName = skill.Name
+		}
+		// * the prompt and history repeat every turn, the log keeps them as blobs and only the input inline
+		last := len(session.Messages) - 1
+		log.Append(sessionStore.Record{
+			Type:    sessionStore.RecordTurnStart,
+			Agent:   data.AgentName,
+			Skill:   skillName,
+			Input:   userInput,
+			Context: log.StoreContext(session.Messages[:last]),
+
+			Schema:      data.Schema,
+			Attachments: data.Attachments,
+		})
+		logMessage(log, session.Messages[last])
+	}
+
+	exec, err := tools.NewExecutor(data.WorkDir, session.ID)
 	if err != nil {
 		return fmt.Errorf("tools.NewExecutor: %w", err)
 	}
+	if skill != nil {
+		exec.Tools = tools.Filter(exec.Tools, skill.AllowedTools)
+		// * declared scripts always belong to the skill, allowed-tools does not hide them
+		scriptTools, handlers := script.Tools(skill, data.WorkDir)
+		exec.Tools = append(exec.Tools, scriptTools...)
+		exec.Handlers = handlers
+	}
+	if len(data.Tools) > 0 {
+		merged := make(map[string]toolTypes.Handler, len(exec.Handlers)+len(data.Tools))
+		for name, handler := range exec.Handlers {
+			merged[name] = handler
+		}
+		for name, handler := range data.Tools {
+			merged[name] = handler
+		}
+		exec.Handlers = merged
+	}
 
 	limit := MaxToolIterations
 	if skill != nil {
 		limit = MaxSkillIterations
 	}
 
+	// * only the executing agent gets the params, summary and selector send their own options
+	opts := agentTypes.OptionsData{Params: data.Params, Schema: data.Schema}
+
+	// * earlier stages only hand their answer to the next one
+	record := data.Stage == nil || data.Stage.Final
+
+	// * truncated answers are continued and stitched, the pieces never enter the session themselves
+	maxContinuations := getContinuationLimit()
+	var partial strings.Builder
+	var continued []agentTypes.Message
+	continuations := 0
+	usage := &agentTypes.UsageData{}
+
 	alreadyCall := make(map[string]string)
 	emptyCount := 0
 	const maxEmpty = 3
 	for i := 0; i < limit; i++ {
-		resp, err := agent.Send(ctx, session.Messages, exec.Tools)
+		resp, err := agent.Send(ctx, append(session.Messages, continued...), exec.Tools, opts)
 		if err != nil {
+			log.Append(sessionStore.Record{
+				Type:  sessionStore.RecordError,
+				Error: err.Error(),
+			})
 			return err
 		}
+		usage.Add(resp.Usage)
 
 		if len(resp.Choices) == 0 {
 			emptyCount++
+			if emptyCount >= maxEmpty && data.Schema != nil {
+				return failTurn(log, fmt.Errorf("agent.Send: no answer matching the schema"))
+			}
 			if emptyCount >= maxEmpty {
 				events <- agentTypes.Event{Type: agentTypes.EventText, Text: "工具無法取得資料，請稍後再試或改用其他方式查詢。"}
-				events <- agentTypes.Event{Type: agentTypes.EventDone}
+				events <- agentTypes.Event{Type: agentTypes.EventDone, Usage: usage}
+				endTurn(log, start, "", usage)
 				return nil
 			}
 			continue
@@ -76,8 +231,34 @@ func Execute(ctx context.Context, agent agentTypes.Agent, workDir string, skill
 		emptyCount = 0
 
 		choice := resp.Choices[0]
+		if reasoning := strings.TrimSpace(choice.Message.Reasoning); reasoning != "" {
+			events <- agentTypes.Event{Type: agentTypes.EventReasoning, Text: reasoning}
+		}
+		if text, ok := choice.Message.Content.(string); ok && choice.FinishReason == agentTypes.FinishLength && len(choice.Message.ToolCalls) == 0 {
+			if continuations < maxContinuations {
+				continuations++
+				partial.WriteString(text)
+				continued = append(continued,
+					agentTypes.Message{Role: "assistant", Content: text},
+					agentTypes.Message{Role: "user", Content: continuePrompt},
+				)
+				continue
+			}
+			slog.Warn("response still truncated after continuations",
+				slog.Int("continuations", maxContinuations))
+		}
+
 		if len(choice.Message.ToolCalls) > 0 {
-			session, alreadyCall, err = toolCall(ctx, exec, choice, session, events, allowAll, alreadyCall)
+			// * a tool call in the middle of a continuation needs the earlier pieces as context
+			for _, m := range continued {
+				session.Messages = append(session.Messages, m)
+				logMessage(log, m)
+			}
+			// * the pieces now live in the session, the answer after the tools starts fresh
+			continued = nil
+			partial.Reset()
+			continuations = 0
+			session, alreadyCall, err = toolCall(ctx, exec, choice, session, events, data.AllowAll, alreadyCall, log)
 			if err != nil {
 				return err
 			}
@@ -86,44 +267,53 @@ func Execute(ctx context.Context, agent agentTypes.Agent, workDir string, skill
 
 		switch value := choice.Message.Content.(type) {
 		case string:
-			text := value
+			text := partial.String() + value
+			if data.Schema != nil {
+				answer, err := repairJSON(ctx, agent, append(session.Messages, continued...), text, opts, usage)
+				if err != nil {
+					return failTurn(log, err)
+				}
+				text = answer
+			}
 			if text == "" {
 				text = "工具無法取得資料，請稍後再試或改用其他方式查詢。"
 			}
-			cleaned := extractSummary(configDir, session.ID, text)
+			cleaned := text
+			if data.Schema == nil {
+				cleaned = extractSummary(text)
+			}
 
 			events <- agentTypes.Event{Type: agentTypes.EventText, Text: cleaned}
+			if record {
+				summarizeAsync(ctx, bot, configDir, session, userInput, cleaned)
+			}
 
 			choice.Message.Content = fmt.Sprintf("ts:%d\n%s", time.Now().Unix(), cleaned)
+			// * reasoning is only needed within the turn, histories keep the answer
+			choice.Message.Reasoning = ""
+			choice.Message.Thinking = nil
 
 			session.Messages = append(session.Messages, choice.Message)
+			logMessage(log, choice.Message)
 
-			err := writeHistory(choice, configDir, session)
-			if err != nil {
-				slog.Warn("Failed to write history",
-					slog.String("error", err.Error()))
+			if record {
+				if err := writeHistory(choice, configDir, session); err != nil {
+					slog.Warn("Failed to write history",
+						slog.String("error", err.Error()))
+				}
 			}
+			endTurn(log, start, cleaned, usage)
 		case nil:
+			if data.Schema != nil {
+				return failTurn(log, fmt.Errorf("agent.Send: no answer matching the schema"))
+			}
 			events <- agentTypes.Event{Type: agentTypes.EventText, Text: "工具無法取得資料，請稍後再試或改用其他方式查詢。"}
+			endTurn(log, start, "", usage)
 		default:
 			return fmt.Errorf("unexpected content type: %T", choice.Message.Content)
 		}
 
-		events <- agentTypes.Event{Type: agentTypes.EventDone}
-
-		if len(session.Tools) > 0 {
-			now := time.Now()
-			date := now.Format("2006-01-02")
-			dateWithSec := now.Format("2006-01-02-15-04-05")
-			toolActionsDir := filepath.Join(configDir.Work, session.ID, date)
-			if err := os.MkdirAll(toolActionsDir, 0755); err == nil {
-				filename := dateWithSec + ".json"
-				toolActionsPath := filepath.Join(toolActionsDir, filename)
-				if data, err := json.Marshal(session.Tools); err == nil {
-					os.WriteFile(toolActionsPath, data, 0644)
-				}
-			}
-		}
+		events <- agentTypes.Event{Type: agentTypes.EventDone, Usage: usage}
 		return nil
 	}
 
@@ -131,22 +321,74 @@ func Execute(ctx context.Context, agent agentTypes.Agent, workDir string, skill
 		Role:    "user",
 		Content: "請根據以上工具查詢結果，整理並總結回答原始問題。",
 	})
-	resp, err := agent.Send(ctx, summaryMessages, nil)
+	resp, err := agent.Send(ctx, summaryMessages, nil, opts)
+	if err == nil {
+		usage.Add(resp.Usage)
+	}
 	if err == nil && len(resp.Choices) > 0 {
-		if text, ok := resp.Choices[0].Message.Content.(string); ok && text != "" {
-			cleaned := extractSummary(configDir, session.ID, text)
+		if text, ok := resp.Choices[0].Message.Content.(string); ok && (text != "" || data.Schema != nil) {
+			cleaned := extractSummary(text)
+			if data.Schema != nil {
+				cleaned, err = repairJSON(ctx, agent, summaryMessages, text, opts, usage)
+				if err != nil {
+					return failTurn(log, err)
+				}
+			}
 			events <- agentTypes.Event{Type: agentTypes.EventText, Text: cleaned}
-			events <- agentTypes.Event{Type: agentTypes.EventDone}
+			events <- agentTypes.Event{Type: agentTypes.EventDone, Usage: usage}
+			if record {
+				summarizeAsync(ctx, bot, configDir, session, userInput, cleaned)
+			}
+			logMessage(log, agentTypes.Message{Role: "assistant", Content: cleaned})
+			endTurn(log, start, cleaned, usage)
 			return nil
 		}
 	}
 
+	if data.Schema != nil {
+		if err == nil {
+			err = fmt.Errorf("agent.Send: no answer matching the schema")
+		}
+		return failTurn(log, err)
+	}
 	events <- agentTypes.Event{Type: agentTypes.EventText, Text: "工具無法取得資料，請稍後再試或改用其他方式查詢。"}
-	events <- agentTypes.Event{Type: agentTypes.EventDone}
+	events <- agentTypes.Event{Type: agentTypes.EventDone, Usage: usage}
+	endTurn(log, start, "", usage)
 	return nil
 }
 
-func getSystemPrompt(workDir string, skill *skill.Skill) string {
+// * a schema turn without a valid answer ends with an error instead of free text
+func failTurn(log *sessionStore.Log, err error) error {
+	log.Append(sessionStore.Record{
+		Type:  sessionStore.RecordError,
+		Error: err.Error(),
+	})
+	return err
+}
+
+func logMessage(log *sessionStore.Log, message agentTypes.Message) {
+	if err := log.Append(sessionStore.Record{
+		Type:    sessionStore.RecordMessage,
+		Message: &message,
+	}); err != nil {
+		slog.Warn("failed to append session log",
+			slog.String("error", err.Error()))
+	}
+}
+
+func endTurn(log *sessionStore.Log, start time.Time, text string, usage *agentTypes.UsageData) {
+	record := sessionStore.Record{
+		Type:     sessionStore.RecordTurnEnd,
+		Result:   text,
+		Duration: time.Since(start).Milliseconds(),
+	}
+	if usage.InputTokens > 0 || usage.OutputTokens > 0 {
+		record.Usage = usage
+	}
+	log.Append(record)
+}
+
+func getSystemPrompt(workDir string, skill *skill.Skill, args map[string]string) string {
 	if skill == nil {
 		return strings.NewReplacer(
 			"{{.WorkPath}}", workDir,
@@ -155,15 +397,7 @@ func getSystemPrompt(workDir string, skill *skill.Skill) string {
 			"{{.Content}}", "",
 		).Replace(systemPrompt)
 	}
-	content := skill.Content
-
-	for _, prefix := range []string{"scripts/", "templates/", "assets/"} {
-		resolved := filepath.Join(skill.Path, prefix)
-
-		if _, err := os.Stat(resolved); err == nil {
-			content = strings.ReplaceAll(content, prefix, resolved+string(filepath.Separator))
-		}
-	}
+	content := skill.ResolvedContent() + getSkillArgsPrompt(skill, args)
 
 	return strings.NewReplacer(
 		"{{.WorkPath}}", workDir,
@@ -172,3 +406,40 @@ func getSystemPrompt(workDir string, skill *skill.Skill) string {
 		"{{.Content}}", content,
 	).Replace(systemPrompt)
 }
+
+func getSkillArgsPrompt(skill *skill.Skill, args map[string]string) string {
+	if len(skill.Arguments) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	// * explicit values were already validated by the caller
+	if args != nil {
+		sb.WriteString("\n\n技能參數：\n")
+		for _, a := range skill.Arguments {
+			if value, ok := args[a.Name]; ok {
+				sb.WriteString(fmt.Sprintf("- %s: %s\n", a.Name, value))
+			}
+		}
+		return sb.String()
+	}
+
+	sb.WriteString("\n\n技能參數（請從使用者輸入判斷，必填參數缺漏時先詢問使用者）：\n")
+	for _, a := range skill.Arguments {
+		line := fmt.Sprintf("- %s", a.Name)
+		if a.Required {
+			line += "（必填）"
+		}
+		if a.Description != "" {
+			line += "：" + a.Description
+		}
+		if len(a.Enum) > 0 {
+			line += fmt.Sprintf("，可選值：%s", strings.Join(a.Enum, "、"))
+		}
+		if a.Default != "" {
+			line += fmt.Sprintf("，預設：%s", a.Default)
+		}
+		sb.WriteString(line + "\n")
+	}
+	return sb.String()
+}
@@ -2,12 +2,8 @@ package exec
 
 import (
 	"encoding/json"
-	"os"
-	"path/filepath"
 	"regexp"
 	"strings"
-
-	"github.com/pardnchiu/agenvoy/internal/utils"
 )
 
 var (
@@ -27,59 +23,28 @@ func isSummaryJSON(m map[string]any) bool {
 	return matched >= 2
 }
 
-func extractSummary(configDir *utils.ConfigDirData, sessionID, value string) string {
+// * summary is maintained by updateSummary, only strip legacy inline blocks here
+func extractSummary(value string) string {
 	const summaryStart = "<!--SUMMARY_START-->"
-	const summaryEnd = "<!--SUMMARY_END-->"
-
-	var jsonData any
-	var cleaned string
 
-	// Primary: delimiter-wrapped summary
-	start := strings.Index(value, summaryStart)
-	end := strings.Index(value, summaryEnd)
-	if start != -1 && end != -1 && end > start {
-		jsonPart := strings.TrimSpace(value[start+len(summaryStart) : end])
-		json.Unmarshal([]byte(jsonPart), &jsonData)
+	cleaned := value
+	if start := strings.Index(value, summaryStart); start != -1 {
 		cleaned = strings.TrimRight(value[:start], " \t\n\r")
-	} else {
-		if start != -1 {
-			cleaned = strings.TrimRight(value[:start], " \t\n\r")
-		}
+	} else if loc := trailingJsonRegex.FindStringSubmatchIndex(value); loc != nil {
 		// Fallback: strip any trailing markdown JSON block that looks like a summary
-		if loc := trailingJsonRegex.FindStringSubmatchIndex(value); loc != nil {
-			jsonPart := value[loc[2]:loc[3]]
-			var m map[string]any
-			if json.Unmarshal([]byte(jsonPart), &m) == nil && isSummaryJSON(m) {
-				jsonData = m
-				cleaned = strings.TrimRight(value[:loc[0]], " \t\n\r")
-			}
-		}
-		if cleaned == "" {
-			cleaned = value
+		var m map[string]any
+		if json.Unmarshal([]byte(value[loc[2]:loc[3]]), &m) == nil && isSummaryJSON(m) {
+			cleaned = strings.TrimRight(value[:loc[0]], " \t\n\r")
 		}
 	}
 
-	if jsonData != nil {
-		path := filepath.Join(configDir.Home, sessionID, "summary.json")
-
-		if newMap, ok := jsonData.(map[string]any); ok {
-			if existing, err := os.ReadFile(path); err == nil {
-				var oldMap map[string]any
-				if json.Unmarshal(existing, &oldMap) == nil {
-					newMap = mergeSummary(oldMap, newMap)
-				}
-			}
-			jsonData = newMap
-		}
-
-		data, err := json.Marshal(jsonData)
-		if err == nil {
-			os.WriteFile(path, data, 0644)
-		}
+	if cleaned == "" {
+		return value
 	}
 	return cleaned
 }
 
+// * old entries missing from new are kept in front, so bounding drops the oldest first
 func mergeSummary(old, new map[string]any) map[string]any {
 	arrayFields := []string{
 		"confirmed_needs", "constraints", "excluded_options", "key_data", "current_conclusion",
@@ -91,12 +56,17 @@ func mergeSummary(old, new map[string]any) map[string]any {
 		for _, s := range newVals {
 			vals[s] = struct{}{}
 		}
+		merged := make([]string, 0, len(oldVals)+len(newVals))
 		for _, s := range oldVals {
 			if _, exist := vals[s]; !exist {
-				newVals = append(newVals, s)
+				merged = append(merged, s)
 			}
 		}
-		new[field] = newVals
+		merged = append(merged, newVals...)
+		if len(merged) > maxSummaryItems {
+			merged = merged[len(merged)-maxSummaryItems:]
+		}
+		new[field] = merged
 	}
 
 	// { "conclusion": "resolved", "time": "2026-02-27 23:57", "topic": "DGX Spark vs Ryzen Halo 比較" },
@@ -108,16 +78,21 @@ func mergeSummary(old, new map[string]any) map[string]any {
 			vals[t] = struct{}{}
 		}
 	}
+	merged := make([]map[string]any, 0, len(oldVals)+len(newVals))
 	for _, val := range oldVals {
 		t, ok := val["topic"].(string)
 		if !ok {
 			continue
 		}
 		if _, exist := vals[t]; !exist {
-			newVals = append(newVals, val)
+			merged = append(merged, val)
 		}
 	}
-	new["discussion_log"] = newVals
+	merged = append(merged, newVals...)
+	if len(merged) > maxSummaryItems {
+		merged = merged[len(merged)-maxSummaryItems:]
+	}
+	new["discussion_log"] = merged
 
 	return new
 }
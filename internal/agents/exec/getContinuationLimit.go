@@ -0,0 +1,40 @@
+package exec
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/pardnchiu/agenvoy/internal/utils"
+)
+
+const defaultMaxContinuations = 3
+
+// * max_continuations in config.json, 0 or unset uses the default and negative disables continuation
+func getContinuationLimit() int {
+	configDir, err := utils.GetConfigDir()
+	if err != nil {
+		return defaultMaxContinuations
+	}
+
+	for _, dir := range configDir.Dirs {
+		data, err := os.ReadFile(filepath.Join(dir, "config.json"))
+		if err != nil {
+			continue
+		}
+		var cfg struct {
+			MaxContinuations *int `json:"max_continuations"`
+		}
+		if json.Unmarshal(data, &cfg) != nil || cfg.MaxContinuations == nil {
+			continue
+		}
+		switch {
+		case *cfg.MaxContinuations < 0:
+			return 0
+		case *cfg.MaxContinuations == 0:
+			return defaultMaxContinuations
+		}
+		return *cfg.MaxContinuations
+	}
+	return defaultMaxContinuations
+}
@@ -0,0 +1,50 @@
+package exec
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/pardnchiu/agenvoy/internal/utils"
+)
+
+// * legacy behaviour, nvidia default model was always used as selector
+var defaultSelectorModels = []string{"nvidia@openai/gpt-oss-120b"}
+
+type SelectorConfigData struct {
+	Models    []string `json:"models"`
+	Local     bool     `json:"local"`
+	Threshold float64  `json:"threshold"`
+	Compose   string   `json:"compose"`
+}
+
+func GetSelectorConfig() SelectorConfigData {
+	configDir, err := utils.GetConfigDir()
+	if err != nil {
+		return SelectorConfigData{Models: defaultSelectorModels, Threshold: defaultRouteThreshold, Compose: ComposeMerge}
+	}
+
+	for _, dir := range configDir.Dirs {
+		data, err := os.ReadFile(filepath.Join(dir, "config.json"))
+		if err != nil {
+			continue
+		}
+		var cfg struct {
+			Selector *SelectorConfigData `json:"selector"`
+		}
+		if json.Unmarshal(data, &cfg) != nil || cfg.Selector == nil {
+			continue
+		}
+		if len(cfg.Selector.Models) == 0 {
+			cfg.Selector.Models = defaultSelectorModels
+		}
+		if cfg.Selector.Threshold <= 0 {
+			cfg.Selector.Threshold = defaultRouteThreshold
+		}
+		if cfg.Selector.Compose == "" {
+			cfg.Selector.Compose = ComposeMerge
+		}
+		return *cfg.Selector
+	}
+	return SelectorConfigData{Models: defaultSelectorModels, Threshold: defaultRouteThreshold, Compose: ComposeMerge}
+}
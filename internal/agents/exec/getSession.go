@@ -9,10 +9,10 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
-	"syscall"
 	"time"
 
 	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+	sessionStore "github.com/pardnchiu/agenvoy/internal/session"
 	"github.com/pardnchiu/agenvoy/internal/utils"
 )
 
@@ -44,9 +44,9 @@ func getSession(prompt string, userInput string) (*agentTypes.AgentSession, erro
 	}
 
 	indexJsonPath := filepath.Join(configDir.Home, "..", "config.json")
-	unlock, err := lockConfig(filepath.Dir(indexJsonPath))
+	unlock, err := sessionStore.LockConfig(filepath.Dir(indexJsonPath))
 	if err != nil {
-		return nil, fmt.Errorf("lockConfig: %w", err)
+		return nil, fmt.Errorf("sessionStore.LockConfig: %w", err)
 	}
 	defer unlock()
 
@@ -89,20 +89,20 @@ func getSession(prompt string, userInput string) (*agentTypes.AgentSession, erro
 					Content: summary,
 				})
 			}
-
-			session.Histories = append(session.Histories, agentTypes.Message{
-				Role:    "user",
-				Content: fmt.Sprintf("ts:%s\n%s", now, trimInput),
-			})
-			session.Messages = append(session.Messages, agentTypes.Message{
-				Role:    "user",
-				Content: fmt.Sprintf("ts:%s\n%s", now, trimInput),
-			})
 		}
 
+		session.Histories = append(session.Histories, agentTypes.Message{
+			Role:    "user",
+			Content: fmt.Sprintf("ts:%s\n%s", now, trimInput),
+		})
+		session.Messages = append(session.Messages, agentTypes.Message{
+			Role:    "user",
+			Content: fmt.Sprintf("ts:%s\n%s", now, trimInput),
+		})
+
 	case os.IsNotExist(configErr):
 		// * config is not exist
-		sessionID, err := newSessionID()
+		sessionID, err = newSessionID()
 		if err != nil {
 			return nil, fmt.Errorf("newSessionID: %w", err)
 		}
@@ -150,24 +150,6 @@ func getSession(prompt string, userInput string) (*agentTypes.AgentSession, erro
 	return &session, nil
 }
 
-func lockConfig(dir string) (func(), error) {
-	lockPath := filepath.Join(dir, "config.json.lock")
-	file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_WRONLY, 0600)
-	if err != nil {
-		return nil, fmt.Errorf("os.OpenFile: %w", err)
-	}
-
-	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
-		file.Close()
-		return nil, fmt.Errorf("syscall.Flock: %w", err)
-	}
-
-	return func() {
-		_ = syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
-		file.Close()
-	}, nil
-}
-
 func newSessionID() (string, error) {
 	b := make([]byte, 16)
 	if _, err := rand.Read(b); err != nil {
@@ -178,3 +160,63 @@ func newSessionID() (string, error) {
 	h := hex.EncodeToString(b)
 	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], nil
 }
+
+func resumeSession(pending *sessionStore.PendingTurn) (*agentTypes.AgentSession, error) {
+	configDir, err := utils.GetConfigDir("sessions")
+	if err != nil {
+		return nil, fmt.Errorf("utils.ConfigDir: %w", err)
+	}
+
+	sessionID, err := getSessionID(configDir)
+	if err != nil {
+		return nil, fmt.Errorf("getSessionID: %w", err)
+	}
+
+	session := agentTypes.AgentSession{
+		ID:        sessionID,
+		Tools:     []agentTypes.Message{},
+		Messages:  append([]agentTypes.Message(nil), pending.Messages...),
+		Histories: []agentTypes.Message{},
+	}
+
+	if historyData, err := os.ReadFile(filepath.Join(configDir.Home, sessionID, "history.json")); err == nil {
+		var oldHistory []agentTypes.Message
+		if err := json.Unmarshal(historyData, &oldHistory); err == nil {
+			session.Histories = oldHistory
+		}
+	}
+
+	// * history only gets the user message once the turn completes
+	for i := len(pending.Messages) - 1; i >= 0; i-- {
+		if pending.Messages[i].Role == "user" {
+			session.Histories = append(session.Histories, pending.Messages[i])
+			break
+		}
+	}
+
+	for _, m := range pending.Messages {
+		if m.Role == "tool" {
+			session.Tools = append(session.Tools, m)
+		}
+	}
+
+	return &session, nil
+}
+
+func getSessionID(configDir *utils.ConfigDirData) (string, error) {
+	data, err := os.ReadFile(filepath.Join(configDir.Home, "..", "config.json"))
+	if err != nil {
+		return "", fmt.Errorf("os.ReadFile: %w", err)
+	}
+
+	var indexData IndexData
+	if err := json.Unmarshal(data, &indexData); err != nil {
+		return "", fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	sessionID := strings.TrimSpace(indexData.SessionID)
+	if sessionID == "" {
+		return "", fmt.Errorf("session_id is empty")
+	}
+	return sessionID, nil
+}
@@ -0,0 +1,160 @@
+package exec
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// * explicit sequencing words, "generate the changelog and then draft release notes"
+var stageRegex = regexp.MustCompile(`(?i)[,，;；]?\s*(?:\band then\b|\bthen\b|\bafter that\b|\bfollowed by\b|然後|接著|再來|之後再|最後再)\s*`)
+
+// * filler words carry no routing signal
+var stopWords = map[string]struct{}{
+	"the": {}, "and": {}, "for": {}, "with": {}, "this": {}, "that": {}, "is": {}, "are": {},
+	"to": {}, "of": {}, "in": {}, "on": {}, "me": {}, "my": {}, "it": {}, "an": {},
+	"please": {}, "can": {}, "you": {}, "what": {}, "how": {},
+}
+
+// * tf-idf cosine between input and each candidate name + description, "" when nothing overlaps
+func localSelect(input string, candidates map[string]string) (string, float64) {
+	query := tokenize(input)
+	if len(query) == 0 || len(candidates) == 0 {
+		return "", 0
+	}
+
+	names := make([]string, 0, len(candidates))
+	for name := range candidates {
+		names = append(names, name)
+	}
+	// * stable winner on ties
+	sort.Strings(names)
+
+	docs := make([]map[string]float64, len(names))
+	df := make(map[string]int)
+	for i, name := range names {
+		docs[i] = termFreq(tokenize(name + " " + candidates[name]))
+		for t := range docs[i] {
+			df[t]++
+		}
+	}
+
+	n := float64(len(names))
+	idf := func(t string) float64 {
+		return math.Log((n+1)/float64(df[t]+1)) + 1
+	}
+
+	queryVec := termFreq(query)
+	queryNorm := 0.0
+	for t, tf := range queryVec {
+		queryVec[t] = tf * idf(t)
+		queryNorm += queryVec[t] * queryVec[t]
+	}
+	queryNorm = math.Sqrt(queryNorm)
+
+	best, bestScore := "", 0.0
+	for i, name := range names {
+		dot, docNorm := 0.0, 0.0
+		for t, tf := range docs[i] {
+			w := tf * idf(t)
+			docNorm += w * w
+			dot += w * queryVec[t]
+		}
+		if dot == 0 {
+			continue
+		}
+		score := dot / (queryNorm * math.Sqrt(docNorm))
+		if score > bestScore {
+			best, bestScore = name, score
+		}
+	}
+	return best, bestScore
+}
+
+// * sublinear tf, a repeated word should not dominate a short description
+func termFreq(tokens []string) map[string]float64 {
+	counts := make(map[string]int, len(tokens))
+	for _, t := range tokens {
+		counts[t]++
+	}
+	tf := make(map[string]float64, len(counts))
+	for t, c := range counts {
+		tf[t] = 1 + math.Log(float64(c))
+	}
+	return tf
+}
+
+// * latin words are split on non letters, CJK text has no spaces so use rune bigrams
+func tokenize(text string) []string {
+	var tokens []string
+	var word []rune
+	var han []rune
+
+	flushWord := func() {
+		if len(word) > 1 {
+			if _, ok := stopWords[string(word)]; !ok {
+				tokens = append(tokens, string(word))
+			}
+		}
+		word = word[:0]
+	}
+	flushHan := func() {
+		if len(han) == 1 {
+			tokens = append(tokens, string(han))
+		}
+		for i := 0; i+1 < len(han); i++ {
+			tokens = append(tokens, string(han[i:i+2]))
+		}
+		han = han[:0]
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flushWord()
+			han = append(han, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushHan()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushHan()
+		}
+	}
+	flushWord()
+	flushHan()
+	return tokens
+}
+
+// * each stage of a sequenced request must match a different skill on its own
+func localStages(input string, candidates map[string]string, threshold float64) ([]string, float64) {
+	segments := stageRegex.Split(input, -1)
+	if len(segments) < 2 {
+		return nil, 0
+	}
+
+	var names []string
+	minScore := math.Inf(1)
+	seen := make(map[string]bool, len(segments))
+	for _, segment := range segments {
+		if strings.TrimSpace(segment) == "" {
+			continue
+		}
+		name, score := localSelect(segment, candidates)
+		if name == "" || score < threshold {
+			return nil, 0
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+		minScore = math.Min(minScore, score)
+	}
+	if len(names) < 2 {
+		return nil, 0
+	}
+	return names, minScore
+}
@@ -0,0 +1,78 @@
+package exec
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+)
+
+const (
+	defaultRouteThreshold = 0.25
+
+	RouteLocal    = "local"
+	RouteCache    = "cache"
+	RouteLLM      = "llm"
+	RouteOverride = "override"
+	RouteSkill    = "skill"
+)
+
+type routeData struct {
+	Name   string
+	Names  []string // ordered skills when a request composes several, Name is the first
+	Score  float64
+	Source string
+}
+
+// * local score first, cached or LLM decision only when local is not confident enough
+func route(ctx context.Context, bot agentTypes.Agent, kind string, candidates map[string]string, userInput string, threshold float64, ask func() (string, error)) routeData {
+	name, score := localSelect(userInput, candidates)
+	if score >= threshold {
+		return routeData{Name: name, Score: score, Source: RouteLocal}
+	}
+	// * nobody to ask, a weak local match is no match
+	if bot == nil {
+		return routeData{Score: score, Source: RouteLocal}
+	}
+
+	key := routeKey(kind, candidates, userInput)
+	if cached, ok := getRouteCache(key); ok {
+		if names := splitNames(cached, candidates); len(names) > 0 || cached == "" {
+			return routeData{Name: cached, Score: score, Source: RouteCache}
+		}
+	}
+
+	answer, err := ask()
+	if err != nil {
+		// * whole selector chain failed or timed out, a weak local match is still no match
+		slog.Warn("selector failed",
+			slog.String("kind", kind),
+			slog.String("error", err.Error()))
+		return routeData{Score: score, Source: RouteLLM}
+	}
+
+	if err := setRouteCache(key, answer); err != nil {
+		slog.Warn("failed to cache route",
+			slog.String("kind", kind),
+			slog.String("error", err.Error()))
+	}
+	return routeData{Name: answer, Score: score, Source: RouteLLM}
+}
+
+// * selector answers may list several names in order, unknown ones are dropped
+func splitNames(answer string, candidates map[string]string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, part := range strings.FieldsFunc(answer, func(r rune) bool {
+		return r == ',' || r == '，' || r == '\n'
+	}) {
+		name := strings.Trim(strings.TrimSpace(part), "\"'` ")
+		if _, ok := candidates[name]; !ok || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
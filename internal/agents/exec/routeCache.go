@@ -0,0 +1,119 @@
+package exec
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pardnchiu/agenvoy/internal/utils"
+)
+
+const (
+	routeCacheFile = "router.json"
+	maxRouteCache  = 512
+)
+
+var routeCacheMu sync.Mutex
+
+type routeCacheEntry struct {
+	Name string `json:"name"`
+	Time int64  `json:"time"`
+}
+
+// * candidates are part of the key, adding or editing a skill invalidates old decisions
+func routeKey(kind string, candidates map[string]string, input string) string {
+	names := make([]string, 0, len(candidates))
+	for name := range candidates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	h.Write([]byte(kind))
+	for _, name := range names {
+		h.Write([]byte{0})
+		h.Write([]byte(name))
+		h.Write([]byte{0})
+		h.Write([]byte(candidates[name]))
+	}
+	h.Write([]byte{0})
+	h.Write([]byte(strings.Join(strings.Fields(strings.ToLower(input)), " ")))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func loadRouteCache() (map[string]routeCacheEntry, string, error) {
+	configDir, err := utils.GetConfigDir()
+	if err != nil {
+		return nil, "", fmt.Errorf("utils.GetConfigDir: %w", err)
+	}
+	path := filepath.Join(configDir.Home, routeCacheFile)
+
+	cache := make(map[string]routeCacheEntry)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cache, path, nil
+		}
+		return nil, "", fmt.Errorf("os.ReadFile: %w", err)
+	}
+	// * corrupted cache is only a miss
+	_ = json.Unmarshal(data, &cache)
+	return cache, path, nil
+}
+
+func getRouteCache(key string) (string, bool) {
+	routeCacheMu.Lock()
+	defer routeCacheMu.Unlock()
+
+	cache, _, err := loadRouteCache()
+	if err != nil {
+		return "", false
+	}
+	entry, ok := cache[key]
+	return entry.Name, ok
+}
+
+func setRouteCache(key, name string) error {
+	routeCacheMu.Lock()
+	defer routeCacheMu.Unlock()
+
+	cache, path, err := loadRouteCache()
+	if err != nil {
+		return err
+	}
+	cache[key] = routeCacheEntry{Name: name, Time: time.Now().Unix()}
+
+	// * drop the oldest decisions once over the limit
+	if len(cache) > maxRouteCache {
+		keys := make([]string, 0, len(cache))
+		for k := range cache {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return cache[keys[i]].Time < cache[keys[j]].Time
+		})
+		for _, k := range keys[:len(cache)-maxRouteCache] {
+			delete(cache, k)
+		}
+	}
+
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("os.WriteFile: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("os.Rename: %w", err)
+	}
+	return nil
+}
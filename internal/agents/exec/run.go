@@ -3,56 +3,322 @@ package exec
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+	sessionStore "github.com/pardnchiu/agenvoy/internal/session"
 	"github.com/pardnchiu/agenvoy/internal/skill"
+	"github.com/pardnchiu/agenvoy/internal/tools/file"
+	toolTypes "github.com/pardnchiu/agenvoy/internal/tools/types"
+	"github.com/pardnchiu/agenvoy/internal/utils"
 )
 
+const (
+	selectTimeout = 30 * time.Second
+
+	ComposeMerge  = "merge"
+	ComposeStages = "stages"
+)
+
+// OverrideData bypasses the selectors, names are validated against scanner and registry.
+type OverrideData struct {
+	Skill       string // comma separated names compose several skills in order
+	NoSkill     bool
+	Agent       string
+	Args        map[string]string            // skill arguments, only valid together with a single Skill
+	Compose     string                       // ComposeMerge or ComposeStages, empty uses selector.compose
+	Params      agentTypes.ParamsData        // applied over the model entry and skill params
+	Attachments []string                     // image or pdf paths, relative ones resolve against the work dir
+	Schema      *agentTypes.SchemaData       // final answer as JSON matching it, selectors are unaffected
+	Tools       map[string]toolTypes.Handler // replace tools by name for this run, e.g. offline fakes in tests
+}
+
 func Run(ctx context.Context, bot agentTypes.Agent, registry agentTypes.AgentRegistry, scanner *skill.Scanner, userInput string, events chan<- agentTypes.Event, allowAll bool) error {
+	return RunWithOverride(ctx, bot, registry, scanner, userInput, OverrideData{}, events, allowAll)
+}
+
+func RunWithOverride(ctx context.Context, bot agentTypes.Agent, registry agentTypes.AgentRegistry, scanner *skill.Scanner, userInput string, override OverrideData, events chan<- agentTypes.Event, allowAll bool) error {
 	workDir, err := os.Getwd()
 	if err != nil {
 		return fmt.Errorf("os.Getwd: %w", err)
 	}
 
 	trimInput := strings.TrimSpace(userInput)
+	skillOverride := strings.TrimSpace(override.Skill)
+	agentOverride := strings.TrimSpace(override.Agent)
 
-	events <- agentTypes.Event{
-		Type: agentTypes.EventSkillSelect,
+	if skillOverride != "" && override.NoSkill {
+		return fmt.Errorf("skill override conflicts with no skill")
+	}
+	var overrideNames []string
+	var overrideSkills []*skill.Skill
+	for _, name := range strings.Split(skillOverride, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		s, ok := scanner.Get(name)
+		if !ok {
+			return fmt.Errorf("skill not found: %s", name)
+		}
+		overrideNames = append(overrideNames, name)
+		overrideSkills = append(overrideSkills, s)
+	}
+	if len(override.Args) > 0 && len(overrideSkills) != 1 {
+		return fmt.Errorf("skill arguments require a single skill override")
+	}
+	var skillArgs map[string]string
+	if len(overrideSkills) == 1 {
+		skillArgs, err = overrideSkills[0].ResolveArgs(override.Args)
+		if err != nil {
+			return fmt.Errorf("ResolveArgs: %w", err)
+		}
+	}
+	if _, ok := registry.Registry[agentOverride]; agentOverride != "" && !ok {
+		return fmt.Errorf("agent not available: %s", agentOverride)
+	}
+	if err := override.Params.Validate(); err != nil {
+		return fmt.Errorf("override.Params: %w", err)
 	}
-	matchedSkill := selectSkill(ctx, bot, scanner, trimInput)
-	if matchedSkill != nil {
-		events <- agentTypes.Event{
-			Type: agentTypes.EventSkillResult,
-			Text: strings.TrimSpace(matchedSkill.Name),
+	var attachments []agentTypes.ContentPart
+	for _, path := range override.Attachments {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(workDir, path)
 		}
+		part, err := file.LoadMedia(path)
+		if err != nil {
+			return fmt.Errorf("file.LoadMedia: %w", err)
+		}
+		attachments = append(attachments, *part)
+	}
+
+	cfg := GetSelectorConfig()
+	threshold := cfg.Threshold
+	compose := override.Compose
+	if compose == "" {
+		compose = cfg.Compose
+	}
+	if compose != ComposeMerge && compose != ComposeStages {
+		return fmt.Errorf("unknown compose mode: %s", compose)
+	}
+
+	// * both selections are independent, run them together under one deadline
+	selectCtx, cancel := context.WithTimeout(ctx, selectTimeout)
+	defer cancel()
+
+	skillCh := make(chan routeData, 1)
+	agentCh := make(chan routeData, 1)
+	if len(overrideNames) > 0 || override.NoSkill {
+		r := routeData{Names: overrideNames, Source: RouteOverride}
+		if len(overrideNames) > 0 {
+			r.Name = overrideNames[0]
+		}
+		skillCh <- r
 	} else {
-		events <- agentTypes.Event{
-			Type: agentTypes.EventSkillResult,
-			Text: "none",
+		go func() {
+			skillCh <- selectSkill(selectCtx, bot, scanner, trimInput, threshold)
+		}()
+	}
+	// * a skill with its own model makes agent selection moot, cancel it separately
+	agentCtx, cancelAgent := context.WithCancel(selectCtx)
+	defer cancelAgent()
+	if agentOverride != "" {
+		agentCh <- routeData{Name: agentOverride, Source: RouteOverride}
+	} else {
+		go func() {
+			agentCh <- selectAgent(agentCtx, bot, registry.Entries, trimInput, threshold)
+		}()
+	}
+
+	// * events keep the sequential order, skill result always comes before agent select
+	events <- agentTypes.Event{
+		Type: agentTypes.EventSkillSelect,
+	}
+	skillRoute := <-skillCh
+	var matchedSkills []*skill.Skill
+	var matchedNames []string
+	for _, name := range skillRoute.Names {
+		if s, ok := scanner.Get(name); ok {
+			matchedSkills = append(matchedSkills, s)
+			matchedNames = append(matchedNames, strings.TrimSpace(s.Name))
 		}
 	}
+	var matchedSkill *skill.Skill
+	skillName := "none"
+	if len(matchedSkills) > 0 {
+		matchedSkill = skill.Compose(matchedSkills)
+		skillName = strings.Join(matchedNames, " → ")
+	}
+	events <- agentTypes.Event{
+		Type:   agentTypes.EventSkillResult,
+		Text:   skillName,
+		Source: skillRoute.Source,
+		Score:  skillRoute.Score,
+	}
 
 	events <- agentTypes.Event{
 		Type: agentTypes.EventAgentSelect,
 	}
 	// * default is fallback
 	agent := registry.Fallback
-	if chosen := selectAgent(ctx, bot, registry.Entries, trimInput); chosen != "" {
-		if a, ok := registry.Registry[chosen]; ok {
-			agent = a
+	agentName := ""
+	var agentRoute routeData
+	if _, ok := registry.Registry[skillModel(matchedSkill)]; ok && agentOverride == "" {
+		cancelAgent()
+		agentRoute = routeData{Name: matchedSkill.Model, Source: RouteSkill}
+	} else {
+		if model := skillModel(matchedSkill); model != "" && agentOverride == "" {
+			slog.Warn("skill model not available",
+				slog.String("skill", matchedSkill.Name),
+				slog.String("model", model))
 		}
-		events <- agentTypes.Event{
-			Type: agentTypes.EventAgentResult,
-			Text: strings.TrimSpace(chosen),
+		agentRoute = <-agentCh
+	}
+	if a, ok := registry.Registry[agentRoute.Name]; ok {
+		agent = a
+		agentName = agentRoute.Name
+	}
+	resultName := "fallback"
+	if agentName != "" {
+		resultName = agentName
+	}
+	events <- agentTypes.Event{
+		Type:   agentTypes.EventAgentResult,
+		Text:   resultName,
+		Source: agentRoute.Source,
+		Score:  agentRoute.Score,
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	params, err := getParams(registry, agentName, matchedSkill, override.Params)
+	if err != nil {
+		return err
+	}
+	data := ExecData{
+		Bot:         bot,
+		Agent:       agent,
+		AgentName:   agentName,
+		WorkDir:     workDir,
+		Skill:       matchedSkill,
+		SkillArgs:   skillArgs,
+		UserInput:   trimInput,
+		AllowAll:    allowAll,
+		Params:      params,
+		Attachments: attachments,
+		Schema:      override.Schema,
+		Tools:       override.Tools,
+	}
+	if compose == ComposeStages && len(matchedSkills) > 1 {
+		return runStages(ctx, data, matchedSkills, registry, agentOverride == "", override.Params, events)
+	}
+	return Execute(ctx, data, events)
+}
+
+// * model entry first, then the skill, then the run override, later ones win
+func getParams(registry agentTypes.AgentRegistry, agentName string, s *skill.Skill, override agentTypes.ParamsData) (agentTypes.ParamsData, error) {
+	params := registry.Params(agentName)
+	if err := params.Validate(); err != nil {
+		return params, fmt.Errorf("agent %s params: %w", agentName, err)
+	}
+	if s != nil {
+		skillParams := agentTypes.ParamsData(s.Params)
+		if err := skillParams.Validate(); err != nil {
+			return params, fmt.Errorf("skill %s params: %w", s.Name, err)
 		}
-	} else {
-		events <- agentTypes.Event{
-			Type: agentTypes.EventAgentResult,
-			Text: "fallback",
+		params = params.Merge(skillParams)
+	}
+	return params.Merge(override), nil
+}
+
+func skillModel(s *skill.Skill) string {
+	if s == nil {
+		return ""
+	}
+	return s.Model
+}
+
+// Resume continues the last interrupted turn of the current session from its event log.
+func Resume(ctx context.Context, bot agentTypes.Agent, registry agentTypes.AgentRegistry, scanner *skill.Scanner, events chan<- agentTypes.Event, allowAll bool) error {
+	workDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("os.Getwd: %w", err)
+	}
+
+	configDir, err := utils.GetConfigDir("sessions")
+	if err != nil {
+		return fmt.Errorf("utils.ConfigDir: %w", err)
+	}
+
+	sessionID, err := getSessionID(configDir)
+	if err != nil {
+		return fmt.Errorf("getSessionID: %w", err)
+	}
+
+	records, err := sessionStore.ReadLog(filepath.Join(configDir.Home, sessionID))
+	if err != nil {
+		return fmt.Errorf("sessionStore.ReadLog: %w", err)
+	}
+
+	pending := sessionStore.GetPendingTurn(records)
+	if pending == nil {
+		return fmt.Errorf("no interrupted turn to resume")
+	}
+	if err := sessionStore.RestorePending(filepath.Join(configDir.Home, sessionID), pending); err != nil {
+		return fmt.Errorf("sessionStore.RestorePending: %w", err)
+	}
+
+	// * a merged composition is logged as a+b, rebuild it from its parts
+	var parts []*skill.Skill
+	for _, name := range strings.Split(pending.Skill, "+") {
+		if s, ok := scanner.Get(name); ok && name != "" {
+			parts = append(parts, s)
 		}
 	}
+	var matchedSkill *skill.Skill
+	skillName := "none"
+	if len(parts) > 0 {
+		matchedSkill = skill.Compose(parts)
+		skillName = matchedSkill.Name
+	}
+	events <- agentTypes.Event{
+		Type: agentTypes.EventSkillResult,
+		Text: skillName,
+	}
+
+	agent := registry.Fallback
+	agentName := "fallback"
+	if a, ok := registry.Registry[pending.Agent]; ok {
+		agent = a
+		agentName = pending.Agent
+	}
+	events <- agentTypes.Event{
+		Type: agentTypes.EventAgentResult,
+		Text: agentName,
+	}
+
+	params, err := getParams(registry, pending.Agent, matchedSkill, agentTypes.ParamsData{})
+	if err != nil {
+		return err
+	}
 
-	return Execute(ctx, agent, workDir, matchedSkill, trimInput, events, allowAll)
+	return Execute(ctx, ExecData{
+		Bot:       bot,
+		Agent:     agent,
+		AgentName: pending.Agent,
+		WorkDir:   workDir,
+		Skill:     matchedSkill,
+		UserInput: pending.Input,
+		AllowAll:  allowAll,
+		Pending:   pending,
+		Params:    params,
+		// * a schema run keeps its validation, the media stays with the input
+		Schema:      pending.Schema,
+		Attachments: pending.Attachments,
+	}, events)
 }
@@ -0,0 +1,103 @@
+package exec
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"strings"
+
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+	"github.com/pardnchiu/agenvoy/internal/skill"
+)
+
+//go:embed prompt/skillStage.md
+var skillStagePrompt string
+
+// * one Execute per skill on the same session, the answer of each stage is handed to the next one
+func runStages(ctx context.Context, data ExecData, skills []*skill.Skill, registry agentTypes.AgentRegistry, useSkillModel bool, params agentTypes.ParamsData, events chan<- agentTypes.Event) error {
+	names := make([]string, len(skills))
+	for i, s := range skills {
+		names[i] = s.Name
+	}
+
+	output := ""
+	for i, s := range skills {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		stage := data
+		stage.Skill = s
+		stage.UserInput = getStageInput(data.UserInput, names, i, output)
+		stage.Stage = &StageData{Input: data.UserInput, Final: i == len(skills)-1}
+		// * earlier stages hand text to the next one, only the last answer is shaped
+		if i < len(skills)-1 {
+			stage.Schema = nil
+		}
+		source := ""
+		if a, ok := registry.Registry[s.Model]; ok && useSkillModel {
+			stage.Agent = a
+			stage.AgentName = s.Model
+			source = RouteSkill
+		}
+		var err error
+		stage.Params, err = getParams(registry, stage.AgentName, s, params)
+		if err != nil {
+			return err
+		}
+
+		events <- agentTypes.Event{
+			Type: agentTypes.EventSkillResult,
+			Text: fmt.Sprintf("%s (%d/%d)", s.Name, i+1, len(skills)),
+		}
+		if source != "" {
+			events <- agentTypes.Event{
+				Type:   agentTypes.EventAgentResult,
+				Text:   s.Model,
+				Source: source,
+			}
+		}
+
+		output, err = executeStage(ctx, stage, events)
+		if err != nil {
+			return fmt.Errorf("stage %d %s: %w", i+1, s.Name, err)
+		}
+	}
+	return nil
+}
+
+// * forwards every event and keeps the last text, which is the stage answer
+func executeStage(ctx context.Context, data ExecData, events chan<- agentTypes.Event) (string, error) {
+	stageEvents := make(chan agentTypes.Event)
+	done := make(chan struct{})
+	output := ""
+	go func() {
+		defer close(done)
+		for ev := range stageEvents {
+			if ev.Type == agentTypes.EventText {
+				output = ev.Text
+			}
+			events <- ev
+		}
+	}()
+
+	err := Execute(ctx, data, stageEvents)
+	close(stageEvents)
+	<-done
+	return output, err
+}
+
+func getStageInput(input string, names []string, index int, previous string) string {
+	text := strings.NewReplacer(
+		"{{.Input}}", input,
+		"{{.Index}}", fmt.Sprintf("%d", index+1),
+		"{{.Total}}", fmt.Sprintf("%d", len(names)),
+		"{{.Skill}}", names[index],
+		"{{.Stages}}", strings.Join(names, " → "),
+	).Replace(strings.TrimSpace(skillStagePrompt))
+
+	if previous != "" {
+		text += fmt.Sprintf("\n\n前一階段（%s）的輸出：\n%s", names[index-1], previous)
+	}
+	return text
+}
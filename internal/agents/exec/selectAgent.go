@@ -48,55 +48,70 @@ func GetAgentEntries() []agentTypes.AgentEntry {
 	return []agentTypes.AgentEntry{}
 }
 
-func selectAgent(ctx context.Context, bot agentTypes.Agent, agentEntries []agentTypes.AgentEntry, userInput string) string {
+func selectAgent(ctx context.Context, bot agentTypes.Agent, agentEntries []agentTypes.AgentEntry, userInput string, threshold float64) routeData {
 	trimInput := strings.TrimSpace(userInput)
 
 	if len(agentEntries) == 0 {
-		return ""
+		return routeData{Source: RouteLocal}
 	}
 
-	agentMap := make(map[string]struct{}, len(agentEntries))
-	for _, a := range agentEntries {
-		agentMap[a.Name] = struct{}{}
+	agentMap := make(map[string]string, len(agentEntries))
+	// * params mean nothing to the selector, only name and description are sent
+	candidates := make([]agentTypes.AgentEntry, len(agentEntries))
+	for i, a := range agentEntries {
+		agentMap[a.Name] = a.Description
+		candidates[i] = agentTypes.AgentEntry{Name: a.Name, Description: a.Description}
 	}
 
-	agentJson, err := json.Marshal(agentEntries)
-	if err != nil {
-		return ""
-	}
-
-	messages := []agentTypes.Message{
-		{
-			Role:    "system",
-			Content: strings.TrimSpace(agentSelectorPrompt),
-		},
-		{
-			Role: "user",
-			Content: fmt.Sprintf(
-				"Available agents:\n%s\nUser request: %s",
-				string(agentJson),
-				strings.TrimSpace(trimInput),
-			),
-		},
-	}
-
-	resp, err := bot.Send(ctx, messages, nil)
-	if err != nil || len(resp.Choices) == 0 {
-		return ""
-	}
+	return route(ctx, bot, "agent", agentMap, trimInput, threshold, func() (string, error) {
+		agentJson, err := json.Marshal(candidates)
+		if err != nil {
+			return "", fmt.Errorf("json.Marshal: %w", err)
+		}
 
-	answer := ""
-	if content, ok := resp.Choices[0].Message.Content.(string); ok {
-		answer = strings.Trim(strings.TrimSpace(content), "\"'` \n")
-	}
+		messages := []agentTypes.Message{
+			{
+				Role:    "system",
+				Content: strings.TrimSpace(agentSelectorPrompt),
+			},
+			{
+				Role: "user",
+				Content: fmt.Sprintf(
+					"Available agents:\n%s\nUser request: %s",
+					string(agentJson),
+					strings.TrimSpace(trimInput),
+				),
+			},
+		}
 
-	if answer == "NONE" || answer == "" {
-		return ""
-	}
+		names := make([]string, len(candidates))
+		for i, c := range candidates {
+			names[i] = c.Name
+		}
+		schema := &agentTypes.SchemaData{
+			Name: "agent_selection",
+			Schema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"agent": map[string]any{"type": "string", "enum": names},
+				},
+				"required":             []string{"agent"},
+				"additionalProperties": false,
+			},
+		}
 
-	if _, ok := agentMap[answer]; ok {
-		return answer
-	}
+		var result struct {
+			Agent string `json:"agent"`
+		}
+		if err := sendJSON(ctx, bot, messages, schema, &result); err != nil {
+			return "", fmt.Errorf("sendJSON: %w", err)
+		}
+		answer := result.Agent
 
-	return ""
+		// * unknown name means fallback
+		if _, ok := agentMap[answer]; !ok {
+			return "", nil
+		}
+		return answer, nil
+	})
 }
@@ -5,6 +5,7 @@ import (
 	_ "embed"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
 
 	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
@@ -14,54 +15,89 @@ import (
 //go:embed prompt/skillSelector.md
 var skillSelectorPrompt string
 
-func selectSkill(ctx context.Context, bot agentTypes.Agent, scanner *skill.Scanner, userInput string) *skill.Skill {
+func selectSkill(ctx context.Context, bot agentTypes.Agent, scanner *skill.Scanner, userInput string, threshold float64) routeData {
 	trimInput := strings.TrimSpace(userInput)
 
 	skills := scanner.List()
 	if len(skills) == 0 {
-		return nil
+		return routeData{Source: RouteLocal}
 	}
 
 	skillMap := make(map[string]string, len(skills))
 	for _, name := range skills {
 		// * already checked List() will output trimmed skill name
-		skillMap[name] = strings.TrimSpace(scanner.Skills.ByName[name].Description)
-	}
-	skillJson, err := json.Marshal(skillMap)
-	if err != nil {
-		return nil
+		s, ok := scanner.Get(name)
+		if !ok {
+			continue
+		}
+		skillMap[name] = strings.TrimSpace(s.Description)
+		// * tags help both the local router and the selector
+		if len(s.Tags) > 0 {
+			skillMap[name] += fmt.Sprintf(" (tags: %s)", strings.Join(s.Tags, ", "))
+		}
 	}
 
-	messages := []agentTypes.Message{
-		{
-			Role:    "system",
-			Content: strings.TrimSpace(skillSelectorPrompt),
-		},
-		{
-			Role: "user",
-			Content: fmt.Sprintf(
-				"Available skills: %s\nUser request: %s",
-				string(skillJson),
-				strings.TrimSpace(trimInput),
-			),
-		},
+	// * a clearly sequenced request composes skills without asking the selector
+	if names, score := localStages(trimInput, skillMap, threshold); len(names) > 1 {
+		return routeData{Name: names[0], Names: names, Score: score, Source: RouteLocal}
 	}
 
-	resp, err := bot.Send(ctx, messages, nil)
-	if err != nil || len(resp.Choices) == 0 {
-		return nil
-	}
+	result := route(ctx, bot, "skill", skillMap, trimInput, threshold, func() (string, error) {
+		skillJson, err := json.Marshal(skillMap)
+		if err != nil {
+			return "", fmt.Errorf("json.Marshal: %w", err)
+		}
 
-	answer := ""
-	if content, ok := resp.Choices[0].Message.Content.(string); ok {
-		answer = strings.Trim(strings.TrimSpace(content), "\"'` \n")
-	}
+		messages := []agentTypes.Message{
+			{
+				Role:    "system",
+				Content: strings.TrimSpace(skillSelectorPrompt),
+			},
+			{
+				Role: "user",
+				Content: fmt.Sprintf(
+					"Available skills: %s\nUser request: %s",
+					string(skillJson),
+					strings.TrimSpace(trimInput),
+				),
+			},
+		}
 
-	if answer == "NONE" || answer == "" {
-		return nil
-	} else if s, ok := scanner.Skills.ByName[answer]; ok {
-		return s
-	}
+		names := make([]string, 0, len(skillMap))
+		for name := range skillMap {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		schema := &agentTypes.SchemaData{
+			Name: "skill_selection",
+			Schema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"skills": map[string]any{
+						"type":  "array",
+						"items": map[string]any{"type": "string", "enum": names},
+					},
+				},
+				"required":             []string{"skills"},
+				"additionalProperties": false,
+			},
+		}
 
-	return nil
+		var result struct {
+			Skills []string `json:"skills"`
+		}
+		if err := sendJSON(ctx, bot, messages, schema, &result); err != nil {
+			return "", fmt.Errorf("sendJSON: %w", err)
+		}
+
+		// * an empty list means no skill, the route cache keeps names comma separated
+		return strings.Join(splitNames(strings.Join(result.Skills, ","), skillMap), ","), nil
+	})
+
+	result.Names = splitNames(result.Name, skillMap)
+	result.Name = ""
+	if len(result.Names) > 0 {
+		result.Name = result.Names[0]
+	}
+	return result
 }
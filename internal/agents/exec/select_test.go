@@ -0,0 +1,410 @@
+package exec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+	"github.com/pardnchiu/agenvoy/internal/skill"
+	toolTypes "github.com/pardnchiu/agenvoy/internal/tools/types"
+	"github.com/pardnchiu/agenvoy/internal/utils"
+)
+
+// fakeBot is the scripted agent of these tests, answer or reply decides what Send returns.
+type fakeBot struct {
+	mu        sync.Mutex
+	answer    string // fixed reply, empty makes Send fail
+	reply     func(ctx context.Context, system string, n int) (string, error)
+	calls     int
+	requests  [][]agentTypes.Message // every request except summaries
+	summaries []string               // last message of every summary request
+}
+
+func (b *fakeBot) Execute(ctx context.Context, skill *skill.Skill, userInput string, events chan<- agentTypes.Event, allowAll bool) error {
+	return nil
+}
+
+// * reply runs unlocked, n counts the requests of the same kind, summary or not
+func (b *fakeBot) Send(ctx context.Context, messages []agentTypes.Message, tools []toolTypes.Tool, opts agentTypes.OptionsData) (*agentTypes.Output, error) {
+	system, _ := messages[0].Content.(string)
+	b.mu.Lock()
+	b.calls++
+	var n int
+	if strings.Contains(system, "SUMMARY") {
+		b.summaries = append(b.summaries, agentTypes.ContentText(messages[len(messages)-1].Content))
+		n = len(b.summaries)
+	} else {
+		b.requests = append(b.requests, messages)
+		n = len(b.requests)
+	}
+	b.mu.Unlock()
+
+	answer := b.answer
+	if b.reply != nil {
+		var err error
+		if answer, err = b.reply(ctx, system, n); err != nil {
+			return nil, err
+		}
+	}
+	if answer == "" {
+		return nil, fmt.Errorf("unavailable")
+	}
+	return &agentTypes.Output{
+		Choices: []agentTypes.OutputChoices{{Message: agentTypes.Message{Role: "assistant", Content: answer}}},
+	}, nil
+}
+
+// ---------- localSelect ----------
+
+func TestLocalSelect(t *testing.T) {
+	candidates := map[string]string{
+		"readme-generator": "Generate README.md for a project",
+		"weather":          "查詢天氣預報與氣溫",
+	}
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"please write a README for this repo", "readme-generator"},
+		{"天氣預報", "weather"},
+		{"hello", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got, _ := localSelect(tt.input, candidates); got != tt.want {
+			t.Errorf("localSelect(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	if _, score := localSelect("generate readme", candidates); score < defaultRouteThreshold {
+		t.Errorf("localSelect() score = %.2f, want >= %.2f", score, defaultRouteThreshold)
+	}
+}
+
+// ---------- route ----------
+
+func TestSelectAgent_Route(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+
+	entries := []agentTypes.AgentEntry{
+		{Name: "claude@x", Description: "code review and refactoring"},
+		{Name: "openai@y", Description: "casual chat"},
+	}
+	bot := &fakeBot{answer: `{"agent":"claude@x"}`}
+
+	// * confident local match never reaches the LLM
+	got := selectAgent(context.Background(), bot, entries, "casual chat", defaultRouteThreshold)
+	if got.Name != "openai@y" || got.Source != RouteLocal || bot.calls != 0 {
+		t.Errorf("selectAgent() = %+v, calls = %d", got, bot.calls)
+	}
+
+	// * low confidence defers to the LLM once, then hits the cache
+	got = selectAgent(context.Background(), bot, entries, "fix this bug", defaultRouteThreshold)
+	if got.Name != "claude@x" || got.Source != RouteLLM || bot.calls != 1 {
+		t.Errorf("selectAgent() = %+v, calls = %d", got, bot.calls)
+	}
+	got = selectAgent(context.Background(), bot, entries, "  Fix this BUG ", defaultRouteThreshold)
+	if got.Name != "claude@x" || got.Source != RouteCache || bot.calls != 1 {
+		t.Errorf("selectAgent() = %+v, calls = %d", got, bot.calls)
+	}
+
+	// * without a selector a weak local match is dropped
+	if got := selectAgent(context.Background(), nil, entries, "chat about code", 1); got.Name != "" || got.Source != RouteLocal {
+		t.Errorf("selectAgent() = %+v, want no match", got)
+	}
+
+	// * a selector that runs past the deadline is no match either
+	slow := &fakeBot{reply: func(ctx context.Context, system string, n int) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if got := selectAgent(ctx, slow, entries, "casual code talk", 1); got.Name != "" || slow.calls != 1 {
+		t.Errorf("selectAgent() = %+v, calls = %d, want no match after the timeout", got, slow.calls)
+	}
+}
+
+func TestSelectorChain(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+
+	down := &fakeBot{}
+	up := &fakeBot{answer: `{"agent":"claude@x"}`}
+	entries := []agentTypes.AgentEntry{
+		{Name: "claude@x", Description: "code"},
+		{Name: "openai@y", Description: "chat"},
+	}
+
+	if got := selectAgent(context.Background(), NewSelectorChain(down, up), entries, "hi", 1); got.Name != "claude@x" {
+		t.Errorf("selectAgent() = %+v, want claude@x", got)
+	}
+	if down.calls != 1 || up.calls != 1 {
+		t.Errorf("calls = %d/%d, want 1/1", down.calls, up.calls)
+	}
+
+	// * every model down leaves a weak local match unused
+	if got := selectAgent(context.Background(), NewSelectorChain(down), entries, "chat with me", 1); got.Name != "" {
+		t.Errorf("selectAgent() = %+v, want no match", got)
+	}
+}
+
+// ---------- Run ----------
+
+// * selector prompts are only answered once both are in flight
+func barrier() func(ctx context.Context, system string, n int) (string, error) {
+	var mu sync.Mutex
+	waiting := 0
+	release := make(chan struct{})
+	return func(ctx context.Context, system string, n int) (string, error) {
+		if strings.Contains(system, "SUMMARY") {
+			return `{"core_discussion":"zzz"}`, nil
+		}
+		if !strings.Contains(system, "Selector") {
+			return "done", nil
+		}
+		mu.Lock()
+		waiting++
+		if waiting == 2 {
+			close(release)
+		}
+		mu.Unlock()
+
+		select {
+		case <-release:
+		case <-time.After(time.Second):
+			return "", fmt.Errorf("selectors did not run concurrently")
+		}
+		if strings.Contains(system, "AGENT") {
+			return `{"agent":"stub@agent"}`, nil
+		}
+		return `{"skills":[]}`, nil
+	}
+}
+
+func TestRun_ConcurrentSelect(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+
+	bot := &fakeBot{reply: barrier()}
+	registry := agentTypes.AgentRegistry{
+		Registry: map[string]agentTypes.Agent{"stub@agent": bot},
+		Entries:  []agentTypes.AgentEntry{{Name: "stub@agent", Description: "stub"}},
+		Fallback: &fakeBot{},
+	}
+	scanner := &skill.Scanner{Skills: &skill.SkillList{ByName: map[string]*skill.Skill{
+		"noop": {Name: "noop", Description: "unrelated", Content: "noop"},
+	}}}
+
+	events := make(chan agentTypes.Event, 16)
+	var got []agentTypes.Event
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for ev := range events {
+			got = append(got, ev)
+		}
+	}()
+
+	err := Run(context.Background(), bot, registry, scanner, "zzz", events, true)
+	close(events)
+	<-done
+	WaitSummary()
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	want := []agentTypes.EventType{
+		agentTypes.EventSkillSelect,
+		agentTypes.EventSkillResult,
+		agentTypes.EventAgentSelect,
+		agentTypes.EventAgentResult,
+		agentTypes.EventText,
+		agentTypes.EventDone,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("events = %+v", got)
+	}
+	for i, ev := range got {
+		if ev.Type != want[i] {
+			t.Errorf("event[%d] = %d, want %d", i, ev.Type, want[i])
+		}
+	}
+	if got[1].Source != RouteLLM || got[3].Text != "stub@agent" || got[3].Source != RouteLLM {
+		t.Errorf("selection = %+v / %+v", got[1], got[3])
+	}
+}
+
+// ---------- compose ----------
+
+func TestLocalStages(t *testing.T) {
+	candidates := map[string]string{
+		"changelog-generate": "Generate a changelog from git diff",
+		"release-notes":      "Draft release notes for a new version",
+		"weather":            "查詢天氣預報與氣溫",
+	}
+
+	names, _ := localStages("generate the changelog and then draft release notes", candidates, defaultRouteThreshold)
+	if len(names) != 2 || names[0] != "changelog-generate" || names[1] != "release-notes" {
+		t.Errorf("localStages() = %v, want [changelog-generate release-notes]", names)
+	}
+	if names, _ := localStages("generate the changelog", candidates, defaultRouteThreshold); names != nil {
+		t.Errorf("localStages() = %v, want nil without a sequence", names)
+	}
+	// * every stage has to match on its own
+	if names, _ := localStages("generate the changelog and then say hello", candidates, defaultRouteThreshold); names != nil {
+		t.Errorf("localStages() = %v, want nil for an unmatched stage", names)
+	}
+
+	if got := splitNames(" `release-notes`, unknown，changelog-generate, release-notes", candidates); len(got) != 2 || got[0] != "release-notes" {
+		t.Errorf("splitNames() = %v", got)
+	}
+}
+
+// * every turn answers with its number, summaries with a fixed topic
+func numbered(ctx context.Context, system string, n int) (string, error) {
+	if strings.Contains(system, "SUMMARY") {
+		return `{"core_discussion":"compose"}`, nil
+	}
+	return fmt.Sprintf("answer %d", n), nil
+}
+
+func TestRunWithOverride_Compose(t *testing.T) {
+	scanner := &skill.Scanner{Skills: &skill.SkillList{ByName: map[string]*skill.Skill{
+		"changelog": {Name: "changelog", Content: "write the changelog"},
+		"notes":     {Name: "notes", Content: "draft the notes"},
+	}}}
+
+	run := func(t *testing.T, compose string) *fakeBot {
+		t.Setenv("HOME", t.TempDir())
+		t.Chdir(t.TempDir())
+
+		agent := &fakeBot{reply: numbered}
+		registry := agentTypes.AgentRegistry{
+			Registry: map[string]agentTypes.Agent{"stub@agent": agent},
+			Fallback: agent,
+		}
+		events := make(chan agentTypes.Event, 64)
+		override := OverrideData{Skill: "changelog,notes", Agent: "stub@agent", Compose: compose}
+		if err := RunWithOverride(context.Background(), nil, registry, scanner, "ship it", override, events, true); err != nil {
+			t.Fatalf("RunWithOverride() error: %v", err)
+		}
+		close(events)
+		WaitSummary()
+		return agent
+	}
+
+	t.Run("merge", func(t *testing.T) {
+		agent := run(t, ComposeMerge)
+		if len(agent.requests) != 1 {
+			t.Fatalf("requests = %d, want 1", len(agent.requests))
+		}
+		system, _ := agent.requests[0][0].Content.(string)
+		first, second := strings.Index(system, "write the changelog"), strings.Index(system, "draft the notes")
+		if first < 0 || second < first {
+			t.Errorf("merged prompt does not keep the order: %q", system)
+		}
+	})
+
+	t.Run("stages", func(t *testing.T) {
+		agent := run(t, ComposeStages)
+		if len(agent.requests) != 2 {
+			t.Fatalf("requests = %d, want 2", len(agent.requests))
+		}
+		second := agent.requests[1]
+		system, _ := second[0].Content.(string)
+		input, _ := second[len(second)-1].Content.(string)
+		if !strings.Contains(system, "draft the notes") || strings.Contains(system, "write the changelog") {
+			t.Errorf("second stage system prompt = %q", system)
+		}
+		if !strings.Contains(input, "2/2") || !strings.Contains(input, "answer 1") {
+			t.Errorf("second stage input = %q, want previous output", input)
+		}
+
+		// * the session keeps the original input and the last answer, summarized once
+		if len(agent.summaries) != 1 || !strings.Contains(agent.summaries[0], "ship it") || strings.Contains(agent.summaries[0], "2/2") {
+			t.Errorf("summaries = %q, want one for the original input", agent.summaries)
+		}
+		configDir, err := utils.GetConfigDir("sessions")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sessionID, err := getSessionID(configDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(filepath.Join(configDir.Home, sessionID, "history.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var history []agentTypes.Message
+		json.Unmarshal(data, &history)
+		if len(history) != 2 || !strings.HasSuffix(agentTypes.ContentText(history[0].Content), "ship it") || !strings.HasSuffix(agentTypes.ContentText(history[1].Content), "answer 2") {
+			t.Errorf("history = %+v, want the input and the last answer", history)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Setenv("HOME", t.TempDir())
+		t.Chdir(t.TempDir())
+
+		events := make(chan agentTypes.Event, 8)
+		override := OverrideData{Skill: "changelog,notes", Args: map[string]string{"a": "b"}}
+		if err := RunWithOverride(context.Background(), nil, agentTypes.AgentRegistry{}, scanner, "x", override, events, true); err == nil {
+			t.Error("arguments with several skills should fail")
+		}
+		override = OverrideData{Skill: "changelog", Compose: "parallel"}
+		if err := RunWithOverride(context.Background(), nil, agentTypes.AgentRegistry{}, scanner, "x", override, events, true); err == nil {
+			t.Error("unknown compose mode should fail")
+		}
+	})
+}
+
+// ---------- updateSummary ----------
+
+func TestSummarizeAsync_Concurrent(t *testing.T) {
+	home := t.TempDir()
+	os.MkdirAll(filepath.Join(home, "s1"), 0755)
+	configDir := &utils.ConfigDirData{Home: home}
+	session := &agentTypes.AgentSession{ID: "s1"}
+
+	bot := &fakeBot{reply: func(ctx context.Context, system string, n int) (string, error) {
+		// * widen the window between reading and writing summary.json
+		time.Sleep(5 * time.Millisecond)
+		return fmt.Sprintf(`{"core_discussion":"topic","key_data":["fact %d"]}`, n), nil
+	}}
+	for range 8 {
+		summarizeAsync(context.Background(), bot, configDir, session, "q", "a")
+	}
+	WaitSummary()
+
+	data, err := os.ReadFile(filepath.Join(home, "s1", "summary.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summary summarySchema
+	if err := json.Unmarshal(data, &summary); err != nil {
+		t.Fatal(err)
+	}
+	// * every update merges the one before it, none is lost
+	if len(summary.KeyData) != 8 {
+		t.Errorf("key_data = %v, want 8 facts", summary.KeyData)
+	}
+}
+
+func TestTrimRunes(t *testing.T) {
+	if got := trimRunes("天氣預報", 4); got != "天..." {
+		t.Errorf("trimRunes() = %q", got)
+	}
+	if got := trimRunes("abc", 4); got != "abc" {
+		t.Errorf("trimRunes() = %q", got)
+	}
+}
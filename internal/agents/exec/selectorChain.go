@@ -0,0 +1,47 @@
+package exec
+
+import (
+	"context"
+	"fmt"
+
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+	"github.com/pardnchiu/agenvoy/internal/skill"
+	toolTypes "github.com/pardnchiu/agenvoy/internal/tools/types"
+)
+
+type selectorChain struct {
+	bots []agentTypes.Agent
+}
+
+// NewSelectorChain returns an agent that tries each bot in order until one answers.
+func NewSelectorChain(bots ...agentTypes.Agent) agentTypes.Agent {
+	if len(bots) == 1 {
+		return bots[0]
+	}
+	return &selectorChain{bots: bots}
+}
+
+func (c *selectorChain) Send(ctx context.Context, messages []agentTypes.Message, tools []toolTypes.Tool, opts agentTypes.OptionsData) (*agentTypes.Output, error) {
+	var lastErr error
+	for _, bot := range c.bots {
+		resp, err := bot.Send(ctx, messages, tools, opts)
+		if err == nil && len(resp.Choices) > 0 {
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err == nil {
+			err = fmt.Errorf("empty response")
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no selector model")
+	}
+	return nil, fmt.Errorf("selectorChain.Send: %w", lastErr)
+}
+
+func (c *selectorChain) Execute(ctx context.Context, skill *skill.Skill, userInput string, events chan<- agentTypes.Event, allowAll bool) error {
+	return fmt.Errorf("selectorChain.Execute: selector cannot execute")
+}
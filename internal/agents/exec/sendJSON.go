@@ -0,0 +1,62 @@
+package exec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+)
+
+const repairPrompt = "你的回覆不符合要求的 JSON Schema：%s。請只輸出修正後的 JSON，不要加上任何其他文字。"
+
+// * asks for an answer matching schema and decodes it into v, an invalid answer is repaired once
+func sendJSON(ctx context.Context, bot agentTypes.Agent, messages []agentTypes.Message, schema *agentTypes.SchemaData, v any) error {
+	opts := agentTypes.OptionsData{Schema: schema}
+	resp, err := bot.Send(ctx, messages, nil, opts)
+	if err != nil {
+		return fmt.Errorf("bot.Send: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return fmt.Errorf("bot.Send: empty response")
+	}
+
+	answer, err := repairJSON(ctx, bot, messages, agentTypes.ContentText(resp.Choices[0].Message.Content), opts, nil)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(answer), v); err != nil {
+		return fmt.Errorf("json.Unmarshal: %w", err)
+	}
+	return nil
+}
+
+// * validates text against opts.Schema, an invalid answer goes back once with the error;
+// * the repair turn has no tools, so every provider can constrain it natively
+func repairJSON(ctx context.Context, agent agentTypes.Agent, messages []agentTypes.Message, text string, opts agentTypes.OptionsData, usage *agentTypes.UsageData) (string, error) {
+	answer, err := opts.Schema.Validate(text)
+	if err == nil {
+		return answer, nil
+	}
+
+	messages = append(messages[:len(messages):len(messages)],
+		agentTypes.Message{Role: "assistant", Content: text},
+		agentTypes.Message{Role: "user", Content: fmt.Sprintf(repairPrompt, err.Error())},
+	)
+	resp, err := agent.Send(ctx, messages, nil, opts)
+	if err != nil {
+		return "", fmt.Errorf("agent.Send: %w", err)
+	}
+	if usage != nil {
+		usage.Add(resp.Usage)
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("agent.Send: empty response")
+	}
+
+	answer, err = opts.Schema.Validate(agentTypes.ContentText(resp.Choices[0].Message.Content))
+	if err != nil {
+		return "", fmt.Errorf("schema.Validate: %w", err)
+	}
+	return answer, nil
+}
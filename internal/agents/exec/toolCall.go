@@ -5,14 +5,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+	sessionStore "github.com/pardnchiu/agenvoy/internal/session"
 	"github.com/pardnchiu/agenvoy/internal/tools"
 	toolTypes "github.com/pardnchiu/agenvoy/internal/tools/types"
 )
 
-func toolCall(ctx context.Context, exec *toolTypes.Executor, choice agentTypes.OutputChoices, sessionData *agentTypes.AgentSession, events chan<- agentTypes.Event, allowAll bool, alreadyCall map[string]string) (*agentTypes.AgentSession, map[string]string, error) {
+func toolCall(ctx context.Context, exec *toolTypes.Executor, choice agentTypes.OutputChoices, sessionData *agentTypes.AgentSession, events chan<- agentTypes.Event, allowAll bool, alreadyCall map[string]string, log *sessionStore.Log) (*agentTypes.AgentSession, map[string]string, error) {
 	sessionData.Messages = append(sessionData.Messages, choice.Message)
+	logMessage(log, choice.Message)
 
 	for _, tool := range choice.Message.ToolCalls {
 		toolID := strings.TrimSpace(tool.ID)
@@ -22,13 +25,34 @@ func toolCall(ctx context.Context, exec *toolTypes.Executor, choice agentTypes.O
 			toolName = toolName[:idx]
 		}
 
+		log.Append(sessionStore.Record{
+			Type:     sessionStore.RecordToolCall,
+			ToolName: toolName,
+			ToolArgs: toolArg,
+			ToolID:   toolID,
+		})
+
 		hash := fmt.Sprintf("%v|%v", toolName, toolArg)
 		if cached, ok := alreadyCall[hash]; ok && cached != "" {
-			sessionData.Messages = append(sessionData.Messages, agentTypes.Message{
+			message := agentTypes.Message{
 				Role:       "tool",
 				Content:    strings.TrimSpace(cached),
 				ToolCallID: toolID,
-			})
+			}
+			sessionData.Messages = append(sessionData.Messages, message)
+			logToolResult(log, toolName, toolID, "cached", cached, 0, message)
+			continue
+		}
+
+		// * skill allowed-tools may hide tools, the model must not reach them anyway
+		if !tools.IsAvailable(exec, toolName) {
+			message := agentTypes.Message{
+				Role:       "tool",
+				Content:    fmt.Sprintf("[%s] tool is not available", toolName),
+				ToolCallID: toolID,
+			}
+			sessionData.Messages = append(sessionData.Messages, message)
+			logToolResult(log, toolName, toolID, "error", "", 0, message)
 			continue
 		}
 
@@ -55,16 +79,14 @@ func toolCall(ctx context.Context, exec *toolTypes.Executor, choice agentTypes.O
 					ToolName: toolName,
 					ToolID:   toolID,
 				}
-				sessionData.Tools = append(sessionData.Tools, agentTypes.Message{
-					Role:       "tool",
-					Content:    "Skipped by user",
-					ToolCallID: toolID,
-				})
-				sessionData.Messages = append(sessionData.Messages, agentTypes.Message{
+				message := agentTypes.Message{
 					Role:       "tool",
 					Content:    "Skipped by user",
 					ToolCallID: toolID,
-				})
+				}
+				sessionData.Tools = append(sessionData.Tools, message)
+				sessionData.Messages = append(sessionData.Messages, message)
+				logToolResult(log, toolName, toolID, "skipped", "", 0, message)
 				continue
 			}
 		}
@@ -75,10 +97,14 @@ func toolCall(ctx context.Context, exec *toolTypes.Executor, choice agentTypes.O
 			ToolID:   toolID,
 		}
 
-		result, err := tools.Execute(ctx, exec, toolName, json.RawMessage(tool.Function.Arguments))
+		start := time.Now()
+		status := "ok"
+		result, media, err := tools.ExecuteMedia(ctx, exec, toolName, json.RawMessage(tool.Function.Arguments))
 		if err != nil {
+			status = "error"
 			result = "no data"
 		}
+		duration := time.Since(start)
 
 		if result != "" {
 			events <- agentTypes.Event{
@@ -104,16 +130,34 @@ func toolCall(ctx context.Context, exec *toolTypes.Executor, choice agentTypes.O
 			ToolID:   toolID,
 			Result:   result,
 		}
-		sessionData.Tools = append(sessionData.Tools, agentTypes.Message{
-			Role:       "tool",
-			Content:    content,
-			ToolCallID: toolID,
-		})
-		sessionData.Messages = append(sessionData.Messages, agentTypes.Message{
+		message := agentTypes.Message{
 			Role:       "tool",
 			Content:    content,
 			ToolCallID: toolID,
-		})
+		}
+		// * the cache keeps the text only, the media is in the conversation already
+		if media != nil {
+			message.Content = []agentTypes.ContentPart{
+				{Type: agentTypes.PartText, Text: content},
+				*media,
+			}
+		}
+		sessionData.Tools = append(sessionData.Tools, message)
+		sessionData.Messages = append(sessionData.Messages, message)
+		logToolResult(log, toolName, toolID, status, result, duration, message)
 	}
 	return sessionData, alreadyCall, nil
 }
+
+// * tool_result keeps the raw output and timing, the message record is what gets replayed
+func logToolResult(log *sessionStore.Log, toolName, toolID, status, result string, duration time.Duration, message agentTypes.Message) {
+	log.Append(sessionStore.Record{
+		Type:     sessionStore.RecordToolResult,
+		ToolName: toolName,
+		ToolID:   toolID,
+		Status:   status,
+		Result:   result,
+		Duration: duration.Milliseconds(),
+	})
+	logMessage(log, message)
+}
@@ -0,0 +1,218 @@
+package exec
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+	"github.com/pardnchiu/agenvoy/internal/utils"
+)
+
+//go:embed prompt/summarizer.md
+var summarizerPrompt string
+
+const (
+	maxSummaryItems   = 32
+	maxSummaryToolLen = 2048
+	summaryTimeout    = 60 * time.Second
+)
+
+var summaryWG sync.WaitGroup
+
+// * one lock per session, each update reads and merges the summary the previous one wrote
+var summaryLocks sync.Map
+
+// * same shape as summarySchema, sent so providers can constrain the summary natively
+var summaryJSONSchema = func() *agentTypes.SchemaData {
+	list := map[string]any{"type": "array", "items": map[string]any{"type": "string"}}
+	return &agentTypes.SchemaData{
+		Name: "summary",
+		Schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"core_discussion":    map[string]any{"type": "string", "minLength": 1},
+				"confirmed_needs":    list,
+				"constraints":        list,
+				"excluded_options":   list,
+				"key_data":           list,
+				"current_conclusion": list,
+				"pending_questions":  list,
+				"discussion_log": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"topic":      map[string]any{"type": "string"},
+							"time":       map[string]any{"type": "string"},
+							"conclusion": map[string]any{"type": "string"},
+						},
+						"required":             []string{"topic", "time", "conclusion"},
+						"additionalProperties": false,
+					},
+				},
+			},
+			// * merging keeps the previous entries, so only the topic has to be there
+			"required":             []string{"core_discussion"},
+			"additionalProperties": false,
+		},
+	}
+}()
+
+type summarySchema struct {
+	CoreDiscussion    string   `json:"core_discussion"`
+	ConfirmedNeeds    []string `json:"confirmed_needs"`
+	Constraints       []string `json:"constraints"`
+	ExcludedOptions   []string `json:"excluded_options"`
+	KeyData           []string `json:"key_data"`
+	CurrentConclusion []string `json:"current_conclusion"`
+	PendingQuestions  []string `json:"pending_questions"`
+	DiscussionLog     []struct {
+		Topic      string `json:"topic"`
+		Time       string `json:"time"`
+		Conclusion string `json:"conclusion"`
+	} `json:"discussion_log"`
+}
+
+// WaitSummary blocks until every pending summary update has been written.
+func WaitSummary() {
+	summaryWG.Wait()
+}
+
+func summarizeAsync(ctx context.Context, bot agentTypes.Agent, configDir *utils.ConfigDirData, session *agentTypes.AgentSession, userInput, reply string) {
+	// * snapshot tool results, session keeps mutating after return
+	tools := append([]agentTypes.Message(nil), session.Tools...)
+	sessionID := session.ID
+
+	summaryWG.Add(1)
+	go func() {
+		defer summaryWG.Done()
+
+		// * detach from caller, answer is already delivered when this runs
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), summaryTimeout)
+		defer cancel()
+
+		mu, _ := summaryLocks.LoadOrStore(sessionID, &sync.Mutex{})
+		mu.(*sync.Mutex).Lock()
+		defer mu.(*sync.Mutex).Unlock()
+
+		if err := updateSummary(ctx, bot, configDir, sessionID, userInput, reply, tools); err != nil {
+			slog.Warn("failed to update summary",
+				slog.String("session", sessionID),
+				slog.String("error", err.Error()))
+		}
+	}()
+}
+
+func updateSummary(ctx context.Context, bot agentTypes.Agent, configDir *utils.ConfigDirData, sessionID, userInput, reply string, tools []agentTypes.Message) error {
+	path := filepath.Join(configDir.Home, sessionID, "summary.json")
+
+	previous := "{}"
+	var oldMap map[string]any
+	if data, err := os.ReadFile(path); err == nil && json.Unmarshal(data, &oldMap) == nil {
+		previous = string(data)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("現在時間：%s\n\n", time.Now().Format("2006-01-02 15:04")))
+	sb.WriteString(fmt.Sprintf("前次 summary：\n%s\n\n", previous))
+	sb.WriteString(fmt.Sprintf("使用者輸入：\n%s\n\n", strings.TrimSpace(userInput)))
+	if len(tools) > 0 {
+		sb.WriteString("工具結果：\n")
+		for _, t := range tools {
+			content := agentTypes.ContentText(t.Content)
+			content = trimRunes(content, maxSummaryToolLen)
+			sb.WriteString(fmt.Sprintf("- %s\n", strings.TrimSpace(content)))
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString(fmt.Sprintf("助理回覆：\n%s", strings.TrimSpace(reply)))
+
+	var raw json.RawMessage
+	if err := sendJSON(ctx, bot, []agentTypes.Message{
+		{
+			Role:    "system",
+			Content: strings.TrimSpace(summarizerPrompt),
+		},
+		{
+			Role:    "user",
+			Content: sb.String(),
+		},
+	}, summaryJSONSchema, &raw); err != nil {
+		return fmt.Errorf("sendJSON: %w", err)
+	}
+
+	newMap, err := parseSummary(string(raw))
+	if err != nil {
+		return fmt.Errorf("parseSummary: %w", err)
+	}
+
+	if oldMap == nil {
+		oldMap = map[string]any{}
+	}
+	newMap = mergeSummary(oldMap, newMap)
+
+	data, err := json.Marshal(newMap)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+	// * a reader never sees a half written summary
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("os.WriteFile: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("os.Rename: %w", err)
+	}
+	return nil
+}
+
+// * cut at most max bytes without splitting a multi-byte character
+func trimRunes(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max] + "..."
+}
+
+// * validate against summarySchema, unknown fields or wrong types are rejected
+func parseSummary(text string) (map[string]any, error) {
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+	if start == -1 || end < start {
+		return nil, fmt.Errorf("json object not found")
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(text[start : end+1]))
+	decoder.DisallowUnknownFields()
+
+	var schema summarySchema
+	if err := decoder.Decode(&schema); err != nil {
+		return nil, fmt.Errorf("decoder.Decode: %w", err)
+	}
+	if strings.TrimSpace(schema.CoreDiscussion) == "" {
+		return nil, fmt.Errorf("core_discussion is required")
+	}
+
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+	return m, nil
+}
@@ -0,0 +1,84 @@
+package azure
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	providerConfig "github.com/pardnchiu/agenvoy/internal/agents/provider/config"
+)
+
+type Agent struct {
+	httpClient *http.Client
+	deployment string
+	endpoint   string
+	apiVersion string
+	apiKey     string
+	workDir    string
+
+	// * entra id client credentials, used when no api key is set
+	authority    string
+	tenantID     string
+	clientID     string
+	clientSecret string
+
+	mu    sync.Mutex
+	token *tokenData
+}
+
+const (
+	defaultDeployment = "gpt-4.1"
+	defaultAPIVersion = "2024-10-21"
+	defaultAuthority  = "https://login.microsoftonline.com"
+	providerType      = "azure"
+)
+
+// * the model after azure@ is the deployment name, not the underlying model
+func New(model ...string) (*Agent, error) {
+	cfg, deployment := providerConfig.Resolve(providerType, defaultDeployment, model...)
+
+	endpoint := cfg.URL(os.Getenv("AZURE_OPENAI_ENDPOINT"))
+	if endpoint == "" {
+		return nil, fmt.Errorf("os.Getenv: AZURE_OPENAI_ENDPOINT is required")
+	}
+	apiKey, err := cfg.Key("AZURE_OPENAI_API_KEY")
+	if err != nil {
+		return nil, fmt.Errorf("cfg.Key: %w", err)
+	}
+	httpClient, err := cfg.Client(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("cfg.Client: %w", err)
+	}
+	apiVersion := os.Getenv("AZURE_OPENAI_API_VERSION")
+	if apiVersion == "" {
+		apiVersion = defaultAPIVersion
+	}
+	authority := strings.TrimRight(os.Getenv("AZURE_AUTHORITY_HOST"), "/")
+	if authority == "" {
+		authority = defaultAuthority
+	}
+
+	a := &Agent{
+		httpClient:   httpClient,
+		deployment:   deployment,
+		endpoint:     endpoint,
+		apiVersion:   apiVersion,
+		apiKey:       apiKey,
+		authority:    authority,
+		tenantID:     os.Getenv("AZURE_TENANT_ID"),
+		clientID:     os.Getenv("AZURE_CLIENT_ID"),
+		clientSecret: os.Getenv("AZURE_CLIENT_SECRET"),
+	}
+	if a.apiKey == "" && (a.tenantID == "" || a.clientID == "" || a.clientSecret == "") {
+		return nil, fmt.Errorf("os.Getenv: AZURE_OPENAI_API_KEY or AZURE_TENANT_ID, AZURE_CLIENT_ID and AZURE_CLIENT_SECRET are required")
+	}
+
+	workDir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("os.Getwd: %w", err)
+	}
+	a.workDir = workDir
+	return a, nil
+}
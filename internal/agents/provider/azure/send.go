@@ -0,0 +1,61 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/pardnchiu/agenvoy/internal/agents/exec"
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+	"github.com/pardnchiu/agenvoy/internal/skill"
+	toolTypes "github.com/pardnchiu/agenvoy/internal/tools/types"
+	"github.com/pardnchiu/agenvoy/internal/utils"
+)
+
+func (a *Agent) Execute(ctx context.Context, skill *skill.Skill, userInput string, events chan<- agentTypes.Event, allowAll bool) error {
+	return exec.Execute(ctx, exec.ExecData{
+		Agent:     a,
+		WorkDir:   a.workDir,
+		Skill:     skill,
+		UserInput: userInput,
+		AllowAll:  allowAll,
+	}, events)
+}
+
+// * same chat completions body as openai, the deployment in the path picks the model
+func (a *Agent) Send(ctx context.Context, messages []agentTypes.Message, tools []toolTypes.Tool, opts agentTypes.OptionsData) (*agentTypes.Output, error) {
+	if err := opts.Params.Check(providerType, agentTypes.LimitsData{MaxStop: 4}); err != nil {
+		return nil, fmt.Errorf("opts.Params.Check: %w", err)
+	}
+	headers, err := a.getHeaders(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("a.getHeaders: %w", err)
+	}
+	headers["Content-Type"] = "application/json"
+
+	chatAPI := fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
+		a.endpoint, url.PathEscape(a.deployment), url.QueryEscape(a.apiVersion))
+
+	body := map[string]any{
+		"messages": agentTypes.ChatMessages(messages),
+		"tools":    tools,
+	}
+	// * the deployment name says nothing about the model, reasoning params are sent as configured
+	opts.Params.SetChatParams(body, "max_completion_tokens", true)
+	opts.Schema.SetChatFormat(body)
+
+	result, code, err := utils.POST[agentTypes.Output](ctx, a.httpClient, chatAPI, headers, body, "json")
+	if err != nil {
+		return nil, fmt.Errorf("utils.POST: %w", err)
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("utils.POST: %s", result.Error.Message)
+	}
+	if code != http.StatusOK {
+		return nil, fmt.Errorf("utils.POST: status %d", code)
+	}
+	result.Normalize()
+
+	return &result, nil
+}
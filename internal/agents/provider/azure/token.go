@@ -0,0 +1,56 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pardnchiu/agenvoy/internal/utils"
+)
+
+const tokenScope = "https://cognitiveservices.azure.com/.default"
+
+type tokenData struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+	ExpiresAt   int64  `json:"-"`
+}
+
+// * api key wins, otherwise an entra id token is fetched and reused until a minute before expiry
+func (a *Agent) getHeaders(ctx context.Context) (map[string]string, error) {
+	if a.apiKey != "" {
+		return map[string]string{"api-key": a.apiKey}, nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token == nil || time.Now().Unix() >= a.token.ExpiresAt-60 {
+		token, err := a.getToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+		a.token = token
+	}
+	return map[string]string{"Authorization": "Bearer " + a.token.AccessToken}, nil
+}
+
+func (a *Agent) getToken(ctx context.Context) (*tokenData, error) {
+	tokenAPI := fmt.Sprintf("%s/%s/oauth2/v2.0/token", a.authority, url.PathEscape(a.tenantID))
+	token, code, err := utils.POST[tokenData](ctx, a.httpClient, tokenAPI, nil, map[string]any{
+		"grant_type":    "client_credentials",
+		"client_id":     a.clientID,
+		"client_secret": a.clientSecret,
+		"scope":         tokenScope,
+	}, "form")
+	if err != nil {
+		return nil, fmt.Errorf("utils.POST: %w", err)
+	}
+	if code != http.StatusOK || token.AccessToken == "" {
+		return nil, fmt.Errorf("utils.POST: token request failed with status %d", code)
+	}
+
+	token.ExpiresAt = time.Now().Unix() + token.ExpiresIn
+	return &token, nil
+}
@@ -0,0 +1,121 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+)
+
+// ---------- SigV4 ----------
+
+// get-vanilla from the aws signature v4 test suite
+func TestSignRequest_Vanilla(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds := credentials{AccessKey: "AKIDEXAMPLE", SecretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	signRequest(req, nil, creds, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCanonicalURI(t *testing.T) {
+	// model ids hold a colon, it is sent once encoded and signed twice encoded
+	path := "/model/" + awsEscape("anthropic.claude-v2:1") + "/converse"
+	if path != "/model/anthropic.claude-v2%3A1/converse" {
+		t.Errorf("path = %s", path)
+	}
+	if got := canonicalURI(path); got != "/model/anthropic.claude-v2%253A1/converse" {
+		t.Errorf("canonicalURI() = %s", got)
+	}
+}
+
+// ---------- Converse ----------
+
+func TestSend_Converse(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	var got struct {
+		path, auth, token string
+		body              map[string]any
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.path = r.URL.EscapedPath()
+		got.auth = r.Header.Get("Authorization")
+		got.token = r.Header.Get("X-Amz-Security-Token")
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &got.body)
+		w.Write([]byte(`{"output":{"message":{"role":"assistant","content":[{"reasoningContent":{"reasoningText":{"text":"想一下","signature":"sig"}}},{"toolUse":{"toolUseId":"tu_1","name":"calculate","input":{"expression":"1+1"}}}]}},"stopReason":"tool_use","usage":{"inputTokens":20,"outputTokens":5,"cacheReadInputTokens":100}}`))
+	}))
+	defer srv.Close()
+	t.Setenv("BEDROCK_ENDPOINT", srv.URL)
+	t.Setenv("AWS_REGION", "ap-northeast-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "session")
+	t.Setenv("AWS_BEARER_TOKEN_BEDROCK", "")
+
+	a, err := New("bedrock@anthropic.claude-v2:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := agentTypes.ToolCall{ID: "tu_0", Type: "function"}
+	call.Function.Name = "calculate"
+	call.Function.Arguments = `{"expression":"2+2"}`
+	messages := []agentTypes.Message{
+		{Role: "system", Content: "system"},
+		{Role: "user", Content: "1+1?"},
+		{Role: "assistant", ToolCalls: []agentTypes.ToolCall{call}},
+		{Role: "tool", Content: "[calculate] 4", ToolCallID: "tu_0"},
+		{Role: "user", Content: "繼續"},
+	}
+	out, err := a.Send(context.Background(), messages, nil, agentTypes.OptionsData{})
+	if err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+
+	if got.path != "/model/anthropic.claude-v2%3A1/converse" {
+		t.Errorf("path = %s", got.path)
+	}
+	if !strings.HasPrefix(got.auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(got.auth, "/ap-northeast-1/bedrock/aws4_request") || got.token != "session" {
+		t.Errorf("auth = %s token = %s", got.auth, got.token)
+	}
+	// tool result and the following input share one user turn
+	sent, _ := got.body["messages"].([]any)
+	if len(sent) != 3 {
+		t.Fatalf("messages = %v, want user, assistant, user", sent)
+	}
+	last, _ := sent[2].(map[string]any)
+	if content, _ := last["content"].([]any); len(content) != 2 {
+		t.Errorf("last message = %v, want tool result and text", last)
+	}
+
+	choice := out.Choices[0]
+	if choice.FinishReason != agentTypes.FinishToolCalls || len(choice.Message.ToolCalls) != 1 || choice.Message.Reasoning != "想一下" {
+		t.Fatalf("choice = %+v", choice)
+	}
+	if choice.Message.ToolCalls[0].Function.Arguments != `{"expression":"1+1"}` || choice.Message.Thinking[0].Signature != "sig" {
+		t.Errorf("message = %+v", choice.Message)
+	}
+	if out.Usage.InputTokens != 120 || out.Usage.CacheReadTokens != 100 {
+		t.Errorf("usage = %+v", out.Usage)
+	}
+
+	// converse takes at most four stop sequences, more fails before the request
+	got.path = ""
+	opts := agentTypes.OptionsData{Params: agentTypes.ParamsData{Stop: []string{"a", "b", "c", "d", "e"}}}
+	if _, err := a.Send(context.Background(), messages, nil, opts); err == nil || got.path != "" {
+		t.Errorf("Send() with 5 stop sequences = %v, path = %q", err, got.path)
+	}
+}
@@ -0,0 +1,75 @@
+package bedrock
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	providerConfig "github.com/pardnchiu/agenvoy/internal/agents/provider/config"
+)
+
+type Agent struct {
+	httpClient  *http.Client
+	model       string
+	region      string
+	endpoint    string
+	credentials credentials
+	bearerToken string
+	workDir     string
+}
+
+const (
+	defaultModel  = "anthropic.claude-3-5-sonnet-20241022-v2:0"
+	defaultRegion = "us-east-1"
+	providerType  = "bedrock"
+	service       = "bedrock"
+)
+
+func New(model ...string) (*Agent, error) {
+	cfg, usedModel := providerConfig.Resolve(providerType, defaultModel, model...)
+
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		region = defaultRegion
+	}
+	// * vpc endpoints and proxies replace the public runtime host
+	endpoint := cfg.URL(os.Getenv("BEDROCK_ENDPOINT"))
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com", region)
+	}
+	// * api_key stands for the bedrock api key, sigv4 credentials stay in the env
+	bearerToken, err := cfg.Key("AWS_BEARER_TOKEN_BEDROCK")
+	if err != nil {
+		return nil, fmt.Errorf("cfg.Key: %w", err)
+	}
+	httpClient, err := cfg.Client(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("cfg.Client: %w", err)
+	}
+
+	a := &Agent{
+		httpClient: httpClient,
+		model:      usedModel,
+		region:     region,
+		endpoint:   endpoint,
+		credentials: credentials{
+			AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		},
+		bearerToken: bearerToken,
+	}
+	if a.bearerToken == "" && (a.credentials.AccessKey == "" || a.credentials.SecretKey == "") {
+		return nil, fmt.Errorf("os.Getenv: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY or AWS_BEARER_TOKEN_BEDROCK are required")
+	}
+
+	workDir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("os.Getwd: %w", err)
+	}
+	a.workDir = workDir
+	return a, nil
+}
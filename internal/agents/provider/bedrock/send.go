@@ -0,0 +1,304 @@
+package bedrock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pardnchiu/agenvoy/internal/agents/exec"
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+	"github.com/pardnchiu/agenvoy/internal/skill"
+	toolTypes "github.com/pardnchiu/agenvoy/internal/tools/types"
+)
+
+const (
+	defaultMaxTokens  = 16384
+	minThinkingTokens = 1024
+)
+
+// * document names allow letters, digits, spaces, hyphens, parentheses and brackets only
+var documentName = regexp.MustCompile(`[^A-Za-z0-9 \-()\[\]]+`)
+
+func (a *Agent) Execute(ctx context.Context, skill *skill.Skill, userInput string, events chan<- agentTypes.Event, allowAll bool) error {
+	return exec.Execute(ctx, exec.ExecData{
+		Agent:     a,
+		WorkDir:   a.workDir,
+		Skill:     skill,
+		UserInput: userInput,
+		AllowAll:  allowAll,
+	}, events)
+}
+
+// * converse takes the same body for every model family hosted on bedrock
+func (a *Agent) Send(ctx context.Context, messages []agentTypes.Message, tools []toolTypes.Tool, opts agentTypes.OptionsData) (*agentTypes.Output, error) {
+	if err := opts.Params.Check(providerType, agentTypes.LimitsData{MaxStop: 4}); err != nil {
+		return nil, fmt.Errorf("opts.Params.Check: %w", err)
+	}
+
+	var system []Block
+	var newMessages []Message
+	for _, msg := range messages {
+		if msg.Role == "system" {
+			if content := agentTypes.ContentText(msg.Content); content != "" {
+				system = append(system, Block{Text: content})
+			}
+			continue
+		}
+
+		message := convertToMessage(msg)
+		if len(message.Content) == 0 {
+			continue
+		}
+		// * roles must alternate, tool results of one call and the next user input merge
+		if n := len(newMessages); n > 0 && newMessages[n-1].Role == message.Role {
+			newMessages[n-1].Content = append(newMessages[n-1].Content, message.Content...)
+			continue
+		}
+		newMessages = append(newMessages, message)
+	}
+
+	body := map[string]any{
+		"messages": newMessages,
+	}
+	if len(system) > 0 {
+		body["system"] = system
+	}
+	if len(tools) > 0 {
+		body["toolConfig"] = map[string]any{"tools": convertToTools(tools)}
+	}
+	a.setParams(body, opts.Params)
+
+	var result Output
+	if err := a.post(ctx, "/model/"+awsEscape(a.model)+"/converse", body, &result); err != nil {
+		return nil, fmt.Errorf("a.post: %w", err)
+	}
+	return convertToOutput(&result), nil
+}
+
+func (a *Agent) setParams(body map[string]any, params agentTypes.ParamsData) {
+	config := map[string]any{}
+	if params.MaxTokens > 0 {
+		config["maxTokens"] = params.MaxTokens
+	}
+	if len(params.Stop) > 0 {
+		config["stopSequences"] = params.Stop
+	}
+	// * thinking is an anthropic field passed through, it runs with the default sampling only
+	if params.Reasoning() && strings.Contains(a.model, "anthropic.") {
+		budget := max(params.ReasoningTokens(), minThinkingTokens)
+		if params.MaxTokens <= budget {
+			config["maxTokens"] = budget + defaultMaxTokens
+		}
+		body["additionalModelRequestFields"] = map[string]any{
+			"thinking": map[string]any{
+				"type":          "enabled",
+				"budget_tokens": budget,
+			},
+		}
+	} else {
+		if params.Temperature != nil {
+			config["temperature"] = *params.Temperature
+		}
+		if params.TopP != nil {
+			config["topP"] = *params.TopP
+		}
+	}
+	if len(config) > 0 {
+		body["inferenceConfig"] = config
+	}
+}
+
+func (a *Agent) post(ctx context.Context, path string, body map[string]any, result any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint+path, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("http.NewRequestWithContext: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if a.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+a.bearerToken)
+	} else {
+		signRequest(req, data, a.credentials, a.region, service, time.Now())
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("httpClient.Do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respData, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("io.ReadAll: %w", err)
+	}
+	// * errors come back as {"message": ...} with a non 2xx status
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		json.Unmarshal(respData, &apiErr)
+		return fmt.Errorf("status %d: %s", resp.StatusCode, apiErr.Message)
+	}
+	if err := json.Unmarshal(respData, result); err != nil {
+		return fmt.Errorf("json.Unmarshal: %w", err)
+	}
+	return nil
+}
+
+func convertToMessage(message agentTypes.Message) Message {
+	if message.ToolCallID != "" {
+		return Message{
+			Role: "user",
+			Content: []Block{{
+				ToolResult: &ToolResult{
+					ToolUseID: message.ToolCallID,
+					Content:   convertToBlocks(message.Content),
+				},
+			}},
+		}
+	}
+
+	if message.Role != "assistant" {
+		return Message{Role: "user", Content: convertToBlocks(message.Content)}
+	}
+
+	// * signed reasoning must precede tool use when the result is sent back
+	var content []Block
+	if len(message.ToolCalls) > 0 {
+		for _, block := range message.Thinking {
+			reasoning := &ReasoningContent{}
+			switch block.Type {
+			case "thinking":
+				reasoning.ReasoningText = &ReasoningText{Text: block.Text, Signature: block.Signature}
+			case "redacted_thinking":
+				reasoning.RedactedContent = block.Data
+			default:
+				continue
+			}
+			content = append(content, Block{ReasoningContent: reasoning})
+		}
+	}
+	if text := agentTypes.ContentText(message.Content); strings.TrimSpace(text) != "" {
+		content = append(content, Block{Text: text})
+	}
+	for _, tool := range message.ToolCalls {
+		input := json.RawMessage(tool.Function.Arguments)
+		if !json.Valid(input) {
+			input = json.RawMessage("{}")
+		}
+		content = append(content, Block{ToolUse: &ToolUse{
+			ToolUseID: tool.ID,
+			Name:      tool.Function.Name,
+			Input:     input,
+		}})
+	}
+	return Message{Role: "assistant", Content: content}
+}
+
+func convertToBlocks(content any) []Block {
+	var blocks []Block
+	for _, part := range agentTypes.ContentParts(content) {
+		switch part.Type {
+		case agentTypes.PartText:
+			if strings.TrimSpace(part.Text) != "" {
+				blocks = append(blocks, Block{Text: part.Text})
+			}
+		case agentTypes.PartImage:
+			media := &Media{Format: strings.TrimPrefix(part.MediaType, "image/")}
+			media.Source.Bytes = part.Data
+			blocks = append(blocks, Block{Image: media})
+		case agentTypes.PartFile:
+			name := strings.TrimSuffix(part.Name, filepath.Ext(part.Name))
+			name = strings.Trim(documentName.ReplaceAllString(name, "-"), "- ")
+			if name == "" {
+				name = "document"
+			}
+			media := &Media{Format: "pdf", Name: name}
+			media.Source.Bytes = part.Data
+			blocks = append(blocks, Block{Document: media})
+		}
+	}
+	return blocks
+}
+
+func convertToTools(tools []toolTypes.Tool) []map[string]any {
+	newTools := make([]map[string]any, len(tools))
+	for i, tool := range tools {
+		newTools[i] = map[string]any{
+			"toolSpec": map[string]any{
+				"name":        tool.Function.Name,
+				"description": tool.Function.Description,
+				"inputSchema": map[string]any{"json": json.RawMessage(tool.Function.Parameters)},
+			},
+		}
+	}
+	return newTools
+}
+
+func convertToOutput(resp *Output) *agentTypes.Output {
+	message := agentTypes.Message{Role: "assistant"}
+	var text, reasoning string
+	for _, block := range resp.Output.Message.Content {
+		switch {
+		case block.Text != "":
+			text += block.Text
+		case block.ToolUse != nil:
+			toolCall := agentTypes.ToolCall{
+				ID:   block.ToolUse.ToolUseID,
+				Type: "function",
+			}
+			toolCall.Function.Name = block.ToolUse.Name
+			toolCall.Function.Arguments = string(block.ToolUse.Input)
+			if len(block.ToolUse.Input) == 0 {
+				toolCall.Function.Arguments = "{}"
+			}
+			message.ToolCalls = append(message.ToolCalls, toolCall)
+		case block.ReasoningContent != nil:
+			if r := block.ReasoningContent.ReasoningText; r != nil {
+				reasoning += r.Text
+				message.Thinking = append(message.Thinking, agentTypes.ThinkingBlock{
+					Type:      "thinking",
+					Text:      r.Text,
+					Signature: r.Signature,
+				})
+			} else if block.ReasoningContent.RedactedContent != "" {
+				message.Thinking = append(message.Thinking, agentTypes.ThinkingBlock{
+					Type: "redacted_thinking",
+					Data: block.ReasoningContent.RedactedContent,
+				})
+			}
+		}
+	}
+	message.Content = text
+	message.Reasoning = reasoning
+
+	output := &agentTypes.Output{
+		Choices: []agentTypes.OutputChoices{
+			{
+				Message:      message,
+				FinishReason: agentTypes.NormalizeFinish(resp.StopReason),
+			},
+		},
+	}
+	if resp.Usage != nil {
+		output.Usage = &agentTypes.UsageData{
+			InputTokens:      resp.Usage.InputTokens + resp.Usage.CacheReadInputTokens + resp.Usage.CacheWriteInputTokens,
+			OutputTokens:     resp.Usage.OutputTokens,
+			CacheReadTokens:  resp.Usage.CacheReadInputTokens,
+			CacheWriteTokens: resp.Usage.CacheWriteInputTokens,
+		}
+	}
+	return output
+}
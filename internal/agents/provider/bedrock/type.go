@@ -0,0 +1,61 @@
+package bedrock
+
+import "encoding/json"
+
+// * converse blocks hold exactly one of their fields
+type Block struct {
+	Text             string            `json:"text,omitempty"`
+	Image            *Media            `json:"image,omitempty"`
+	Document         *Media            `json:"document,omitempty"`
+	ToolUse          *ToolUse          `json:"toolUse,omitempty"`
+	ToolResult       *ToolResult       `json:"toolResult,omitempty"`
+	ReasoningContent *ReasoningContent `json:"reasoningContent,omitempty"`
+}
+
+type Media struct {
+	Format string `json:"format"`
+	Name   string `json:"name,omitempty"`
+	Source struct {
+		Bytes string `json:"bytes"`
+	} `json:"source"`
+}
+
+type ToolUse struct {
+	ToolUseID string          `json:"toolUseId"`
+	Name      string          `json:"name"`
+	Input     json.RawMessage `json:"input"`
+}
+
+type ToolResult struct {
+	ToolUseID string  `json:"toolUseId"`
+	Content   []Block `json:"content"`
+}
+
+type ReasoningContent struct {
+	ReasoningText   *ReasoningText `json:"reasoningText,omitempty"`
+	RedactedContent string         `json:"redactedContent,omitempty"`
+}
+
+type ReasoningText struct {
+	Text      string `json:"text"`
+	Signature string `json:"signature,omitempty"`
+}
+
+type Message struct {
+	Role    string  `json:"role"`
+	Content []Block `json:"content"`
+}
+
+type Output struct {
+	Output struct {
+		Message Message `json:"message"`
+	} `json:"output"`
+	StopReason string `json:"stopReason"`
+	Usage      *struct {
+		InputTokens           int `json:"inputTokens"`
+		OutputTokens          int `json:"outputTokens"`
+		CacheReadInputTokens  int `json:"cacheReadInputTokens"`
+		CacheWriteInputTokens int `json:"cacheWriteInputTokens"`
+	} `json:"usage,omitempty"`
+	Message string `json:"message,omitempty"`
+}
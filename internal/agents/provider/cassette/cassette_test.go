@@ -0,0 +1,207 @@
+package cassette
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pardnchiu/agenvoy/internal/agents/exec"
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+	"github.com/pardnchiu/agenvoy/internal/skill"
+	toolTypes "github.com/pardnchiu/agenvoy/internal/tools/types"
+)
+
+// scripted is a stand-in for a live provider, answering by prompt type.
+type scripted struct {
+	calls int
+}
+
+func (s *scripted) Execute(ctx context.Context, skill *skill.Skill, userInput string, events chan<- agentTypes.Event, allowAll bool) error {
+	return nil
+}
+
+func (s *scripted) Send(ctx context.Context, messages []agentTypes.Message, tools []toolTypes.Tool, opts agentTypes.OptionsData) (*agentTypes.Output, error) {
+	s.calls++
+
+	system, _ := messages[0].Content.(string)
+	last := messages[len(messages)-1]
+
+	var message agentTypes.Message
+	switch {
+	case strings.Contains(system, "SKILL Selector"):
+		message = agentTypes.Message{Role: "assistant", Content: `{"skills":[]}`}
+	case strings.Contains(system, "AGENT Selector"):
+		message = agentTypes.Message{Role: "assistant", Content: `{"agent":"fake@model"}`}
+	case strings.Contains(system, "SUMMARY Generator"):
+		message = agentTypes.Message{Role: "assistant", Content: `{"core_discussion":"math"}`}
+	case last.Role == "tool":
+		message = agentTypes.Message{Role: "assistant", Content: "2"}
+	default:
+		call := agentTypes.ToolCall{ID: "call_1", Type: "function"}
+		call.Function.Name = "calculate"
+		call.Function.Arguments = `{"expression":"1+1"}`
+		message = agentTypes.Message{Role: "assistant", ToolCalls: []agentTypes.ToolCall{call}}
+	}
+
+	return &agentTypes.Output{
+		Choices: []agentTypes.OutputChoices{{Message: message}},
+	}, nil
+}
+
+// ---------- Hash ----------
+
+func TestHash_Normalize(t *testing.T) {
+	a := []agentTypes.Message{
+		{Role: "system", Content: "work: /tmp/abc\nnow 2026-01-02 10:00"},
+		{Role: "user", Content: "ts:1700000000\nhello"},
+	}
+	b := []agentTypes.Message{
+		{Role: "system", Content: "work: /tmp/xyz\nnow 2026-03-04 11:22:33"},
+		{Role: "user", Content: "ts:1800000000\nhello"},
+	}
+	if Hash(a, nil, agentTypes.OptionsData{}, []string{"/tmp/abc"}) != Hash(b, nil, agentTypes.OptionsData{}, []string{"/tmp/xyz"}) {
+		t.Error("Hash() should ignore ts prefix, wall clock time and scrubbed strings")
+	}
+
+	c := []agentTypes.Message{{Role: "user", Content: "ts:1700000000\nbye"}}
+	if Hash(a[1:], nil, agentTypes.OptionsData{}, nil) == Hash(c, nil, agentTypes.OptionsData{}, nil) {
+		t.Error("Hash() should differ for different content")
+	}
+
+	tools := []toolTypes.Tool{
+		{Function: toolTypes.ToolFunction{Name: "b"}},
+		{Function: toolTypes.ToolFunction{Name: "a"}},
+	}
+	reversed := []toolTypes.Tool{tools[1], tools[0]}
+	if Hash(c, tools, agentTypes.OptionsData{}, nil) != Hash(c, reversed, agentTypes.OptionsData{}, nil) {
+		t.Error("Hash() should not depend on tool order")
+	}
+
+	seed := 7
+	if Hash(c, nil, agentTypes.OptionsData{}, nil) == Hash(c, nil, agentTypes.OptionsData{Params: agentTypes.ParamsData{Seed: &seed}}, nil) {
+		t.Error("Hash() should differ for different params")
+	}
+	schema := &agentTypes.SchemaData{Name: "answer", Schema: map[string]any{"type": "object"}}
+	if Hash(c, nil, agentTypes.OptionsData{}, nil) == Hash(c, nil, agentTypes.OptionsData{Schema: schema}, nil) {
+		t.Error("Hash() should differ with a schema")
+	}
+}
+
+func TestFileName(t *testing.T) {
+	if got := FileName("nvidia@openai/gpt-oss-120b"); got != "nvidia_openai_gpt-oss-120b.json" {
+		t.Errorf("FileName() = %q", got)
+	}
+}
+
+// ---------- Player ----------
+
+func TestPlayer_Missing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.json")
+	if err := (&Cassette{}).Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewPlayer(path)
+	if err != nil {
+		t.Fatalf("NewPlayer() error: %v", err)
+	}
+	_, err = p.Send(context.Background(), []agentTypes.Message{{Role: "user", Content: "hi"}}, nil, agentTypes.OptionsData{})
+	if err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Errorf("Send() error = %v, want no recorded response", err)
+	}
+}
+
+func TestNewPlayer_NotExist(t *testing.T) {
+	if _, err := NewPlayer(filepath.Join(t.TempDir(), "nope.json")); err == nil {
+		t.Error("NewPlayer() on missing file should fail")
+	}
+}
+
+// ---------- Record / Replay exec.Run ----------
+
+func runOnce(t *testing.T, bot, agent agentTypes.Agent) []agentTypes.Event {
+	t.Helper()
+
+	registry := agentTypes.AgentRegistry{
+		Registry: map[string]agentTypes.Agent{"fake@model": agent},
+		Entries:  []agentTypes.AgentEntry{{Name: "fake@model", Description: "test"}},
+		Fallback: agent,
+	}
+	scanner := &skill.Scanner{Skills: &skill.SkillList{ByName: map[string]*skill.Skill{}}}
+
+	events := make(chan agentTypes.Event, 64)
+	var got []agentTypes.Event
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for ev := range events {
+			got = append(got, ev)
+		}
+	}()
+
+	err := exec.Run(context.Background(), bot, registry, scanner, "1+1?", events, true)
+	close(events)
+	<-done
+	exec.WaitSummary()
+	if err != nil {
+		t.Fatalf("exec.Run() error: %v", err)
+	}
+	return got
+}
+
+func TestRecordReplay_Run(t *testing.T) {
+	dir := t.TempDir()
+	botPath := filepath.Join(dir, "bot.json")
+	agentPath := filepath.Join(dir, "agent.json")
+
+	// record against the scripted provider
+	recordCwd := t.TempDir()
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(recordCwd)
+	live := &scripted{}
+	botRec, err := NewRecorder(live, botPath, recordCwd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agentRec, err := NewRecorder(live, agentPath, recordCwd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded := runOnce(t, botRec, agentRec)
+	if live.calls == 0 {
+		t.Fatal("recording should reach the live provider")
+	}
+
+	// replay on a fresh machine with no live provider
+	home := t.TempDir()
+	cwd := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Chdir(cwd)
+	botPlay, err := NewPlayer(botPath, cwd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agentPlay, err := NewPlayer(agentPath, cwd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed := runOnce(t, botPlay, agentPlay)
+
+	texts := func(evs []agentTypes.Event) []string {
+		var out []string
+		for _, ev := range evs {
+			if ev.Type == agentTypes.EventText || ev.Type == agentTypes.EventToolResult {
+				out = append(out, ev.Text+ev.Result)
+			}
+		}
+		return out
+	}
+	want, got := texts(recorded), texts(replayed)
+	if strings.Join(want, "|") != strings.Join(got, "|") {
+		t.Errorf("replay events = %v, want %v", got, want)
+	}
+	if len(got) == 0 || got[len(got)-1] != "2" {
+		t.Errorf("replay final text = %v, want 2", got)
+	}
+}
@@ -0,0 +1,83 @@
+package cassette
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+	toolTypes "github.com/pardnchiu/agenvoy/internal/tools/types"
+)
+
+var (
+	// * volatile parts of a request: "ts:<unix>" prefixes and wall clock times
+	tsRegex   = regexp.MustCompile(`(?m)^ts:\d+\n`)
+	timeRegex = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[ T]\d{2}:\d{2}(:\d{2})?`)
+)
+
+type normalizedMessage struct {
+	Role       string   `json:"role"`
+	Content    string   `json:"content"`
+	ToolCalls  []string `json:"tool_calls,omitempty"`
+	ToolCallID string   `json:"tool_call_id,omitempty"`
+}
+
+// Hash returns the key of a request after removing timestamps and scrubbed strings.
+func Hash(messages []agentTypes.Message, tools []toolTypes.Tool, opts agentTypes.OptionsData, scrub []string) string {
+	// * the same messages with other params or another schema get another answer
+	normalized := struct {
+		Messages []normalizedMessage    `json:"messages"`
+		Tools    []string               `json:"tools"`
+		Params   agentTypes.ParamsData  `json:"params"`
+		Schema   *agentTypes.SchemaData `json:"schema,omitempty"`
+	}{
+		Messages: make([]normalizedMessage, 0, len(messages)),
+		Tools:    make([]string, 0, len(tools)),
+		Params:   opts.Params,
+		Schema:   opts.Schema,
+	}
+
+	for _, m := range messages {
+		var content string
+		switch v := m.Content.(type) {
+		case string:
+			content = v
+		case nil:
+		default:
+			data, _ := json.Marshal(v)
+			content = string(data)
+		}
+
+		message := normalizedMessage{
+			Role:       m.Role,
+			Content:    normalize(content, scrub),
+			ToolCallID: m.ToolCallID,
+		}
+		for _, call := range m.ToolCalls {
+			message.ToolCalls = append(message.ToolCalls, call.Function.Name+"|"+normalize(call.Function.Arguments, scrub))
+		}
+		normalized.Messages = append(normalized.Messages, message)
+	}
+
+	for _, t := range tools {
+		normalized.Tools = append(normalized.Tools, t.Function.Name)
+	}
+	sort.Strings(normalized.Tools)
+
+	data, _ := json.Marshal(normalized)
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
+func normalize(s string, scrub []string) string {
+	s = tsRegex.ReplaceAllString(s, "")
+	s = timeRegex.ReplaceAllString(s, "<time>")
+	for _, v := range scrub {
+		if v != "" {
+			s = strings.ReplaceAll(s, v, "<scrub>")
+		}
+	}
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,85 @@
+package cassette
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/pardnchiu/agenvoy/internal/agents/exec"
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+	"github.com/pardnchiu/agenvoy/internal/skill"
+	toolTypes "github.com/pardnchiu/agenvoy/internal/tools/types"
+)
+
+type Recorder struct {
+	mu       sync.Mutex
+	inner    agentTypes.Agent
+	path     string
+	scrub    []string
+	cassette *Cassette
+	workDir  string
+}
+
+// NewRecorder wraps an agent and appends every Send to the cassette at path.
+func NewRecorder(inner agentTypes.Agent, path string, scrub ...string) (*Recorder, error) {
+	cassette, err := Load(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("Load: %w", err)
+		}
+		cassette = &Cassette{}
+	}
+
+	workDir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("os.Getwd: %w", err)
+	}
+
+	return &Recorder{
+		inner:    inner,
+		path:     path,
+		scrub:    scrub,
+		cassette: cassette,
+		workDir:  workDir,
+	}, nil
+}
+
+func (r *Recorder) Execute(ctx context.Context, skill *skill.Skill, userInput string, events chan<- agentTypes.Event, allowAll bool) error {
+	return exec.Execute(ctx, exec.ExecData{
+		Agent:     r,
+		WorkDir:   r.workDir,
+		Skill:     skill,
+		UserInput: userInput,
+		AllowAll:  allowAll,
+	}, events)
+}
+
+func (r *Recorder) Send(ctx context.Context, messages []agentTypes.Message, tools []toolTypes.Tool, opts agentTypes.OptionsData) (*agentTypes.Output, error) {
+	resp, sendErr := r.inner.Send(ctx, messages, tools, opts)
+
+	interaction := Interaction{
+		Hash: Hash(messages, tools, opts, r.scrub),
+		Request: Request{
+			Messages: messages,
+		},
+		Response: resp,
+	}
+	for _, t := range tools {
+		interaction.Request.Tools = append(interaction.Request.Tools, t.Function.Name)
+	}
+	if sendErr != nil {
+		interaction.Error = sendErr.Error()
+	}
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	err := r.cassette.Save(r.path)
+	r.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("cassette.Save: %w", err)
+	}
+
+	return resp, sendErr
+}
@@ -0,0 +1,84 @@
+package cassette
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/pardnchiu/agenvoy/internal/agents/exec"
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+	"github.com/pardnchiu/agenvoy/internal/skill"
+	toolTypes "github.com/pardnchiu/agenvoy/internal/tools/types"
+)
+
+type Player struct {
+	mu      sync.Mutex
+	queue   map[string][]Interaction
+	scrub   []string
+	workDir string
+}
+
+// NewPlayer serves Send from the cassette at path, identical requests are answered in recorded order.
+func NewPlayer(path string, scrub ...string) (*Player, error) {
+	cassette, err := Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("Load: %w", err)
+	}
+
+	workDir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("os.Getwd: %w", err)
+	}
+
+	queue := make(map[string][]Interaction, len(cassette.Interactions))
+	for _, interaction := range cassette.Interactions {
+		queue[interaction.Hash] = append(queue[interaction.Hash], interaction)
+	}
+
+	return &Player{
+		queue:   queue,
+		scrub:   scrub,
+		workDir: workDir,
+	}, nil
+}
+
+func (p *Player) Execute(ctx context.Context, skill *skill.Skill, userInput string, events chan<- agentTypes.Event, allowAll bool) error {
+	return exec.Execute(ctx, exec.ExecData{
+		Agent:     p,
+		WorkDir:   p.workDir,
+		Skill:     skill,
+		UserInput: userInput,
+		AllowAll:  allowAll,
+	}, events)
+}
+
+func (p *Player) Send(ctx context.Context, messages []agentTypes.Message, tools []toolTypes.Tool, opts agentTypes.OptionsData) (*agentTypes.Output, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	hash := Hash(messages, tools, opts, p.scrub)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	list := p.queue[hash]
+	if len(list) == 0 {
+		return nil, fmt.Errorf("cassette: no recorded response for request %s", hash[:12])
+	}
+
+	interaction := list[0]
+	// * keep the last one so repeated requests after exhaustion still replay
+	if len(list) > 1 {
+		p.queue[hash] = list[1:]
+	}
+
+	if interaction.Error != "" {
+		return nil, fmt.Errorf("%s", interaction.Error)
+	}
+	if interaction.Response == nil {
+		return nil, fmt.Errorf("cassette: empty response for request %s", hash[:12])
+	}
+	return interaction.Response, nil
+}
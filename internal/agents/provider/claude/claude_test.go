@@ -0,0 +1,341 @@
+package claude
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+	toolTypes "github.com/pardnchiu/agenvoy/internal/tools/types"
+)
+
+// stub records every messages body and answers with reply.
+type stub struct {
+	reply  string
+	bodies []map[string]any
+}
+
+func (s *stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/messages" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	var body map[string]any
+	json.NewDecoder(r.Body).Decode(&body)
+	s.bodies = append(s.bodies, body)
+	w.Write([]byte(s.reply))
+}
+
+// newAgent points the claude instance of a sandboxed home config at a stub.
+func newAgent(t *testing.T) (*Agent, *stub) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Chdir(t.TempDir())
+	t.Setenv("ANTHROPIC_API_KEY", "key")
+	s := &stub{reply: `{"content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn","usage":{"input_tokens":10,"output_tokens":2}}`}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+
+	dir := filepath.Join(home, ".config", "agenvoy")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	data := `{"providers":{"claude":{"base_url":"` + srv.URL + `"}}}`
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	a, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, s
+}
+
+// send runs one Send and returns the body the stub received.
+func send(t *testing.T, a *Agent, s *stub, messages []agentTypes.Message, tools []toolTypes.Tool, opts agentTypes.OptionsData) map[string]any {
+	t.Helper()
+	if _, err := a.Send(context.Background(), messages, tools, opts); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	return s.bodies[len(s.bodies)-1]
+}
+
+func equalJSON(t *testing.T, got any, want string) {
+	t.Helper()
+	data, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var g, w any
+	json.Unmarshal(data, &g)
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatalf("want is not json: %v", err)
+	}
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("body =\n%s\nwant\n%s", data, want)
+	}
+}
+
+func toolCall(id, name, arguments string) agentTypes.ToolCall {
+	call := agentTypes.ToolCall{ID: id, Type: "function"}
+	call.Function.Name = name
+	call.Function.Arguments = arguments
+	return call
+}
+
+func TestSend_Thinking(t *testing.T) {
+	a, s := newAgent(t)
+	temperature, topP := 0.5, 0.9
+
+	t.Run("replayed before tool_use", func(t *testing.T) {
+		messages := []agentTypes.Message{
+			{Role: "system", Content: "系統"},
+			{Role: "user", Content: "1+1?"},
+			{
+				Role: "assistant",
+				Thinking: []agentTypes.ThinkingBlock{
+					{Type: "thinking", Text: "先計算", Signature: "sig"},
+					{Type: "redacted_thinking", Data: "xyz"},
+				},
+				ToolCalls: []agentTypes.ToolCall{toolCall("call_1", "calculate", `{"expression":"1+1"}`)},
+			},
+			{Role: "tool", ToolCallID: "call_1", Content: "2"},
+		}
+		opts := agentTypes.OptionsData{Params: agentTypes.ParamsData{
+			Temperature:     &temperature,
+			TopP:            &topP,
+			MaxTokens:       4096,
+			ReasoningEffort: "medium",
+		}}
+		equalJSON(t, send(t, a, s, messages, nil, opts), `{
+			"model": "claude-sonnet-4-5",
+			"max_tokens": 24576,
+			"system": [{"type": "text", "text": "系統", "cache_control": {"type": "ephemeral"}}],
+			"messages": [
+				{"role": "user", "content": "1+1?"},
+				{"role": "assistant", "content": [
+					{"type": "thinking", "thinking": "先計算", "signature": "sig"},
+					{"type": "redacted_thinking", "data": "xyz"},
+					{"type": "tool_use", "id": "call_1", "name": "calculate", "input": {"expression": "1+1"}}
+				]},
+				{"role": "user", "content": [
+					{"type": "tool_result", "tool_use_id": "call_1", "content": "2", "cache_control": {"type": "ephemeral"}}
+				]}
+			],
+			"tools": [],
+			"thinking": {"type": "enabled", "budget_tokens": 8192}
+		}`)
+	})
+
+	t.Run("budget below minimum", func(t *testing.T) {
+		opts := agentTypes.OptionsData{Params: agentTypes.ParamsData{
+			Temperature:     &temperature,
+			MaxTokens:       4096,
+			ReasoningBudget: 500,
+		}}
+		equalJSON(t, send(t, a, s, []agentTypes.Message{{Role: "user", Content: "hi"}}, nil, opts), `{
+			"model": "claude-sonnet-4-5",
+			"max_tokens": 4096,
+			"messages": [{"role": "user", "content": [{"type": "text", "text": "hi", "cache_control": {"type": "ephemeral"}}]}],
+			"tools": [],
+			"thinking": {"type": "enabled", "budget_tokens": 1024}
+		}`)
+	})
+
+	t.Run("sampling without thinking", func(t *testing.T) {
+		opts := agentTypes.OptionsData{Params: agentTypes.ParamsData{
+			Temperature: &temperature,
+			TopP:        &topP,
+		}}
+		equalJSON(t, send(t, a, s, []agentTypes.Message{{Role: "user", Content: "hi"}}, nil, opts), `{
+			"model": "claude-sonnet-4-5",
+			"max_tokens": 16384,
+			"messages": [{"role": "user", "content": [{"type": "text", "text": "hi", "cache_control": {"type": "ephemeral"}}]}],
+			"tools": [],
+			"temperature": 0.5,
+			"top_p": 0.9
+		}`)
+	})
+}
+
+// breakpoints counts the cache_control markers anywhere in a body.
+func breakpoints(v any) int {
+	n := 0
+	switch v := v.(type) {
+	case map[string]any:
+		for key, child := range v {
+			if key == "cache_control" {
+				n++
+				continue
+			}
+			n += breakpoints(child)
+		}
+	case []any:
+		for _, child := range v {
+			n += breakpoints(child)
+		}
+	}
+	return n
+}
+
+func TestSend_CacheControl(t *testing.T) {
+	a, s := newAgent(t)
+	messages := []agentTypes.Message{
+		{Role: "system", Content: "系統"},
+		{Role: "system", Content: "摘要"},
+		{Role: "user", Content: "1+1?"},
+		{Role: "assistant", Content: "2"},
+		{Role: "user", Content: []agentTypes.ContentPart{
+			{Type: agentTypes.PartText, Text: "再加一"},
+			{Type: agentTypes.PartImage, MediaType: "image/png", Data: "aW1n"},
+		}},
+	}
+	tools := []toolTypes.Tool{
+		{Type: "function", Function: toolTypes.ToolFunction{Name: "calculate", Description: "計算", Parameters: json.RawMessage(`{"type":"object"}`)}},
+		{Type: "function", Function: toolTypes.ToolFunction{Name: "search", Description: "搜尋", Parameters: json.RawMessage(`{"type":"object"}`)}},
+	}
+	system := `[
+		{"type": "text", "text": "系統", "cache_control": {"type": "ephemeral"}},
+		{"type": "text", "text": "摘要"}
+	]`
+	history := `[
+		{"role": "user", "content": "1+1?"},
+		{"role": "assistant", "content": "2"},
+		{"role": "user", "content": [
+			{"type": "text", "text": "再加一"},
+			{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "aW1n"}, "cache_control": {"type": "ephemeral"}}
+		]}
+	]`
+
+	t.Run("with tools", func(t *testing.T) {
+		body := send(t, a, s, messages, tools, agentTypes.OptionsData{})
+		equalJSON(t, body, `{
+			"model": "claude-sonnet-4-5",
+			"max_tokens": 16384,
+			"system": `+system+`,
+			"messages": `+history+`,
+			"tools": [
+				{"name": "calculate", "description": "計算", "input_schema": {"type": "object"}},
+				{"name": "search", "description": "搜尋", "input_schema": {"type": "object"}, "cache_control": {"type": "ephemeral"}}
+			]
+		}`)
+		// * the api rejects more than 4 breakpoints per request
+		if n := breakpoints(body); n != 3 {
+			t.Errorf("breakpoints = %d, want 3", n)
+		}
+	})
+
+	t.Run("without tools", func(t *testing.T) {
+		body := send(t, a, s, messages, nil, agentTypes.OptionsData{})
+		equalJSON(t, body, `{
+			"model": "claude-sonnet-4-5",
+			"max_tokens": 16384,
+			"system": `+system+`,
+			"messages": `+history+`,
+			"tools": []
+		}`)
+		if n := breakpoints(body); n != 2 {
+			t.Errorf("breakpoints = %d, want 2", n)
+		}
+	})
+}
+
+func TestSend_Schema(t *testing.T) {
+	a, s := newAgent(t)
+	messages := []agentTypes.Message{{Role: "user", Content: "1+1?"}}
+	tools := []toolTypes.Tool{
+		{Type: "function", Function: toolTypes.ToolFunction{Name: "calculate", Description: "計算", Parameters: json.RawMessage(`{"type":"object"}`)}},
+	}
+	object := &agentTypes.SchemaData{Name: "answer", Schema: map[string]any{
+		"type":                 "object",
+		"properties":           map[string]any{"answer": map[string]any{"type": "integer"}},
+		"required":             []any{"answer"},
+		"additionalProperties": false,
+	}}
+
+	t.Run("forced tool without tools", func(t *testing.T) {
+		s.reply = `{"content":[{"type":"tool_use","id":"toolu_1","name":"answer","input":{"answer":2}}],"stop_reason":"tool_use","usage":{}}`
+		out, err := a.Send(context.Background(), messages, nil, agentTypes.OptionsData{Schema: object})
+		if err != nil {
+			t.Fatalf("Send() error: %v", err)
+		}
+		equalJSON(t, s.bodies[len(s.bodies)-1], `{
+			"model": "claude-sonnet-4-5",
+			"max_tokens": 16384,
+			"messages": [{"role": "user", "content": [{"type": "text", "text": "1+1?", "cache_control": {"type": "ephemeral"}}]}],
+			"tools": [{
+				"name": "answer",
+				"description": "以此工具回覆最終答案，輸入即為答案",
+				"input_schema": {
+					"type": "object",
+					"properties": {"answer": {"type": "integer"}},
+					"required": ["answer"],
+					"additionalProperties": false
+				},
+				"cache_control": {"type": "ephemeral"}
+			}],
+			"tool_choice": {"type": "tool", "name": "answer"}
+		}`)
+		message := out.Choices[0].Message
+		if message.Content != `{"answer":2}` || len(message.ToolCalls) != 0 || out.Choices[0].FinishReason != agentTypes.FinishStop {
+			t.Errorf("output = %+v, want the tool input as the answer", out.Choices[0])
+		}
+	})
+
+	t.Run("non object root is wrapped", func(t *testing.T) {
+		s.reply = `{"content":[{"type":"tool_use","id":"toolu_1","name":"answer","input":{"value":[1,2]}}],"stop_reason":"tool_use","usage":{}}`
+		list := &agentTypes.SchemaData{Name: "answer", Schema: map[string]any{"type": "array", "items": map[string]any{"type": "integer"}}}
+		out, err := a.Send(context.Background(), messages, nil, agentTypes.OptionsData{Schema: list})
+		if err != nil {
+			t.Fatalf("Send() error: %v", err)
+		}
+		tool := s.bodies[len(s.bodies)-1]["tools"].([]any)[0].(map[string]any)
+		equalJSON(t, tool["input_schema"], `{
+			"type": "object",
+			"properties": {"value": {"type": "array", "items": {"type": "integer"}}},
+			"required": ["value"],
+			"additionalProperties": false
+		}`)
+		if got := out.Choices[0].Message.Content; got != "[1,2]" {
+			t.Errorf("answer = %q, want the unwrapped value", got)
+		}
+	})
+
+	s.reply = `{"content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn","usage":{}}`
+	for _, tt := range []struct {
+		name   string
+		tools  []toolTypes.Tool
+		params agentTypes.ParamsData
+		want   any
+	}{
+		{"any with tools", tools, agentTypes.ParamsData{}, map[string]any{"type": "any"}},
+		{"auto with thinking", nil, agentTypes.ParamsData{ReasoningEffort: "low"}, nil},
+		{"auto with tools and thinking", tools, agentTypes.ParamsData{ReasoningEffort: "low"}, nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			body := send(t, a, s, messages, tt.tools, agentTypes.OptionsData{Params: tt.params, Schema: object})
+			if got := body["tool_choice"]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tool_choice = %v, want %v", got, tt.want)
+			}
+			newTools := body["tools"].([]any)
+			if last := newTools[len(newTools)-1].(map[string]any); last["name"] != "answer" {
+				t.Errorf("last tool = %v, want the schema tool", last["name"])
+			}
+			if n := breakpoints(body); n != 2 {
+				t.Errorf("breakpoints = %d, want 2", n)
+			}
+		})
+	}
+
+	t.Run("none without schema", func(t *testing.T) {
+		body := send(t, a, s, messages, tools, agentTypes.OptionsData{})
+		if _, ok := body["tool_choice"]; ok {
+			t.Errorf("tool_choice = %v, want unset", body["tool_choice"])
+		}
+	})
+}
@@ -4,12 +4,14 @@ import (
 	"fmt"
 	"net/http"
 	"os"
-	"strings"
+
+	providerConfig "github.com/pardnchiu/agenvoy/internal/agents/provider/config"
 )
 
 type Agent struct {
 	httpClient *http.Client
 	model      string
+	baseURL    string
 	apiKey     string
 	workDir    string
 }
@@ -18,18 +20,21 @@ const (
 	// claude-sonnet-4-5 200K/64000
 	// claude-opus-4-6   200K/128K
 	// claude-opus-4-5   200K/128K
-	defaultModel = "claude-sonnet-4-5"
-	prefix       = "claude@"
+	defaultModel   = "claude-sonnet-4-5"
+	providerType   = "claude"
+	defaultBaseURL = "https://api.anthropic.com"
 )
 
 func New(model ...string) (*Agent, error) {
-	usedModel := defaultModel
-	if len(model) > 0 && strings.HasPrefix(model[0], prefix) {
-		usedModel = strings.TrimPrefix(model[0], prefix)
+	cfg, usedModel := providerConfig.Resolve(providerType, defaultModel, model...)
+	apiKey, err := cfg.RequiredKey("ANTHROPIC_API_KEY")
+	if err != nil {
+		return nil, fmt.Errorf("cfg.RequiredKey: %w", err)
 	}
-	apiKey := os.Getenv("ANTHROPIC_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("os.Getenv: ANTHROPIC_API_KEY is required")
+	baseURL := cfg.URL(defaultBaseURL)
+	httpClient, err := cfg.Client(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("cfg.Client: %w", err)
 	}
 
 	workDir, err := os.Getwd()
@@ -38,8 +43,9 @@ func New(model ...string) (*Agent, error) {
 	}
 
 	return &Agent{
-		httpClient: &http.Client{},
+		httpClient: httpClient,
 		model:      usedModel,
+		baseURL:    baseURL,
 		apiKey:     apiKey,
 		workDir:    workDir,
 	}, nil
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
 
 	"github.com/pardnchiu/agenvoy/internal/agents/exec"
 	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
@@ -13,22 +14,36 @@ import (
 )
 
 const (
-	messagesAPI = "https://api.anthropic.com/v1/messages"
-	maxTokens   = 16384
+	defaultMaxTokens  = 16384
+	minThinkingTokens = 1024
 )
 
+var cacheControl = map[string]string{"type": "ephemeral"}
+
 func (a *Agent) Execute(ctx context.Context, skill *skill.Skill, userInput string, events chan<- agentTypes.Event, allowAll bool) error {
-	return exec.Execute(ctx, a, a.workDir, skill, userInput, events, allowAll)
+	return exec.Execute(ctx, exec.ExecData{
+		Agent:     a,
+		WorkDir:   a.workDir,
+		Skill:     skill,
+		UserInput: userInput,
+		AllowAll:  allowAll,
+	}, events)
 }
 
-func (a *Agent) Send(ctx context.Context, messages []agentTypes.Message, tools []toolTypes.Tool) (*agentTypes.Output, error) {
-	var systemPrompt string
+func (a *Agent) Send(ctx context.Context, messages []agentTypes.Message, tools []toolTypes.Tool, opts agentTypes.OptionsData) (*agentTypes.Output, error) {
+	if err := opts.Params.Check(providerType, agentTypes.LimitsData{MaxTemperature: 1}); err != nil {
+		return nil, fmt.Errorf("opts.Params.Check: %w", err)
+	}
+	var systemBlocks []map[string]any
 	var newMessages []map[string]any
 
 	for _, msg := range messages {
 		if msg.Role == "system" {
-			if content, ok := msg.Content.(string); ok {
-				systemPrompt = content
+			if content, ok := msg.Content.(string); ok && content != "" {
+				systemBlocks = append(systemBlocks, map[string]any{
+					"type": "text",
+					"text": content,
+				})
 			}
 			continue
 		}
@@ -37,18 +52,77 @@ func (a *Agent) Send(ctx context.Context, messages []agentTypes.Message, tools [
 		newMessages = append(newMessages, message)
 	}
 
+	// * cache order is tools, system, messages; the first system block is the stable
+	// * prompt while later ones (summary) change per turn, so the breakpoint sits on it
+	if len(systemBlocks) > 0 {
+		systemBlocks[0]["cache_control"] = cacheControl
+	}
+	if len(newMessages) > 0 {
+		setCacheControl(newMessages[len(newMessages)-1])
+	}
+
+	params := opts.Params
+	schema := opts.Schema
 	newTools := a.convertToTools(tools)
-	result, _, err := utils.POST[Output](ctx, a.httpClient, messagesAPI, map[string]string{
-		"x-api-key":         a.apiKey,
-		"anthropic-version": "2023-06-01",
-		"Content-Type":      "application/json",
-	}, map[string]any{
+	// * claude has no response format, the answer is forced through a tool shaped like the schema
+	if schema != nil {
+		newTools = append(newTools, map[string]any{
+			"name":         schema.Name,
+			"description":  "以此工具回覆最終答案，輸入即為答案",
+			"input_schema": schema.ToolSchema(),
+		})
+	}
+	if len(newTools) > 0 {
+		newTools[len(newTools)-1]["cache_control"] = cacheControl
+	}
+	maxTokens := defaultMaxTokens
+	if params.MaxTokens > 0 {
+		maxTokens = params.MaxTokens
+	}
+	body := map[string]any{
 		"model":      a.model,
 		"max_tokens": maxTokens,
-		"system":     systemPrompt,
 		"messages":   newMessages,
 		"tools":      newTools,
-	}, "json")
+	}
+	if len(systemBlocks) > 0 {
+		body["system"] = systemBlocks
+	}
+	// * messages api has no seed, and thinking only runs with the default sampling
+	if params.Reasoning() {
+		budget := max(params.ReasoningTokens(), minThinkingTokens)
+		if maxTokens <= budget {
+			body["max_tokens"] = budget + defaultMaxTokens
+		}
+		body["thinking"] = map[string]any{
+			"type":          "enabled",
+			"budget_tokens": budget,
+		}
+	} else {
+		if params.Temperature != nil {
+			body["temperature"] = *params.Temperature
+		}
+		if params.TopP != nil {
+			body["top_p"] = *params.TopP
+		}
+	}
+	if len(params.Stop) > 0 {
+		body["stop_sequences"] = params.Stop
+	}
+	// * thinking only allows auto, then the prompt has to steer the model to the tool
+	if schema != nil && !params.Reasoning() {
+		if len(tools) == 0 {
+			body["tool_choice"] = map[string]any{"type": "tool", "name": schema.Name}
+		} else {
+			body["tool_choice"] = map[string]any{"type": "any"}
+		}
+	}
+
+	result, _, err := utils.POST[Output](ctx, a.httpClient, a.baseURL+"/v1/messages", map[string]string{
+		"x-api-key":         a.apiKey,
+		"anthropic-version": "2023-06-01",
+		"Content-Type":      "application/json",
+	}, body, "json")
 	if err != nil {
 		return nil, fmt.Errorf("utils.POST: %w", err)
 	}
@@ -57,11 +131,7 @@ func (a *Agent) Send(ctx context.Context, messages []agentTypes.Message, tools [
 		return nil, fmt.Errorf("result.Error: %s", result.Error.Message)
 	}
 
-	if result.StopReason == "max_tokens" {
-		return nil, fmt.Errorf("exceeded max_tokens (%d)", maxTokens)
-	}
-
-	return a.convertToOutput(&result), nil
+	return a.convertToOutput(&result, schema), nil
 }
 
 func (a *Agent) convertToMessage(message agentTypes.Message) map[string]any {
@@ -72,14 +142,30 @@ func (a *Agent) convertToMessage(message agentTypes.Message) map[string]any {
 				{
 					"type":        "tool_result",
 					"tool_use_id": message.ToolCallID,
-					"content":     message.Content,
+					"content":     convertToContent(message.Content),
 				},
 			},
 		}
 	}
 
 	if len(message.ToolCalls) > 0 {
+		// * signed thinking must precede tool_use when the result is sent back
 		var content []map[string]any
+		for _, block := range message.Thinking {
+			switch block.Type {
+			case "thinking":
+				content = append(content, map[string]any{
+					"type":      "thinking",
+					"thinking":  block.Text,
+					"signature": block.Signature,
+				})
+			case "redacted_thinking":
+				content = append(content, map[string]any{
+					"type": "redacted_thinking",
+					"data": block.Data,
+				})
+			}
+		}
 		for _, tool := range message.ToolCalls {
 			var input map[string]any
 			json.Unmarshal([]byte(tool.Function.Arguments), &input)
@@ -98,7 +184,57 @@ func (a *Agent) convertToMessage(message agentTypes.Message) map[string]any {
 
 	return map[string]any{
 		"role":    message.Role,
-		"content": message.Content,
+		"content": convertToContent(message.Content),
+	}
+}
+
+// * images become image blocks and pdf files document blocks, both as base64 sources
+func convertToContent(content any) any {
+	if text, ok := content.(string); ok {
+		return text
+	}
+	parts := agentTypes.ContentParts(content)
+	blocks := make([]map[string]any, 0, len(parts))
+	for _, part := range parts {
+		switch part.Type {
+		case agentTypes.PartText:
+			blocks = append(blocks, map[string]any{"type": "text", "text": part.Text})
+		case agentTypes.PartImage, agentTypes.PartFile:
+			blockType := "image"
+			if part.Type == agentTypes.PartFile {
+				blockType = "document"
+			}
+			blocks = append(blocks, map[string]any{
+				"type": blockType,
+				"source": map[string]any{
+					"type":       "base64",
+					"media_type": part.MediaType,
+					"data":       part.Data,
+				},
+			})
+		}
+	}
+	return blocks
+}
+
+// * the growing conversation is cached up to its last message, each loop step reads the previous one
+func setCacheControl(message map[string]any) {
+	switch content := message["content"].(type) {
+	case string:
+		if content == "" {
+			return
+		}
+		message["content"] = []map[string]any{
+			{
+				"type":          "text",
+				"text":          content,
+				"cache_control": cacheControl,
+			},
+		}
+	case []map[string]any:
+		if len(content) > 0 {
+			content[len(content)-1]["cache_control"] = cacheControl
+		}
 	}
 }
 
@@ -114,16 +250,29 @@ func (a *Agent) convertToTools(tools []toolTypes.Tool) []map[string]any {
 	return newTools
 }
 
-func (a *Agent) convertToOutput(resp *Output) *agentTypes.Output {
+func (a *Agent) convertToOutput(resp *Output, schema *agentTypes.SchemaData) *agentTypes.Output {
 	output := &agentTypes.Output{
 		Choices: make([]agentTypes.OutputChoices, 1),
 	}
 
 	var toolCalls []agentTypes.ToolCall
-	var textContent string
+	var thinking []agentTypes.ThinkingBlock
+	var textContent, reasoning string
 
 	for _, item := range resp.Content {
-		if item.Type == "text" {
+		if item.Type == "thinking" {
+			reasoning += item.Thinking
+			thinking = append(thinking, agentTypes.ThinkingBlock{
+				Type:      item.Type,
+				Text:      item.Thinking,
+				Signature: item.Signature,
+			})
+		} else if item.Type == "redacted_thinking" {
+			thinking = append(thinking, agentTypes.ThinkingBlock{
+				Type: item.Type,
+				Data: item.Data,
+			})
+		} else if item.Type == "text" {
 			textContent = item.Text
 		} else if item.Type == "tool_use" {
 			arg := ""
@@ -145,10 +294,33 @@ func (a *Agent) convertToOutput(resp *Output) *agentTypes.Output {
 		}
 	}
 
+	finish := agentTypes.NormalizeFinish(resp.StopReason)
+	// * the schema tool is the answer, not a call to run; next to real calls it waits for their results
+	if schema != nil {
+		i := slices.IndexFunc(toolCalls, func(call agentTypes.ToolCall) bool { return call.Function.Name == schema.Name })
+		if i >= 0 && len(toolCalls) == 1 {
+			textContent = schema.ToolAnswer(json.RawMessage(toolCalls[i].Function.Arguments))
+			toolCalls = nil
+			finish = agentTypes.FinishStop
+		} else if i >= 0 {
+			toolCalls = slices.Delete(toolCalls, i, i+1)
+		}
+	}
+
 	output.Choices[0].Message = agentTypes.Message{
 		Role:      "assistant",
 		Content:   textContent,
 		ToolCalls: toolCalls,
+		Reasoning: reasoning,
+		Thinking:  thinking,
+	}
+	output.Choices[0].FinishReason = finish
+	// * input_tokens excludes cached tokens here, the shared form counts all of them
+	output.Usage = &agentTypes.UsageData{
+		InputTokens:      resp.Usage.InputTokens + resp.Usage.CacheReadInputTokens + resp.Usage.CacheCreationInputTokens,
+		OutputTokens:     resp.Usage.OutputTokens,
+		CacheReadTokens:  resp.Usage.CacheReadInputTokens,
+		CacheWriteTokens: resp.Usage.CacheCreationInputTokens,
 	}
 
 	return output
@@ -4,7 +4,8 @@ import (
 	"fmt"
 	"net/http"
 	"os"
-	"strings"
+
+	providerConfig "github.com/pardnchiu/agenvoy/internal/agents/provider/config"
 )
 
 type Agent struct {
@@ -17,22 +18,26 @@ type Agent struct {
 
 const (
 	defaultModel = "qwen3:8b"
-	prefix       = "compat@"
+	providerType = "compat"
 )
 
 func New(model ...string) (*Agent, error) {
-	usedModel := defaultModel
-	if len(model) > 0 && strings.HasPrefix(model[0], prefix) {
-		usedModel = strings.TrimPrefix(model[0], prefix)
-	}
+	cfg, usedModel := providerConfig.Resolve(providerType, defaultModel, model...)
 
 	baseURL := os.Getenv("COMPAT_URL")
 	if baseURL == "" {
 		baseURL = "http://localhost:11434"
 	}
-	baseURL = strings.TrimRight(baseURL, "/")
+	baseURL = cfg.URL(baseURL)
 
-	apiKey := os.Getenv("COMPAT_API_KEY")
+	apiKey, err := cfg.Key("COMPAT_API_KEY")
+	if err != nil {
+		return nil, fmt.Errorf("cfg.Key: %w", err)
+	}
+	httpClient, err := cfg.Client(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("cfg.Client: %w", err)
+	}
 
 	workDir, err := os.Getwd()
 	if err != nil {
@@ -40,7 +45,7 @@ func New(model ...string) (*Agent, error) {
 	}
 
 	return &Agent{
-		httpClient: &http.Client{},
+		httpClient: httpClient,
 		model:      usedModel,
 		baseURL:    baseURL,
 		apiKey:     apiKey,
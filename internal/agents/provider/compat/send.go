@@ -12,10 +12,16 @@ import (
 )
 
 func (a *Agent) Execute(ctx context.Context, skill *skill.Skill, userInput string, events chan<- agentTypes.Event, allowAll bool) error {
-	return exec.Execute(ctx, a, a.workDir, skill, userInput, events, allowAll)
+	return exec.Execute(ctx, exec.ExecData{
+		Agent:     a,
+		WorkDir:   a.workDir,
+		Skill:     skill,
+		UserInput: userInput,
+		AllowAll:  allowAll,
+	}, events)
 }
 
-func (a *Agent) Send(ctx context.Context, messages []agentTypes.Message, tools []toolTypes.Tool) (*agentTypes.Output, error) {
+func (a *Agent) Send(ctx context.Context, messages []agentTypes.Message, tools []toolTypes.Tool, opts agentTypes.OptionsData) (*agentTypes.Output, error) {
 	chatAPI := a.baseURL + "/v1/chat/completions"
 
 	headers := map[string]string{
@@ -25,17 +31,23 @@ func (a *Agent) Send(ctx context.Context, messages []agentTypes.Message, tools [
 		headers["Authorization"] = "Bearer " + a.apiKey
 	}
 
-	result, _, err := utils.POST[agentTypes.Output](ctx, a.httpClient, chatAPI, headers, map[string]any{
+	body := map[string]any{
 		"model":    a.model,
-		"messages": messages,
+		"messages": agentTypes.ChatMessages(messages),
 		"tools":    tools,
-	}, "json")
+	}
+	// * servers behind this api may reject unknown fields, reasoning_effort is never sent
+	opts.Params.SetChatParams(body, "max_tokens", false)
+	opts.Schema.SetChatFormat(body)
+
+	result, _, err := utils.POST[agentTypes.Output](ctx, a.httpClient, chatAPI, headers, body, "json")
 	if err != nil {
 		return nil, fmt.Errorf("utils.POST: %w", err)
 	}
 	if result.Error != nil {
 		return nil, fmt.Errorf("utils.POST: %s", result.Error.Message)
 	}
+	result.Normalize()
 
 	return &result, nil
 }
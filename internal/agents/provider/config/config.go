@@ -0,0 +1,231 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pardnchiu/agenvoy/internal/credential"
+	"github.com/pardnchiu/agenvoy/internal/utils"
+)
+
+// ProviderData is one named provider instance from the providers section of config.json.
+type ProviderData struct {
+	Name    string            `json:"-"`
+	Type    string            `json:"type"`
+	BaseURL string            `json:"base_url"`
+	APIKey  string            `json:"api_key"`
+	Headers map[string]string `json:"headers"`
+	Proxy   string            `json:"proxy"`
+	Timeout string            `json:"timeout"`
+}
+
+// warned keeps the project configs already reported, the warning fires once per path
+var warned sync.Map
+
+// * only the home config is read, a cloned repo must not point a key at its own url
+func load() map[string]ProviderData {
+	result := make(map[string]ProviderData)
+
+	configDir, err := utils.GetConfigDir()
+	if err != nil {
+		return result
+	}
+
+	path := filepath.Join(configDir.Work, "config.json")
+	if providers := readProviders(configDir.Work); len(providers) > 0 && configDir.Work != configDir.Home {
+		if _, seen := warned.LoadOrStore(path, true); !seen {
+			slog.Warn("providers in the project config are ignored, move them to the home config",
+				slog.String("path", path))
+		}
+	}
+	for name, p := range readProviders(configDir.Home) {
+		p.Name = name
+		if p.Type == "" {
+			p.Type = name
+		}
+		result[name] = p
+	}
+	return result
+}
+
+func readProviders(dir string) map[string]ProviderData {
+	data, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		return nil
+	}
+	var cfg struct {
+		Providers map[string]ProviderData `json:"providers"`
+	}
+	if json.Unmarshal(data, &cfg) != nil {
+		return nil
+	}
+	return cfg.Providers
+}
+
+// Get returns the instance config for name, an instance without an entry is its own type with no overrides.
+func Get(name string) ProviderData {
+	if p, ok := load()[name]; ok {
+		return p
+	}
+	return ProviderData{Name: name, Type: name}
+}
+
+// List returns every configured instance, sorted by name.
+func List() []ProviderData {
+	providers := load()
+	result := make([]ProviderData, 0, len(providers))
+	for _, p := range providers {
+		result = append(result, p)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// TypeOf returns the provider type behind an instance name, e.g. local -> compat.
+func TypeOf(name string) string {
+	return Get(name).Type
+}
+
+// Resolve splits <instance>@<model> and returns the instance config with the model to use.
+func Resolve(providerType, defaultModel string, model ...string) (ProviderData, string) {
+	name, usedModel := providerType, defaultModel
+	if len(model) > 0 {
+		if instance, m, ok := strings.Cut(model[0], "@"); ok {
+			name = instance
+			if m != "" {
+				usedModel = m
+			}
+		}
+	}
+
+	p := Get(name)
+	// * a name configured as another type is not ours, only the env fallbacks apply
+	if p.Type != providerType {
+		p = ProviderData{Name: name, Type: providerType}
+	}
+	return p, usedModel
+}
+
+// Key resolves api_key, then the secret stored by auth login for the instance, then the first env var that is set.
+func (p ProviderData) Key(envKeys ...string) (string, error) {
+	if p.APIKey != "" {
+		value, err := resolve(p.APIKey)
+		if err != nil {
+			return "", fmt.Errorf("providers.%s.api_key: %w", p.Name, err)
+		}
+		return value, nil
+	}
+
+	// * a store that cannot be unlocked only matters when the env has nothing either
+	value, storeErr := credential.Get(p.Name)
+	if storeErr == nil {
+		return value, nil
+	}
+	for _, key := range envKeys {
+		if value := os.Getenv(key); value != "" {
+			return value, nil
+		}
+	}
+	if !errors.Is(storeErr, credential.ErrNotFound) {
+		return "", fmt.Errorf("credential.Get: %w", storeErr)
+	}
+	return "", nil
+}
+
+// RequiredKey is Key that fails when neither api_key nor the env vars are set.
+func (p ProviderData) RequiredKey(envKeys ...string) (string, error) {
+	value, err := p.Key(envKeys...)
+	if err != nil {
+		return "", err
+	}
+	if value == "" {
+		return "", fmt.Errorf("providers.%s.api_key, auth login %s or %s is required", p.Name, p.Name, strings.Join(envKeys, " or "))
+	}
+	return value, nil
+}
+
+// URL returns base_url without a trailing slash, or fallback when unset.
+func (p ProviderData) URL(fallback string) string {
+	if p.BaseURL != "" {
+		return strings.TrimRight(p.BaseURL, "/")
+	}
+	return strings.TrimRight(fallback, "/")
+}
+
+// Client builds the http client for the instance with its proxy, timeout and extra headers, the headers only go to baseURL.
+func (p ProviderData) Client(baseURL string) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// * without a proxy the HTTPS_PROXY / NO_PROXY environment still applies
+	if p.Proxy != "" {
+		proxyURL, err := url.Parse(p.Proxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("providers.%s.proxy: invalid url %q", p.Name, p.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	client := &http.Client{Transport: transport}
+	if p.Timeout != "" {
+		timeout, err := time.ParseDuration(p.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("providers.%s.timeout: %w", p.Name, err)
+		}
+		client.Timeout = timeout
+	}
+
+	if len(p.Headers) > 0 {
+		headers := make(map[string]string, len(p.Headers))
+		for key, value := range p.Headers {
+			resolved, err := resolve(value)
+			if err != nil {
+				return nil, fmt.Errorf("providers.%s.headers.%s: %w", p.Name, key, err)
+			}
+			headers[key] = resolved
+		}
+		base, err := url.Parse(baseURL)
+		if err != nil || base.Host == "" {
+			return nil, fmt.Errorf("providers.%s: headers need a base url, got %q", p.Name, baseURL)
+		}
+		client.Transport = &headerTransport{base: transport, url: base, headers: headers}
+	}
+	return client, nil
+}
+
+type headerTransport struct {
+	base    http.RoundTripper
+	url     *url.URL
+	headers map[string]string
+}
+
+// * configured headers override what the provider sets, e.g. a gateway key in place of Authorization;
+// * token endpoints and other hosts never see them
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.matches(req.URL) {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+	return t.base.RoundTrip(req)
+}
+
+func (t *headerTransport) matches(u *url.URL) bool {
+	if u.Scheme != t.url.Scheme || u.Host != t.url.Host {
+		return false
+	}
+	prefix := strings.TrimRight(t.url.Path, "/")
+	return u.Path == prefix || strings.HasPrefix(u.Path, prefix+"/")
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pardnchiu/agenvoy/internal/credential"
+)
+
+// * values are env:NAME, keychain:NAME (the credential store) or a literal secret
+func resolve(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, "env:"):
+		key := strings.TrimPrefix(value, "env:")
+		result := os.Getenv(key)
+		if result == "" {
+			return "", fmt.Errorf("env %s is not set", key)
+		}
+		return result, nil
+
+	case strings.HasPrefix(value, "keychain:"):
+		name := strings.TrimPrefix(value, "keychain:")
+		result, err := credential.Get(name)
+		if errors.Is(err, credential.ErrNotFound) {
+			return "", fmt.Errorf("keychain %s is not set, run auth login %s", name, name)
+		}
+		if err != nil {
+			return "", fmt.Errorf("credential.Get: %w", err)
+		}
+		return result, nil
+
+	default:
+		return value, nil
+	}
+}
@@ -6,12 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"os/exec"
-	"path/filepath"
 	"runtime"
 	"time"
 
+	"github.com/pardnchiu/agenvoy/internal/credential"
 	"github.com/pardnchiu/agenvoy/internal/utils"
 )
 
@@ -121,19 +120,13 @@ func (c *Agent) getAccessToken(ctx context.Context, client *http.Client, deviceC
 			Scope:       accessToken.Scope,
 		}
 
-		path := filepath.Dir(c.tokenDir)
-		if err := os.MkdirAll(path, 0700); err != nil {
-			return nil, fmt.Errorf("os.MkdirAll: %w", err)
-		}
-
 		data, err := json.Marshal(token)
 		if err != nil {
-			return nil, fmt.Errorf("json.MarshalIndent: %w", err)
+			return nil, fmt.Errorf("json.Marshal: %w", err)
 		}
 
-		err = os.WriteFile(c.tokenDir, data, 0600)
-		if err != nil {
-			return nil, fmt.Errorf("os.WriteFile: %w", err)
+		if err := credential.Set(c.name, string(data)); err != nil {
+			return nil, fmt.Errorf("credential.Set: %w", err)
 		}
 		return token, nil
 
@@ -8,9 +8,10 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
-	"strings"
 	"time"
 
+	providerConfig "github.com/pardnchiu/agenvoy/internal/agents/provider/config"
+	"github.com/pardnchiu/agenvoy/internal/credential"
 	"github.com/pardnchiu/agenvoy/internal/utils"
 )
 
@@ -24,10 +25,11 @@ type Token struct {
 type Agent struct {
 	httpClient *http.Client
 	model      string
+	baseURL    string
 	Token      *Token
 	Refresh    *RefreshToken
 	workDir    string
-	tokenDir   string
+	name       string
 }
 
 const (
@@ -35,19 +37,15 @@ const (
 	// gpt-4.1-mini 1m/32k
 	// gpt-5-mini   400k/128k
 	// gpt-4o       128k/4k
-	defaultModel = "gpt-4.1"
-	prefix       = "copilot@"
+	defaultModel   = "gpt-4.1"
+	providerType   = "copilot"
+	defaultBaseURL = "https://api.githubcopilot.com"
 )
 
 func New(model ...string) (*Agent, error) {
-	usedModel := defaultModel
-	if len(model) > 0 && strings.HasPrefix(model[0], prefix) {
-		usedModel = strings.TrimPrefix(model[0], prefix)
-	}
-
-	workDir, err := os.Getwd()
+	agent, err := newAgent(model...)
 	if err != nil {
-		return nil, fmt.Errorf("os.Getwd: %w", err)
+		return nil, err
 	}
 
 	configDir, err := utils.GetConfigDir()
@@ -55,36 +53,84 @@ func New(model ...string) (*Agent, error) {
 		return nil, fmt.Errorf("utils.ConfigDir(: %w", err)
 	}
 
-	agent := &Agent{
-		httpClient: &http.Client{},
-		model:      usedModel,
-		workDir:    workDir,
-		tokenDir:   filepath.Join(configDir.Home, "copilot_token.json"),
+	token, err := agent.loadToken(filepath.Join(configDir.Home, "copilot_token.json"))
+	if errors.Is(err, credential.ErrNotFound) {
+		// * if is not exist, then login, github copilot code expire in 900s
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
+		defer cancel()
+
+		token, err = agent.Login(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("agent.Login: %w", err)
+		}
+		agent.Token = token
+		return agent, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("agent.loadToken: %w", err)
 	}
+	agent.Token = token
 
-	var token *Token
+	return agent, nil
+}
 
-	data, err := os.ReadFile(agent.tokenDir)
+// SignIn runs the device login for an instance even when a token is stored.
+// * the stored token is only replaced once github issues the new one
+func SignIn(name string) error {
+	agent, err := newAgent(name + "@")
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			// * if is not exist, then login, github copilot code expire in 900s
-			ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
-			defer cancel()
-
-			token, err = agent.Login(ctx)
-			if err != nil {
-				return nil, fmt.Errorf("agent.Login: %w", err)
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
+	defer cancel()
+	if _, err := agent.Login(ctx); err != nil {
+		return fmt.Errorf("agent.Login: %w", err)
+	}
+	return nil
+}
+
+func newAgent(model ...string) (*Agent, error) {
+	cfg, usedModel := providerConfig.Resolve(providerType, defaultModel, model...)
+	baseURL := cfg.URL(defaultBaseURL)
+	httpClient, err := cfg.Client(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("cfg.Client: %w", err)
+	}
+
+	workDir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("os.Getwd: %w", err)
+	}
+
+	return &Agent{
+		httpClient: httpClient,
+		model:      usedModel,
+		baseURL:    baseURL,
+		workDir:    workDir,
+		name:       cfg.Name,
+	}, nil
+}
+
+// * the token lives in the credential store, a plain token file from older versions is moved in once
+func (c *Agent) loadToken(legacyPath string) (*Token, error) {
+	data, err := credential.Get(c.name)
+	if errors.Is(err, credential.ErrNotFound) && c.name == providerType {
+		if legacy, readErr := os.ReadFile(legacyPath); readErr == nil {
+			if err := credential.Set(c.name, string(legacy)); err != nil {
+				return nil, fmt.Errorf("credential.Set: %w", err)
 			}
-			agent.Token = token
-			return agent, nil
+			os.Remove(legacyPath)
+			data, err = string(legacy), nil
 		}
-		return nil, fmt.Errorf("os.ReadFile: %w", err)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	if err := json.Unmarshal(data, &token); err != nil {
+	var token *Token
+	if err := json.Unmarshal([]byte(data), &token); err != nil {
 		return nil, fmt.Errorf("json.Unmarshal: %w", err)
 	}
-	agent.Token = token
-
-	return agent, nil
+	return token, nil
 }
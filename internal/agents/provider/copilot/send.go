@@ -11,33 +11,43 @@ import (
 	"github.com/pardnchiu/agenvoy/internal/utils"
 )
 
-const (
-	chatAPI = "https://api.githubcopilot.com/chat/completions"
-)
-
 func (a *Agent) Execute(ctx context.Context, skill *skill.Skill, userInput string, events chan<- agentTypes.Event, allowAll bool) error {
-	return exec.Execute(ctx, a, a.workDir, skill, userInput, events, allowAll)
+	return exec.Execute(ctx, exec.ExecData{
+		Agent:     a,
+		WorkDir:   a.workDir,
+		Skill:     skill,
+		UserInput: userInput,
+		AllowAll:  allowAll,
+	}, events)
 }
 
-func (a *Agent) Send(ctx context.Context, messages []agentTypes.Message, tools []toolTypes.Tool) (*agentTypes.Output, error) {
+func (a *Agent) Send(ctx context.Context, messages []agentTypes.Message, tools []toolTypes.Tool, opts agentTypes.OptionsData) (*agentTypes.Output, error) {
+	if err := opts.Params.Check(providerType, agentTypes.LimitsData{MaxStop: 4}); err != nil {
+		return nil, fmt.Errorf("opts.Params.Check: %w", err)
+	}
 	if err := a.checkExpires(ctx); err != nil {
 		return nil, fmt.Errorf("a.checkExpires: %w", err)
 	}
 
-	result, _, err := utils.POST[agentTypes.Output](ctx, a.httpClient, chatAPI, map[string]string{
-		"Authorization":  "Bearer " + a.Refresh.Token,
-		"Editor-Version": "vscode/1.95.0",
-	}, map[string]any{
+	body := map[string]any{
 		"model":    a.model,
-		"messages": messages,
+		"messages": agentTypes.ChatMessages(messages),
 		"tools":    tools,
-	}, "json")
+	}
+	opts.Params.SetChatParams(body, "max_tokens", agentTypes.ReasoningModel(a.model))
+	opts.Schema.SetChatFormat(body)
+
+	result, _, err := utils.POST[agentTypes.Output](ctx, a.httpClient, a.baseURL+"/chat/completions", map[string]string{
+		"Authorization":  "Bearer " + a.Refresh.Token,
+		"Editor-Version": "vscode/1.95.0",
+	}, body, "json")
 	if err != nil {
 		return nil, fmt.Errorf("utils.POST: %w", err)
 	}
 	if result.Error != nil {
 		return nil, fmt.Errorf("utils.POST: %s", result.Error.Message)
 	}
+	result.Normalize()
 
 	return &result, nil
 }
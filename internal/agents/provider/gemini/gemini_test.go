@@ -0,0 +1,68 @@
+package gemini
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+	toolTypes "github.com/pardnchiu/agenvoy/internal/tools/types"
+)
+
+func TestRequestBody_Schema(t *testing.T) {
+	messages := []agentTypes.Message{
+		{Role: "system", Content: "系統"},
+		{Role: "user", Content: "1+1?"},
+	}
+	tools := []toolTypes.Tool{
+		{Type: "function", Function: toolTypes.ToolFunction{Name: "calculate", Description: "計算", Parameters: json.RawMessage(`{"type":"object"}`)}},
+	}
+	schema := &agentTypes.SchemaData{Name: "answer", Schema: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"answer": map[string]any{"type": "integer", "minimum": 0},
+			"note":   map[string]any{"type": []any{"string", "null"}},
+			"steps":  map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		},
+		"required":             []any{"answer"},
+		"additionalProperties": false,
+	}}
+	temperature := 0.2
+
+	t.Run("without tools", func(t *testing.T) {
+		body := RequestBody(messages, nil, agentTypes.OptionsData{
+			Params: agentTypes.ParamsData{Temperature: &temperature},
+			Schema: schema,
+		})
+		data, _ := json.Marshal(body["generationConfig"])
+		var got, want any
+		json.Unmarshal(data, &got)
+		json.Unmarshal([]byte(`{
+			"temperature": 0.2,
+			"responseMimeType": "application/json",
+			"responseSchema": {
+				"type": "OBJECT",
+				"properties": {
+					"answer": {"type": "INTEGER", "minimum": 0},
+					"note": {"type": "STRING", "nullable": true},
+					"steps": {"type": "ARRAY", "items": {"type": "STRING"}}
+				},
+				"required": ["answer"]
+			}
+		}`), &want)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("generationConfig = %s", data)
+		}
+	})
+
+	// * json mode and function calling are exclusive, the schema only reaches the model through the prompt
+	t.Run("with tools", func(t *testing.T) {
+		body := RequestBody(messages, tools, agentTypes.OptionsData{Schema: schema})
+		if config, ok := body["generationConfig"]; ok {
+			t.Errorf("generationConfig = %v, want unset", config)
+		}
+		if _, ok := body["tools"]; !ok {
+			t.Error("tools missing")
+		}
+	})
+}
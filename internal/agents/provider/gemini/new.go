@@ -4,12 +4,14 @@ import (
 	"fmt"
 	"net/http"
 	"os"
-	"strings"
+
+	providerConfig "github.com/pardnchiu/agenvoy/internal/agents/provider/config"
 )
 
 type Agent struct {
 	httpClient *http.Client
 	model      string
+	baseURL    string
 	apiKey     string
 	workDir    string
 }
@@ -17,26 +19,29 @@ type Agent struct {
 const (
 	// gemini-2.5-pro   1m/64k
 	// gemini-2.5-flash 1m/64k
-	defaultModel = "gemini-2.5-pro"
-	prefix       = "gemini@"
+	defaultModel   = "gemini-2.5-pro"
+	providerType   = "gemini"
+	defaultBaseURL = "https://generativelanguage.googleapis.com/v1beta"
 )
 
 func New(model ...string) (*Agent, error) {
-	usedModel := defaultModel
-	if len(model) > 0 && strings.HasPrefix(model[0], prefix) {
-		usedModel = strings.TrimPrefix(model[0], prefix)
+	cfg, usedModel := providerConfig.Resolve(providerType, defaultModel, model...)
+	apiKey, err := cfg.RequiredKey("GEMINI_API_KEY")
+	if err != nil {
+		return nil, fmt.Errorf("cfg.RequiredKey: %w", err)
 	}
-
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("os.Getenv: GEMINI_API_KEY is required")
+	baseURL := cfg.URL(defaultBaseURL)
+	httpClient, err := cfg.Client(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("cfg.Client: %w", err)
 	}
 
 	workDir, _ := os.Getwd()
 
 	return &Agent{
-		httpClient: &http.Client{},
+		httpClient: httpClient,
 		model:      usedModel,
+		baseURL:    baseURL,
 		apiKey:     apiKey,
 		workDir:    workDir,
 	}, nil
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/pardnchiu/agenvoy/internal/agents/exec"
 	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
@@ -13,18 +14,42 @@ import (
 )
 
 const (
-	baseAPI = "https://generativelanguage.googleapis.com/v1beta/models/"
+	thoughtSignature = "thought_signature"
 )
 
 func (a *Agent) Execute(ctx context.Context, skill *skill.Skill, userInput string, events chan<- agentTypes.Event, allowAll bool) error {
-	return exec.Execute(ctx, a, a.workDir, skill, userInput, events, allowAll)
+	return exec.Execute(ctx, exec.ExecData{
+		Agent:     a,
+		WorkDir:   a.workDir,
+		Skill:     skill,
+		UserInput: userInput,
+		AllowAll:  allowAll,
+	}, events)
 }
 
-func (a *Agent) Send(ctx context.Context, messages []agentTypes.Message, tools []toolTypes.Tool) (*agentTypes.Output, error) {
+func (a *Agent) Send(ctx context.Context, messages []agentTypes.Message, tools []toolTypes.Tool, opts agentTypes.OptionsData) (*agentTypes.Output, error) {
+	if err := opts.Params.Check(providerType, agentTypes.LimitsData{MaxStop: 5}); err != nil {
+		return nil, fmt.Errorf("opts.Params.Check: %w", err)
+	}
+	apiURL := fmt.Sprintf("%s/models/%s:generateContent?key=%s", a.baseURL, a.model, a.apiKey)
+
+	result, _, err := utils.POST[Output](ctx, a.httpClient, apiURL, map[string]string{
+		"Content-Type": "application/json",
+	}, RequestBody(messages, tools, opts), "json")
+	if err != nil {
+		return nil, fmt.Errorf("utils.POST: %w", err)
+	}
+
+	return ConvertToOutput(&result), nil
+}
+
+// RequestBody builds a generateContent body, vertex serves gemini models with the same one.
+func RequestBody(messages []agentTypes.Message, tools []toolTypes.Tool, opts agentTypes.OptionsData) map[string]any {
 	var systemPrompt string
 	var newMessages []Content
 
-	for _, msg := range messages {
+	// * function responses carry json only, their media follows as a user turn
+	for _, msg := range agentTypes.SplitToolMedia(messages) {
 		if msg.Role == "system" {
 			if content, ok := msg.Content.(string); ok {
 				systemPrompt = content
@@ -32,31 +57,31 @@ func (a *Agent) Send(ctx context.Context, messages []agentTypes.Message, tools [
 			continue
 		}
 
-		message := a.convertToContent(msg)
+		message := convertToContent(msg)
 		newMessages = append(newMessages, message)
 	}
 
-	newTools := a.convertToTools(tools)
-	apiURL := fmt.Sprintf("%s%s:generateContent?key=%s", baseAPI, a.model, a.apiKey)
-	requestBody := a.generateRequestBody(newMessages, systemPrompt, newTools)
-
-	result, _, err := utils.POST[Output](ctx, a.httpClient, apiURL, map[string]string{
-		"Content-Type": "application/json",
-	}, requestBody, "json")
-	if err != nil {
-		return nil, fmt.Errorf("utils.POST: %w", err)
+	body := generateRequestBody(newMessages, systemPrompt, convertToTools(tools), opts.Params)
+	// * json mode cannot be combined with function calling, turns with tools rely on the prompt
+	// * and the repair turn exec sends without tools
+	if opts.Schema != nil && len(tools) == 0 {
+		config, _ := body["generationConfig"].(map[string]any)
+		if config == nil {
+			config = map[string]any{}
+			body["generationConfig"] = config
+		}
+		config["responseMimeType"] = "application/json"
+		config["responseSchema"] = convertToSchema(opts.Schema.Schema)
 	}
-
-	return a.convertToOutput(&result), nil
+	return body
 }
 
-func (a *Agent) convertToContent(message agentTypes.Message) Content {
+func convertToContent(message agentTypes.Message) Content {
 	content := Content{}
 	if message.ToolCallID != "" {
 		content.Role = "function"
-		data := map[string]any{}
-		if contentStr, ok := message.Content.(string); ok {
-			data["result"] = contentStr
+		data := map[string]any{
+			"result": agentTypes.ContentText(message.Content),
 		}
 		content.Parts = []Part{
 			{
@@ -76,6 +101,13 @@ func (a *Agent) convertToContent(message agentTypes.Message) Content {
 	content.Role = role
 
 	if len(message.ToolCalls) > 0 {
+		// * thought signatures go back on the function call they came with
+		signatures := make(map[string]string, len(message.Thinking))
+		for _, block := range message.Thinking {
+			if block.Type == thoughtSignature {
+				signatures[block.Text] = block.Signature
+			}
+		}
 		for _, tool := range message.ToolCalls {
 			var args map[string]any
 			json.Unmarshal([]byte(tool.Function.Arguments), &args)
@@ -84,6 +116,7 @@ func (a *Agent) convertToContent(message agentTypes.Message) Content {
 					Name: tool.Function.Name,
 					Args: args,
 				},
+				ThoughtSignature: signatures[tool.Function.Name],
 			})
 		}
 		return content
@@ -93,12 +126,26 @@ func (a *Agent) convertToContent(message agentTypes.Message) Content {
 		content.Parts = []Part{
 			{Text: contentStr},
 		}
+		return content
 	}
 
+	for _, part := range agentTypes.ContentParts(message.Content) {
+		switch part.Type {
+		case agentTypes.PartText:
+			content.Parts = append(content.Parts, Part{Text: part.Text})
+		case agentTypes.PartImage, agentTypes.PartFile:
+			content.Parts = append(content.Parts, Part{
+				InlineData: &InlineData{
+					MimeType: part.MediaType,
+					Data:     part.Data,
+				},
+			})
+		}
+	}
 	return content
 }
 
-func (a *Agent) convertToTools(tools []toolTypes.Tool) []map[string]any {
+func convertToTools(tools []toolTypes.Tool) []map[string]any {
 	newTools := make([]map[string]any, len(tools))
 	for i, tool := range tools {
 		var params map[string]any
@@ -113,7 +160,61 @@ func (a *Agent) convertToTools(tools []toolTypes.Tool) []map[string]any {
 	return newTools
 }
 
-func (a *Agent) generateRequestBody(messages []Content, prompt string, newTools []map[string]any) map[string]any {
+// * responseSchema takes the openapi subset, types are upper case and null is a nullable flag
+func convertToSchema(schema map[string]any) map[string]any {
+	result := map[string]any{}
+	for key, value := range schema {
+		switch key {
+		case "type":
+			var types []string
+			switch t := value.(type) {
+			case string:
+				types = []string{t}
+			case []any:
+				for _, item := range t {
+					if str, ok := item.(string); ok {
+						types = append(types, str)
+					}
+				}
+			}
+			for _, t := range types {
+				if t == "null" {
+					result["nullable"] = true
+				} else {
+					result["type"] = strings.ToUpper(t)
+				}
+			}
+		case "properties":
+			props, _ := value.(map[string]any)
+			converted := make(map[string]any, len(props))
+			for name, prop := range props {
+				if sub, ok := prop.(map[string]any); ok {
+					converted[name] = convertToSchema(sub)
+				}
+			}
+			result[key] = converted
+		case "items":
+			if sub, ok := value.(map[string]any); ok {
+				result[key] = convertToSchema(sub)
+			}
+		case "anyOf":
+			options, _ := value.([]any)
+			converted := make([]any, 0, len(options))
+			for _, option := range options {
+				if sub, ok := option.(map[string]any); ok {
+					converted = append(converted, convertToSchema(sub))
+				}
+			}
+			result[key] = converted
+		case "required", "enum", "description", "format", "nullable",
+			"minItems", "maxItems", "minimum", "maximum", "propertyOrdering":
+			result[key] = value
+		}
+	}
+	return result
+}
+
+func generateRequestBody(messages []Content, prompt string, newTools []map[string]any, params agentTypes.ParamsData) map[string]any {
 	body := map[string]any{
 		"contents": messages,
 	}
@@ -131,26 +232,72 @@ func (a *Agent) generateRequestBody(messages []Content, prompt string, newTools
 			{"functionDeclarations": newTools},
 		}
 	}
+
+	config := map[string]any{}
+	if params.Temperature != nil {
+		config["temperature"] = *params.Temperature
+	}
+	if params.TopP != nil {
+		config["topP"] = *params.TopP
+	}
+	if params.MaxTokens > 0 {
+		config["maxOutputTokens"] = params.MaxTokens
+	}
+	if params.Seed != nil {
+		config["seed"] = *params.Seed
+	}
+	if len(params.Stop) > 0 {
+		config["stopSequences"] = params.Stop
+	}
+	if params.Reasoning() {
+		config["thinkingConfig"] = map[string]any{
+			"thinkingBudget":  params.ReasoningTokens(),
+			"includeThoughts": true,
+		}
+	}
+	if len(config) > 0 {
+		body["generationConfig"] = config
+	}
 	return body
 }
 
-func (a *Agent) convertToOutput(resp *Output) *agentTypes.Output {
+// ConvertToOutput maps a generateContent response onto the shared output.
+func ConvertToOutput(resp *Output) *agentTypes.Output {
 	output := &agentTypes.Output{
 		Choices: make([]agentTypes.OutputChoices, 1),
 	}
 
+	// * gemini caches long prefixes implicitly, thoughts are billed as output
+	if resp.UsageMetadata != nil {
+		output.Usage = &agentTypes.UsageData{
+			InputTokens:     resp.UsageMetadata.PromptTokenCount,
+			OutputTokens:    resp.UsageMetadata.CandidatesTokenCount + resp.UsageMetadata.ThoughtsTokenCount,
+			CacheReadTokens: resp.UsageMetadata.CachedTokenCount,
+		}
+	}
+
 	if len(resp.Candidates) == 0 {
 		return output
 	}
 
 	candidate := resp.Candidates[0]
 	var toolCalls []agentTypes.ToolCall
-	var textContent string
+	var thinking []agentTypes.ThinkingBlock
+	var textContent, reasoning string
 
 	for _, part := range candidate.Content.Parts {
-		if part.Text != "" {
+		if part.Thought {
+			reasoning += part.Text
+		} else if part.Text != "" {
 			textContent = part.Text
 		} else if part.FunctionCall != nil {
+			if part.ThoughtSignature != "" {
+				thinking = append(thinking, agentTypes.ThinkingBlock{
+					Type:      thoughtSignature,
+					Text:      part.FunctionCall.Name,
+					Signature: part.ThoughtSignature,
+				})
+			}
 			args := "{}"
 			if part.FunctionCall.Args != nil {
 				data, err := json.Marshal(part.FunctionCall.Args)
@@ -174,8 +321,10 @@ func (a *Agent) convertToOutput(resp *Output) *agentTypes.Output {
 		Role:      "assistant",
 		Content:   textContent,
 		ToolCalls: toolCalls,
+		Reasoning: reasoning,
+		Thinking:  thinking,
 	}
-	output.Choices[0].FinishReason = candidate.FinishReason
+	output.Choices[0].FinishReason = agentTypes.NormalizeFinish(candidate.FinishReason)
 
 	return output
 }
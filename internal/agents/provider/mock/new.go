@@ -0,0 +1,90 @@
+package mock
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+	toolTypes "github.com/pardnchiu/agenvoy/internal/tools/types"
+	"gopkg.in/yaml.v3"
+)
+
+type Agent struct {
+	mu       sync.Mutex
+	script   Script
+	step     int
+	requests [][]agentTypes.Message
+	params   []agentTypes.ParamsData
+	schemas  []*agentTypes.SchemaData
+	tools    [][]toolTypes.Tool
+	workDir  string
+}
+
+const (
+	prefix = "mock@"
+)
+
+// * mock@<script.yaml>, falls back to MOCK_SCRIPT
+func New(model ...string) (*Agent, error) {
+	path := os.Getenv("MOCK_SCRIPT")
+	if len(model) > 0 && strings.HasPrefix(model[0], prefix) {
+		path = strings.TrimPrefix(model[0], prefix)
+	}
+	if path == "" {
+		return nil, fmt.Errorf("os.Getenv: MOCK_SCRIPT is required")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile: %w", err)
+	}
+
+	var script Script
+	if err := yaml.Unmarshal(data, &script); err != nil {
+		return nil, fmt.Errorf("yaml.Unmarshal: %w", err)
+	}
+
+	return NewWithScript(script)
+}
+
+func NewWithScript(script Script) (*Agent, error) {
+	workDir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("os.Getwd: %w", err)
+	}
+
+	return &Agent{
+		script:  script,
+		workDir: workDir,
+	}, nil
+}
+
+// Requests returns every message list passed to Send, in call order.
+func (a *Agent) Requests() [][]agentTypes.Message {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([][]agentTypes.Message(nil), a.requests...)
+}
+
+// Params returns the generation params of every Send, in call order.
+func (a *Agent) Params() []agentTypes.ParamsData {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]agentTypes.ParamsData(nil), a.params...)
+}
+
+// Schemas returns the response schema of every Send, nil where none was asked.
+func (a *Agent) Schemas() []*agentTypes.SchemaData {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]*agentTypes.SchemaData(nil), a.schemas...)
+}
+
+// Tools returns the tool definitions of every Send, in call order.
+func (a *Agent) Tools() [][]toolTypes.Tool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([][]toolTypes.Tool(nil), a.tools...)
+}
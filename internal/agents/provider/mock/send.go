@@ -0,0 +1,133 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pardnchiu/agenvoy/internal/agents/exec"
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+	"github.com/pardnchiu/agenvoy/internal/skill"
+	toolTypes "github.com/pardnchiu/agenvoy/internal/tools/types"
+)
+
+func (a *Agent) Execute(ctx context.Context, skill *skill.Skill, userInput string, events chan<- agentTypes.Event, allowAll bool) error {
+	return exec.Execute(ctx, exec.ExecData{
+		Agent:     a,
+		WorkDir:   a.workDir,
+		Skill:     skill,
+		UserInput: userInput,
+		AllowAll:  allowAll,
+	}, events)
+}
+
+func (a *Agent) Send(ctx context.Context, messages []agentTypes.Message, tools []toolTypes.Tool, opts agentTypes.OptionsData) (*agentTypes.Output, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.requests = append(a.requests, append([]agentTypes.Message(nil), messages...))
+	a.params = append(a.params, opts.Params)
+	a.schemas = append(a.schemas, opts.Schema)
+	a.tools = append(a.tools, tools)
+
+	step, err := a.next(messages)
+	if err != nil {
+		return nil, err
+	}
+
+	if step.Error != "" {
+		return nil, fmt.Errorf("%s", step.Error)
+	}
+	if step.Empty {
+		return &agentTypes.Output{}, nil
+	}
+
+	message := agentTypes.Message{
+		Role:      "assistant",
+		Content:   step.Content,
+		Reasoning: step.Reasoning,
+	}
+	for i, call := range step.ToolCalls {
+		id := call.ID
+		if id == "" {
+			id = fmt.Sprintf("call_%d_%d", len(a.requests), i)
+		}
+
+		args := "{}"
+		switch v := call.Arguments.(type) {
+		case nil:
+		case string:
+			args = v
+		default:
+			data, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("json.Marshal: %w", err)
+			}
+			args = string(data)
+		}
+
+		toolCall := agentTypes.ToolCall{
+			ID:   id,
+			Type: "function",
+		}
+		toolCall.Function.Name = call.Name
+		toolCall.Function.Arguments = args
+		message.ToolCalls = append(message.ToolCalls, toolCall)
+	}
+
+	output := &agentTypes.Output{
+		Choices: []agentTypes.OutputChoices{
+			{
+				Message:      message,
+				FinishReason: agentTypes.NormalizeFinish(step.FinishReason),
+			},
+		},
+	}
+	if step.Usage != nil {
+		output.Usage = &agentTypes.UsageData{
+			InputTokens:      step.Usage.Input,
+			OutputTokens:     step.Usage.Output,
+			CacheReadTokens:  step.Usage.CacheRead,
+			CacheWriteTokens: step.Usage.CacheWrite,
+		}
+	}
+	return output, nil
+}
+
+func (a *Agent) next(messages []agentTypes.Message) (Step, error) {
+	var system, last string
+	if len(messages) > 0 {
+		if messages[0].Role == "system" {
+			system, _ = messages[0].Content.(string)
+		}
+		last = agentTypes.ContentText(messages[len(messages)-1].Content)
+	}
+
+	for _, rule := range a.script.Rules {
+		if rule.System == "" && rule.Last == "" {
+			continue
+		}
+		if rule.System != "" && !strings.Contains(system, rule.System) {
+			continue
+		}
+		if rule.Last != "" && !strings.Contains(last, rule.Last) {
+			continue
+		}
+		return rule.Step, nil
+	}
+
+	if a.step >= len(a.script.Steps) {
+		return Step{}, fmt.Errorf("mock: script exhausted after %d steps", len(a.script.Steps))
+	}
+
+	step := a.script.Steps[a.step]
+	if !step.Repeat {
+		a.step++
+	}
+	return step, nil
+}
@@ -0,0 +1,37 @@
+package mock
+
+type Script struct {
+	Rules []Rule `yaml:"rules"`
+	Steps []Step `yaml:"steps"`
+}
+
+// * rules answer matching requests every time, checked before steps
+type Rule struct {
+	System string `yaml:"system"` // substring of the first system message
+	Last   string `yaml:"last"`   // substring of the last message
+	Step   `yaml:",inline"`
+}
+
+type Step struct {
+	Content      string     `yaml:"content"`
+	Reasoning    string     `yaml:"reasoning"`
+	ToolCalls    []ToolCall `yaml:"tool_calls"`
+	Empty        bool       `yaml:"empty"`  // respond without choices
+	Error        string     `yaml:"error"`  // fail Send with this message
+	Repeat       bool       `yaml:"repeat"` // keep serving this step instead of advancing
+	FinishReason string     `yaml:"finish_reason"`
+	Usage        *Usage     `yaml:"usage"`
+}
+
+type Usage struct {
+	Input      int `yaml:"input"`
+	Output     int `yaml:"output"`
+	CacheRead  int `yaml:"cache_read"`
+	CacheWrite int `yaml:"cache_write"`
+}
+
+type ToolCall struct {
+	ID        string `yaml:"id"`
+	Name      string `yaml:"name"`
+	Arguments any    `yaml:"arguments"`
+}
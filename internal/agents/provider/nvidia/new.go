@@ -4,12 +4,14 @@ import (
 	"fmt"
 	"net/http"
 	"os"
-	"strings"
+
+	providerConfig "github.com/pardnchiu/agenvoy/internal/agents/provider/config"
 )
 
 type Agent struct {
 	httpClient *http.Client
 	model      string
+	baseURL    string
 	apiKey     string
 	workDir    string
 }
@@ -19,25 +21,29 @@ const (
 	// z-ai/glm4.7
 	// qwen/qwen3-235b-a22b
 	// qwen/qwen3-coder-480b-a35b-instruct
-	defaultModel = "openai/gpt-oss-120b"
-	prefix       = "nvidia@"
+	defaultModel   = "openai/gpt-oss-120b"
+	providerType   = "nvidia"
+	defaultBaseURL = "https://integrate.api.nvidia.com/v1"
 )
 
 func New(model ...string) (*Agent, error) {
-	usedModel := defaultModel
-	if len(model) > 0 && strings.HasPrefix(model[0], prefix) {
-		usedModel = strings.TrimPrefix(model[0], prefix)
+	cfg, usedModel := providerConfig.Resolve(providerType, defaultModel, model...)
+	apiKey, err := cfg.RequiredKey("NVIDIA_API_KEY")
+	if err != nil {
+		return nil, fmt.Errorf("cfg.RequiredKey: %w", err)
 	}
-	apiKey := os.Getenv("NVIDIA_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("os.Getenv: NVIDIA_API_KEY is required")
+	baseURL := cfg.URL(defaultBaseURL)
+	httpClient, err := cfg.Client(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("cfg.Client: %w", err)
 	}
 
 	workDir, _ := os.Getwd()
 
 	return &Agent{
-		httpClient: &http.Client{},
+		httpClient: httpClient,
 		model:      usedModel,
+		baseURL:    baseURL,
 		apiKey:     apiKey,
 		workDir:    workDir,
 	}, nil
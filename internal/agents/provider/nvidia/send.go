@@ -11,29 +11,37 @@ import (
 	"github.com/pardnchiu/agenvoy/internal/utils"
 )
 
-const (
-	chatAPI = "https://integrate.api.nvidia.com/v1/chat/completions"
-)
-
 func (a *Agent) Execute(ctx context.Context, skill *skill.Skill, userInput string, events chan<- agentTypes.Event, allowAll bool) error {
-	return exec.Execute(ctx, a, a.workDir, skill, userInput, events, allowAll)
+	return exec.Execute(ctx, exec.ExecData{
+		Agent:     a,
+		WorkDir:   a.workDir,
+		Skill:     skill,
+		UserInput: userInput,
+		AllowAll:  allowAll,
+	}, events)
 }
 
-func (a *Agent) Send(ctx context.Context, messages []agentTypes.Message, tools []toolTypes.Tool) (*agentTypes.Output, error) {
-	result, _, err := utils.POST[agentTypes.Output](ctx, a.httpClient, chatAPI, map[string]string{
-		"Authorization": "Bearer " + a.apiKey,
-		"Content-Type":  "application/json",
-	}, map[string]any{
+func (a *Agent) Send(ctx context.Context, messages []agentTypes.Message, tools []toolTypes.Tool, opts agentTypes.OptionsData) (*agentTypes.Output, error) {
+	body := map[string]any{
 		"model":    a.model,
-		"messages": messages,
+		"messages": agentTypes.ChatMessages(messages),
 		"tools":    tools,
-	}, "json")
+	}
+	// * servers behind this api may reject unknown fields, reasoning_effort is never sent
+	opts.Params.SetChatParams(body, "max_tokens", false)
+	opts.Schema.SetChatFormat(body)
+
+	result, _, err := utils.POST[agentTypes.Output](ctx, a.httpClient, a.baseURL+"/chat/completions", map[string]string{
+		"Authorization": "Bearer " + a.apiKey,
+		"Content-Type":  "application/json",
+	}, body, "json")
 	if err != nil {
 		return nil, fmt.Errorf("utils.POST: %w", err)
 	}
 	if result.Error != nil {
 		return nil, fmt.Errorf("utils.POST: %s", result.Error.Message)
 	}
+	result.Normalize()
 
 	return &result, nil
 }
@@ -0,0 +1,84 @@
+package ollama
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/pardnchiu/agenvoy/internal/utils"
+)
+
+// ContextLength returns the context window the model was trained with, pulling the model first if missing.
+func (a *Agent) ContextLength(ctx context.Context) (int, error) {
+	if err := a.ensureModel(ctx); err != nil {
+		return 0, err
+	}
+	return a.contextLength, nil
+}
+
+// * checked once per agent, a missing model is pulled before the first request
+func (a *Agent) ensureModel(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.ready {
+		return nil
+	}
+
+	show, status, err := a.show(ctx)
+	if err != nil {
+		return fmt.Errorf("a.show: %w", err)
+	}
+	if status == http.StatusNotFound {
+		slog.Info("pulling ollama model", slog.String("model", a.model))
+		if err := a.pull(ctx); err != nil {
+			return fmt.Errorf("a.pull: %w", err)
+		}
+		if show, status, err = a.show(ctx); err != nil {
+			return fmt.Errorf("a.show: %w", err)
+		}
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("ollama show %s: status %d", a.model, status)
+	}
+
+	a.contextLength = getContextLength(show.ModelInfo)
+	a.ready = true
+	return nil
+}
+
+func (a *Agent) show(ctx context.Context) (ShowOutput, int, error) {
+	return utils.POST[ShowOutput](ctx, a.httpClient, a.baseURL+"/api/show", nil, map[string]any{
+		"model": a.model,
+	}, "json")
+}
+
+func (a *Agent) pull(ctx context.Context) error {
+	result, status, err := utils.POST[PullOutput](ctx, a.httpClient, a.baseURL+"/api/pull", nil, map[string]any{
+		"model":  a.model,
+		"stream": false,
+	}, "json")
+	if err != nil {
+		return fmt.Errorf("utils.POST: %w", err)
+	}
+	if status != http.StatusOK || result.Error != "" {
+		return fmt.Errorf("ollama pull %s: status %d %s", a.model, status, result.Error)
+	}
+	return nil
+}
+
+// * model_info keys are prefixed by the architecture, e.g. qwen3.context_length
+func getContextLength(info map[string]any) int {
+	if arch, ok := info["general.architecture"].(string); ok {
+		if n, ok := info[arch+".context_length"].(float64); ok {
+			return int(n)
+		}
+	}
+	for key, value := range info {
+		if n, ok := value.(float64); ok && strings.HasSuffix(key, ".context_length") {
+			return int(n)
+		}
+	}
+	return 0
+}
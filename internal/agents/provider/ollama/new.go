@@ -0,0 +1,73 @@
+package ollama
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	providerConfig "github.com/pardnchiu/agenvoy/internal/agents/provider/config"
+)
+
+type Agent struct {
+	httpClient *http.Client
+	model      string
+	baseURL    string
+	keepAlive  any
+	workDir    string
+
+	mu            sync.Mutex
+	ready         bool
+	contextLength int
+}
+
+const (
+	defaultModel = "qwen3:8b"
+	providerType = "ollama"
+)
+
+func New(model ...string) (*Agent, error) {
+	cfg, usedModel := providerConfig.Resolve(providerType, defaultModel, model...)
+
+	// * same variable the ollama cli reads, it may come without a scheme
+	baseURL := cfg.URL(os.Getenv("OLLAMA_HOST"))
+	if baseURL == "" {
+		baseURL = "http://localhost:11434"
+	}
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "http://" + baseURL
+	}
+
+	// * pulls go through the same client, a timeout has to leave room for them
+	httpClient, err := cfg.Client(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("cfg.Client: %w", err)
+	}
+
+	workDir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("os.Getwd: %w", err)
+	}
+
+	return &Agent{
+		httpClient: httpClient,
+		model:      usedModel,
+		baseURL:    baseURL,
+		keepAlive:  getKeepAlive(os.Getenv("OLLAMA_KEEP_ALIVE")),
+		workDir:    workDir,
+	}, nil
+}
+
+// * keep_alive takes a duration like 30m, or seconds where -1 keeps the model loaded
+func getKeepAlive(value string) any {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	if n, err := strconv.Atoi(value); err == nil {
+		return n
+	}
+	return value
+}
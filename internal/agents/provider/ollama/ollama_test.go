@@ -0,0 +1,119 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+)
+
+// stub serves the native api for one missing model, recording every chat body.
+type stub struct {
+	pulled bool
+	chats  []map[string]any
+}
+
+func (s *stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body map[string]any
+	json.NewDecoder(r.Body).Decode(&body)
+
+	switch r.URL.Path {
+	case "/api/show":
+		if !s.pulled {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"model not found"}`))
+			return
+		}
+		w.Write([]byte(`{"model_info":{"general.architecture":"qwen3","qwen3.context_length":40960}}`))
+	case "/api/pull":
+		s.pulled = true
+		w.Write([]byte(`{"status":"success"}`))
+	case "/api/chat":
+		s.chats = append(s.chats, body)
+		if len(s.chats) == 1 {
+			w.Write([]byte(`{"message":{"role":"assistant","content":"","thinking":"先計算","tool_calls":[{"function":{"name":"calculate","arguments":{"expression":"1+1"}}}]},"done":true,"done_reason":"stop","prompt_eval_count":30,"eval_count":5}`))
+			return
+		}
+		w.Write([]byte(`{"message":{"role":"assistant","content":"2"},"done":true,"done_reason":"length","prompt_eval_count":40,"eval_count":1}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestSend_PullAndToolLoop(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	s := &stub{}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	t.Setenv("OLLAMA_HOST", strings.TrimPrefix(srv.URL, "http://"))
+	t.Setenv("OLLAMA_KEEP_ALIVE", "-1")
+	t.Setenv("OLLAMA_CONTEXT_LENGTH", "")
+
+	a, err := New("ollama@qwen3:8b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	messages := []agentTypes.Message{
+		{Role: "system", Content: "system"},
+		{Role: "user", Content: []agentTypes.ContentPart{
+			{Type: agentTypes.PartText, Text: "1+1?"},
+			{Type: agentTypes.PartImage, MediaType: "image/png", Data: "aW1n"},
+		}},
+	}
+	out, err := a.Send(ctx, messages, nil, agentTypes.OptionsData{})
+	if err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if !s.pulled {
+		t.Error("missing model was not pulled")
+	}
+	if n, _ := a.ContextLength(ctx); n != 40960 {
+		t.Errorf("ContextLength() = %d, want 40960", n)
+	}
+
+	choice := out.Choices[0]
+	if choice.FinishReason != agentTypes.FinishToolCalls || len(choice.Message.ToolCalls) != 1 || choice.Message.Reasoning != "先計算" {
+		t.Fatalf("choice = %+v", choice)
+	}
+	call := choice.Message.ToolCalls[0]
+	if call.ID == "" || call.Function.Arguments != `{"expression":"1+1"}` {
+		t.Errorf("tool call = %+v", call)
+	}
+
+	first := s.chats[0]
+	if first["keep_alive"] != float64(-1) || first["stream"] != false {
+		t.Errorf("keep_alive = %v stream = %v", first["keep_alive"], first["stream"])
+	}
+	if options, _ := first["options"].(map[string]any); options["num_ctx"] != float64(maxNumCtx) {
+		t.Errorf("options = %v, want num_ctx %d", first["options"], maxNumCtx)
+	}
+	if data, _ := json.Marshal(first["messages"]); !strings.Contains(string(data), `"images":["aW1n"]`) {
+		t.Errorf("messages = %s, want base64 image", data)
+	}
+
+	messages = append(messages, choice.Message, agentTypes.Message{
+		Role:       "tool",
+		Content:    "[calculate] 2",
+		ToolCallID: call.ID,
+	})
+	out, err = a.Send(ctx, messages, nil, agentTypes.OptionsData{})
+	if err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if out.Choices[0].FinishReason != agentTypes.FinishLength || out.Usage.InputTokens != 40 {
+		t.Errorf("output = %+v usage = %+v", out.Choices[0], out.Usage)
+	}
+
+	sent, _ := s.chats[1]["messages"].([]any)
+	tool, _ := sent[len(sent)-1].(map[string]any)
+	if tool["role"] != "tool" || tool["tool_name"] != "calculate" {
+		t.Errorf("tool message = %v, want tool_name calculate", tool)
+	}
+}
@@ -0,0 +1,177 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/pardnchiu/agenvoy/internal/agents/exec"
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+	"github.com/pardnchiu/agenvoy/internal/skill"
+	toolTypes "github.com/pardnchiu/agenvoy/internal/tools/types"
+	"github.com/pardnchiu/agenvoy/internal/utils"
+)
+
+// * ollama defaults to a few thousand tokens, too small for tool schemas and history,
+// * models with a longer window get this much unless the server sets its own default
+const maxNumCtx = 32768
+
+func (a *Agent) Execute(ctx context.Context, skill *skill.Skill, userInput string, events chan<- agentTypes.Event, allowAll bool) error {
+	return exec.Execute(ctx, exec.ExecData{
+		Agent:     a,
+		WorkDir:   a.workDir,
+		Skill:     skill,
+		UserInput: userInput,
+		AllowAll:  allowAll,
+	}, events)
+}
+
+func (a *Agent) Send(ctx context.Context, messages []agentTypes.Message, tools []toolTypes.Tool, opts agentTypes.OptionsData) (*agentTypes.Output, error) {
+	if err := a.ensureModel(ctx); err != nil {
+		return nil, fmt.Errorf("a.ensureModel: %w", err)
+	}
+
+	body := map[string]any{
+		"model":    a.model,
+		"messages": convertToMessages(messages),
+		"tools":    tools,
+		"stream":   false,
+	}
+	if a.keepAlive != nil {
+		body["keep_alive"] = a.keepAlive
+	}
+
+	// * format constrains every reply, with tools it would block the calls,
+	// * exec sends the repair turn without tools so that one is constrained
+	if opts.Schema != nil && len(tools) == 0 {
+		body["format"] = opts.Schema.Schema
+	}
+
+	params := opts.Params
+	options := map[string]any{}
+	if params.Temperature != nil {
+		options["temperature"] = *params.Temperature
+	}
+	if params.TopP != nil {
+		options["top_p"] = *params.TopP
+	}
+	if params.MaxTokens > 0 {
+		options["num_predict"] = params.MaxTokens
+	}
+	if params.Seed != nil {
+		options["seed"] = *params.Seed
+	}
+	if len(params.Stop) > 0 {
+		options["stop"] = params.Stop
+	}
+	if a.contextLength > 0 && os.Getenv("OLLAMA_CONTEXT_LENGTH") == "" {
+		options["num_ctx"] = min(a.contextLength, maxNumCtx)
+	}
+	if len(options) > 0 {
+		body["options"] = options
+	}
+	// * gpt-oss only takes a level, other thinking models only take a switch
+	if params.Reasoning() {
+		if strings.HasPrefix(a.model, "gpt-oss") {
+			body["think"] = params.ReasoningLevel()
+		} else {
+			body["think"] = true
+		}
+	}
+
+	result, status, err := utils.POST[Output](ctx, a.httpClient, a.baseURL+"/api/chat", nil, body, "json")
+	if err != nil {
+		return nil, fmt.Errorf("utils.POST: %w", err)
+	}
+	if status != http.StatusOK || result.Error != "" {
+		return nil, fmt.Errorf("ollama chat %s: status %d %s", a.model, status, result.Error)
+	}
+
+	return convertToOutput(&result), nil
+}
+
+// * tool results are matched by tool name, media goes along as base64 images
+func convertToMessages(messages []agentTypes.Message) []Message {
+	toolNames := make(map[string]string)
+	result := make([]Message, 0, len(messages))
+	for _, msg := range agentTypes.SplitToolMedia(messages) {
+		message := Message{
+			Role:    msg.Role,
+			Content: agentTypes.ContentText(msg.Content),
+		}
+
+		if msg.Role == "tool" {
+			message.ToolName = toolNames[msg.ToolCallID]
+		}
+		for _, part := range agentTypes.ContentParts(msg.Content) {
+			switch part.Type {
+			case agentTypes.PartImage:
+				message.Images = append(message.Images, part.Data)
+			case agentTypes.PartFile:
+				message.Content += fmt.Sprintf("\n（附件 %s 無法傳送給本地模型）", part.Name)
+			}
+		}
+		for _, tool := range msg.ToolCalls {
+			toolNames[tool.ID] = tool.Function.Name
+
+			var call ToolCall
+			call.ID = tool.ID
+			call.Function.Name = tool.Function.Name
+			json.Unmarshal([]byte(tool.Function.Arguments), &call.Function.Arguments)
+			message.ToolCalls = append(message.ToolCalls, call)
+		}
+		result = append(result, message)
+	}
+	return result
+}
+
+func convertToOutput(resp *Output) *agentTypes.Output {
+	message := agentTypes.Message{
+		Role:      "assistant",
+		Content:   resp.Message.Content,
+		Reasoning: resp.Message.Thinking,
+	}
+	for i, call := range resp.Message.ToolCalls {
+		args := "{}"
+		if call.Function.Arguments != nil {
+			data, err := json.Marshal(call.Function.Arguments)
+			if err != nil {
+				continue
+			}
+			args = string(data)
+		}
+
+		// * older servers return calls without an id
+		id := call.ID
+		if id == "" {
+			id = fmt.Sprintf("%s_%d", call.Function.Name, i)
+		}
+		toolCall := agentTypes.ToolCall{
+			ID:   id,
+			Type: "function",
+		}
+		toolCall.Function.Name = call.Function.Name
+		toolCall.Function.Arguments = args
+		message.ToolCalls = append(message.ToolCalls, toolCall)
+	}
+
+	finish := agentTypes.NormalizeFinish(resp.DoneReason)
+	if len(message.ToolCalls) > 0 {
+		finish = agentTypes.FinishToolCalls
+	}
+	return &agentTypes.Output{
+		Choices: []agentTypes.OutputChoices{
+			{
+				Message:      message,
+				FinishReason: finish,
+			},
+		},
+		Usage: &agentTypes.UsageData{
+			InputTokens:  resp.PromptEvalCount,
+			OutputTokens: resp.EvalCount,
+		},
+	}
+}
@@ -0,0 +1,36 @@
+package ollama
+
+type Message struct {
+	Role      string     `json:"role"`
+	Content   string     `json:"content"`
+	Images    []string   `json:"images,omitempty"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	ToolName  string     `json:"tool_name,omitempty"`
+	Thinking  string     `json:"thinking,omitempty"`
+}
+
+type ToolCall struct {
+	ID       string `json:"id,omitempty"`
+	Function struct {
+		Name      string         `json:"name"`
+		Arguments map[string]any `json:"arguments"`
+	} `json:"function"`
+}
+
+type Output struct {
+	Message         Message `json:"message"`
+	Done            bool    `json:"done"`
+	DoneReason      string  `json:"done_reason"`
+	PromptEvalCount int     `json:"prompt_eval_count"`
+	EvalCount       int     `json:"eval_count"`
+	Error           string  `json:"error,omitempty"`
+}
+
+type ShowOutput struct {
+	ModelInfo map[string]any `json:"model_info"`
+}
+
+type PullOutput struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
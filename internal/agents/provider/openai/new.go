@@ -4,36 +4,42 @@ import (
 	"fmt"
 	"net/http"
 	"os"
-	"strings"
+
+	providerConfig "github.com/pardnchiu/agenvoy/internal/agents/provider/config"
 )
 
 type Agent struct {
 	httpClient *http.Client
 	model      string
+	baseURL    string
 	apiKey     string
 	workDir    string
 }
 
 const (
-	defaultModel = "gpt-5-mini"
-	prefix       = "openai@"
+	defaultModel   = "gpt-5-mini"
+	providerType   = "openai"
+	defaultBaseURL = "https://api.openai.com/v1"
 )
 
 func New(model ...string) (*Agent, error) {
-	usedModel := defaultModel
-	if len(model) > 0 && strings.HasPrefix(model[0], prefix) {
-		usedModel = strings.TrimPrefix(model[0], prefix)
+	cfg, usedModel := providerConfig.Resolve(providerType, defaultModel, model...)
+	apiKey, err := cfg.RequiredKey("OPENAI_API_KEY")
+	if err != nil {
+		return nil, fmt.Errorf("cfg.RequiredKey: %w", err)
 	}
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("os.Getenv: OPENAI_API_KEY is required")
+	baseURL := cfg.URL(defaultBaseURL)
+	httpClient, err := cfg.Client(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("cfg.Client: %w", err)
 	}
 
 	workDir, _ := os.Getwd()
 
 	return &Agent{
-		httpClient: &http.Client{},
+		httpClient: httpClient,
 		model:      usedModel,
+		baseURL:    baseURL,
 		apiKey:     apiKey,
 		workDir:    workDir,
 	}, nil
@@ -0,0 +1,120 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+)
+
+// stub records every chat completions body and answers ok.
+type stub struct {
+	bodies []map[string]any
+}
+
+func (s *stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/chat/completions" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	var body map[string]any
+	json.NewDecoder(r.Body).Decode(&body)
+	s.bodies = append(s.bodies, body)
+	w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`))
+}
+
+// newStub points the openai instance of a sandboxed home config at a stub.
+func newStub(t *testing.T) *stub {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Chdir(t.TempDir())
+	t.Setenv("OPENAI_API_KEY", "key")
+	s := &stub{}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+
+	dir := filepath.Join(home, ".config", "agenvoy")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	data := `{"providers":{"openai":{"base_url":"` + srv.URL + `"}}}`
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSend_Reasoning(t *testing.T) {
+	s := newStub(t)
+	messages := []agentTypes.Message{{Role: "user", Content: "hi"}}
+	opts := agentTypes.OptionsData{Params: agentTypes.ParamsData{ReasoningBudget: 30000}}
+
+	for _, tt := range []struct {
+		model string
+		want  any
+	}{
+		{"o3-mini", "high"},
+		{"gpt-5-mini", "high"},
+		{"gpt-4.1", nil},
+	} {
+		a, err := New("openai@" + tt.model)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := a.Send(context.Background(), messages, nil, opts); err != nil {
+			t.Fatalf("Send() error: %v", err)
+		}
+		if got := s.bodies[len(s.bodies)-1]["reasoning_effort"]; got != tt.want {
+			t.Errorf("%s reasoning_effort = %v, want %v", tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestSend_Schema(t *testing.T) {
+	s := newStub(t)
+	a, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := []agentTypes.Message{{Role: "user", Content: "hi"}}
+	closed := map[string]any{
+		"type":                 "object",
+		"properties":           map[string]any{"answer": map[string]any{"type": "integer"}},
+		"required":             []any{"answer"},
+		"additionalProperties": false,
+	}
+	open := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"answer": map[string]any{"type": "integer"}},
+	}
+
+	for _, tt := range []struct {
+		name   string
+		schema *agentTypes.SchemaData
+		want   string
+	}{
+		{"strict", &agentTypes.SchemaData{Name: "answer", Schema: closed},
+			`{"type":"json_schema","json_schema":{"name":"answer","schema":{"type":"object","properties":{"answer":{"type":"integer"}},"required":["answer"],"additionalProperties":false},"strict":true}}`},
+		{"guided", &agentTypes.SchemaData{Name: "answer", Schema: open},
+			`{"type":"json_schema","json_schema":{"name":"answer","schema":{"type":"object","properties":{"answer":{"type":"integer"}}},"strict":false}}`},
+		{"none", nil, `null`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := a.Send(context.Background(), messages, nil, agentTypes.OptionsData{Schema: tt.schema}); err != nil {
+				t.Fatalf("Send() error: %v", err)
+			}
+			var want any
+			json.Unmarshal([]byte(tt.want), &want)
+			if got := s.bodies[len(s.bodies)-1]["response_format"]; !reflect.DeepEqual(got, want) {
+				data, _ := json.Marshal(got)
+				t.Errorf("response_format = %s, want %s", data, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package vertex
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+
+	providerConfig "github.com/pardnchiu/agenvoy/internal/agents/provider/config"
+)
+
+type Agent struct {
+	httpClient *http.Client
+	model      string
+	project    string
+	location   string
+	endpoint   string
+	workDir    string
+
+	// * a fixed token (e.g. gcloud auth print-access-token) or a service account to mint them
+	accessToken string
+	account     *serviceAccount
+
+	mu    sync.Mutex
+	token *tokenData
+}
+
+const (
+	defaultModel    = "gemini-2.5-pro"
+	defaultLocation = "us-central1"
+	providerType    = "vertex"
+)
+
+func New(model ...string) (*Agent, error) {
+	cfg, usedModel := providerConfig.Resolve(providerType, defaultModel, model...)
+	// * api_key stands for a fixed access token, a service account still comes from the env
+	accessToken, err := cfg.Key("VERTEX_ACCESS_TOKEN")
+	if err != nil {
+		return nil, fmt.Errorf("cfg.Key: %w", err)
+	}
+	a := &Agent{
+		model:       usedModel,
+		project:     firstEnv("VERTEX_PROJECT", "GOOGLE_CLOUD_PROJECT"),
+		location:    firstEnv("VERTEX_LOCATION", "GOOGLE_CLOUD_LOCATION"),
+		accessToken: accessToken,
+	}
+	if a.location == "" {
+		a.location = defaultLocation
+	}
+
+	if a.accessToken == "" {
+		path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+		if path == "" {
+			return nil, fmt.Errorf("os.Getenv: VERTEX_ACCESS_TOKEN or GOOGLE_APPLICATION_CREDENTIALS is required")
+		}
+		account, err := readServiceAccount(path)
+		if err != nil {
+			return nil, fmt.Errorf("readServiceAccount: %w", err)
+		}
+		a.account = account
+		if a.project == "" {
+			a.project = account.ProjectID
+		}
+	}
+	if a.project == "" {
+		return nil, fmt.Errorf("os.Getenv: VERTEX_PROJECT is required")
+	}
+
+	// * the global location has no regional prefix on its host
+	a.endpoint = cfg.URL(os.Getenv("VERTEX_ENDPOINT"))
+	switch {
+	case a.endpoint != "":
+	case a.location == "global":
+		a.endpoint = "https://aiplatform.googleapis.com"
+	default:
+		a.endpoint = fmt.Sprintf("https://%s-aiplatform.googleapis.com", a.location)
+	}
+	// * the oauth token request goes to google, the configured headers stay on the endpoint
+	a.httpClient, err = cfg.Client(a.endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("cfg.Client: %w", err)
+	}
+
+	workDir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("os.Getwd: %w", err)
+	}
+	a.workDir = workDir
+	return a, nil
+}
+
+func firstEnv(keys ...string) string {
+	for _, key := range keys {
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
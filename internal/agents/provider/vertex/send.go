@@ -0,0 +1,53 @@
+package vertex
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/pardnchiu/agenvoy/internal/agents/exec"
+	"github.com/pardnchiu/agenvoy/internal/agents/provider/gemini"
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+	"github.com/pardnchiu/agenvoy/internal/skill"
+	toolTypes "github.com/pardnchiu/agenvoy/internal/tools/types"
+	"github.com/pardnchiu/agenvoy/internal/utils"
+)
+
+func (a *Agent) Execute(ctx context.Context, skill *skill.Skill, userInput string, events chan<- agentTypes.Event, allowAll bool) error {
+	return exec.Execute(ctx, exec.ExecData{
+		Agent:     a,
+		WorkDir:   a.workDir,
+		Skill:     skill,
+		UserInput: userInput,
+		AllowAll:  allowAll,
+	}, events)
+}
+
+// * vertex serves google models with the gemini api body, only the url and auth differ
+func (a *Agent) Send(ctx context.Context, messages []agentTypes.Message, tools []toolTypes.Tool, opts agentTypes.OptionsData) (*agentTypes.Output, error) {
+	if err := opts.Params.Check(providerType, agentTypes.LimitsData{MaxStop: 5}); err != nil {
+		return nil, fmt.Errorf("opts.Params.Check: %w", err)
+	}
+
+	token, err := a.getAccessToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("a.getAccessToken: %w", err)
+	}
+
+	apiURL := fmt.Sprintf("%s/v1/projects/%s/locations/%s/publishers/google/models/%s:generateContent",
+		a.endpoint, url.PathEscape(a.project), url.PathEscape(a.location), url.PathEscape(a.model))
+
+	result, code, err := utils.POST[gemini.Output](ctx, a.httpClient, apiURL, map[string]string{
+		"Authorization": "Bearer " + token,
+		"Content-Type":  "application/json",
+	}, gemini.RequestBody(messages, tools, opts), "json")
+	if err != nil {
+		return nil, fmt.Errorf("utils.POST: %w", err)
+	}
+	if code != http.StatusOK {
+		return nil, fmt.Errorf("utils.POST: status %d", code)
+	}
+
+	return gemini.ConvertToOutput(&result), nil
+}
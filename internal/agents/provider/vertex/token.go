@@ -0,0 +1,148 @@
+package vertex
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/pardnchiu/agenvoy/internal/utils"
+)
+
+const (
+	tokenScope        = "https://www.googleapis.com/auth/cloud-platform"
+	defaultTokenURI   = "https://oauth2.googleapis.com/token"
+	jwtBearerGrant    = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+	assertionLifetime = time.Hour
+)
+
+type serviceAccount struct {
+	Type         string `json:"type"`
+	ProjectID    string `json:"project_id"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	ClientEmail  string `json:"client_email"`
+	TokenURI     string `json:"token_uri"`
+
+	key *rsa.PrivateKey
+}
+
+type tokenData struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+	ExpiresAt   int64  `json:"-"`
+}
+
+func readServiceAccount(path string) (*serviceAccount, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile: %w", err)
+	}
+	var account serviceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+	if account.Type != "service_account" || account.ClientEmail == "" {
+		return nil, fmt.Errorf("%s is not a service account key", path)
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = defaultTokenURI
+	}
+
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("private_key is not pem encoded")
+	}
+	// * keys from the console are pkcs8, older tooling wrote pkcs1
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return nil, fmt.Errorf("x509.ParsePKCS1PrivateKey: %w", err)
+		}
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private_key is not an rsa key")
+	}
+	account.key = key
+	return &account, nil
+}
+
+// * a fixed token is used as is, minted ones are reused until a minute before expiry
+func (a *Agent) getAccessToken(ctx context.Context) (string, error) {
+	if a.accessToken != "" {
+		return a.accessToken, nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token == nil || time.Now().Unix() >= a.token.ExpiresAt-60 {
+		token, err := a.getToken(ctx)
+		if err != nil {
+			return "", err
+		}
+		a.token = token
+	}
+	return a.token.AccessToken, nil
+}
+
+func (a *Agent) getToken(ctx context.Context) (*tokenData, error) {
+	assertion, err := a.account.sign(time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("account.sign: %w", err)
+	}
+
+	token, code, err := utils.POST[tokenData](ctx, a.httpClient, a.account.TokenURI, nil, map[string]any{
+		"grant_type": jwtBearerGrant,
+		"assertion":  assertion,
+	}, "form")
+	if err != nil {
+		return nil, fmt.Errorf("utils.POST: %w", err)
+	}
+	if code != http.StatusOK || token.AccessToken == "" {
+		return nil, fmt.Errorf("utils.POST: token request failed with status %d", code)
+	}
+
+	token.ExpiresAt = time.Now().Unix() + token.ExpiresIn
+	return &token, nil
+}
+
+// sign returns an RS256 JWT asserting the account for the cloud-platform scope.
+func (s *serviceAccount) sign(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+		"kid": s.PrivateKeyID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("json.Marshal: %w", err)
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iss":   s.ClientEmail,
+		"scope": tokenScope,
+		"aud":   s.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(assertionLifetime).Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	encoding := base64.RawURLEncoding
+	unsigned := encoding.EncodeToString(header) + "." + encoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", fmt.Errorf("rsa.SignPKCS1v15: %w", err)
+	}
+	return unsigned + "." + encoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,116 @@
+package vertex
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+)
+
+func TestSend_ServiceAccount(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tokens int
+	var path, auth string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		// the assertion must verify against the account public key
+		parts := strings.Split(r.Form.Get("assertion"), ".")
+		if r.Form.Get("grant_type") != jwtBearerGrant || len(parts) != 3 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hash[:], signature); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		claims, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		if !strings.Contains(string(claims), `"iss":"bot@demo.iam.gserviceaccount.com"`) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		tokens++
+		w.Write([]byte(`{"access_token":"sa-token","expires_in":3600,"token_type":"Bearer"}`))
+	})
+	mux.HandleFunc("/v1/", func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		if _, ok := body["contents"]; !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"ok"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":8,"candidatesTokenCount":1}}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	credentials := filepath.Join(t.TempDir(), "sa.json")
+	data, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "demo",
+		"private_key_id": "kid",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"client_email":   "bot@demo.iam.gserviceaccount.com",
+		"token_uri":      srv.URL + "/token",
+	})
+	if err := os.WriteFile(credentials, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", credentials)
+	t.Setenv("VERTEX_ENDPOINT", srv.URL)
+	t.Setenv("VERTEX_ACCESS_TOKEN", "")
+	t.Setenv("VERTEX_PROJECT", "")
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "")
+	t.Setenv("VERTEX_LOCATION", "asia-east1")
+
+	a, err := New("vertex@gemini-2.5-flash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := []agentTypes.Message{{Role: "user", Content: "hi"}}
+	for range 2 {
+		out, err := a.Send(context.Background(), messages, nil, agentTypes.OptionsData{})
+		if err != nil {
+			t.Fatalf("Send() error: %v", err)
+		}
+		if out.Choices[0].Message.Content != "ok" || out.Choices[0].FinishReason != agentTypes.FinishStop {
+			t.Errorf("output = %+v", out.Choices[0])
+		}
+	}
+
+	if tokens != 1 {
+		t.Errorf("token requests = %d, want 1 reused token", tokens)
+	}
+	if auth != "Bearer sa-token" {
+		t.Errorf("auth = %q", auth)
+	}
+	if path != "/v1/projects/demo/locations/asia-east1/publishers/google/models/gemini-2.5-flash:generateContent" {
+		t.Errorf("path = %s", path)
+	}
+}
@@ -8,10 +8,16 @@ import (
 )
 
 type Agent interface {
-	Send(ctx context.Context, messages []Message, toolDefs []toolTypes.Tool) (*Output, error)
+	Send(ctx context.Context, messages []Message, toolDefs []toolTypes.Tool, opts OptionsData) (*Output, error)
 	Execute(ctx context.Context, skill *skill.Skill, userInput string, events chan<- Event, allowAll bool) error
 }
 
+// OptionsData is what a single Send asks of the provider besides the conversation.
+type OptionsData struct {
+	Params ParamsData  // generation params, zero fields keep the provider default
+	Schema *SchemaData // the answer must be JSON matching it, nil allows free text
+}
+
 type AgentRegistry struct {
 	Registry map[string]Agent
 	Entries  []AgentEntry
@@ -19,8 +25,22 @@ type AgentRegistry struct {
 }
 
 type AgentEntry struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Params      ParamsData `json:"params,omitzero"`
+}
+
+// Params returns the params configured for name, the fallback uses the first entry.
+func (r AgentRegistry) Params(name string) ParamsData {
+	for _, e := range r.Entries {
+		if e.Name == name {
+			return e.Params
+		}
+	}
+	if name == "" && len(r.Entries) > 0 {
+		return r.Entries[0].Params
+	}
+	return ParamsData{}
 }
 
 type AgentSession struct {
@@ -0,0 +1,76 @@
+package agentTypes
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+const (
+	PartText  = "text"
+	PartImage = "image"
+	PartFile  = "file" // documents such as pdf
+)
+
+// * Message.Content is a string or []ContentPart, media is kept base64 encoded
+type ContentPart struct {
+	Type      string `json:"type"`
+	Text      string `json:"text,omitempty"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Ref       string `json:"ref,omitempty"` // blob hash in the session log, Data is left out there
+}
+
+// DataURL returns the part as a data: url, the form openai style apis take media in.
+func (p ContentPart) DataURL() string {
+	return "data:" + p.MediaType + ";base64," + p.Data
+}
+
+// ContentParts returns content as parts, also after a json round trip turned them into maps.
+func ContentParts(content any) []ContentPart {
+	switch value := content.(type) {
+	case nil:
+		return nil
+	case string:
+		return []ContentPart{{Type: PartText, Text: value}}
+	case []ContentPart:
+		return value
+	default:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil
+		}
+		var parts []ContentPart
+		if err := json.Unmarshal(data, &parts); err != nil {
+			return nil
+		}
+		return parts
+	}
+}
+
+// ContentText returns the text of content, media parts are left out.
+func ContentText(content any) string {
+	if text, ok := content.(string); ok {
+		return text
+	}
+	var texts []string
+	for _, part := range ContentParts(content) {
+		if part.Type == PartText {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// HasMedia reports whether content carries anything besides text.
+func HasMedia(content any) bool {
+	if _, ok := content.(string); ok {
+		return false
+	}
+	for _, part := range ContentParts(content) {
+		if part.Type != PartText {
+			return true
+		}
+	}
+	return false
+}
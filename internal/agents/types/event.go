@@ -17,15 +17,19 @@ const (
 	EventToolConfirm
 	EventError
 	EventDone
+	EventReasoning
 )
 
 type Event struct {
-	Type     EventType `json:"type"`
-	Text     string    `json:"text,omitempty"`
-	ToolName string    `json:"tool_name,omitempty"`
-	ToolArgs string    `json:"tool_args,omitempty"`
-	ToolID   string    `json:"tool_id,omitempty"`
-	Result   string    `json:"result,omitempty"`
-	Err      error     `json:"-"`
-	ReplyCh  chan bool `json:"-"`
+	Type     EventType  `json:"type"`
+	Text     string     `json:"text,omitempty"`
+	ToolName string     `json:"tool_name,omitempty"`
+	ToolArgs string     `json:"tool_args,omitempty"`
+	ToolID   string     `json:"tool_id,omitempty"`
+	Result   string     `json:"result,omitempty"`
+	Source   string     `json:"source,omitempty"`
+	Score    float64    `json:"score,omitempty"`
+	Usage    *UsageData `json:"usage,omitempty"` // EventDone: tokens of the whole turn
+	Err      error      `json:"-"`
+	ReplyCh  chan bool  `json:"-"`
 }
@@ -0,0 +1,44 @@
+package agentTypes
+
+import "strings"
+
+// * every provider maps its own stop reason onto these
+const (
+	FinishStop      = "stop"
+	FinishLength    = "length"
+	FinishToolCalls = "tool_calls"
+	FinishFilter    = "content_filter"
+)
+
+var finishReasons = map[string]string{
+	// openai compatible
+	"stop":           FinishStop,
+	"length":         FinishLength,
+	"tool_calls":     FinishToolCalls,
+	"function_call":  FinishToolCalls,
+	"content_filter": FinishFilter,
+	// claude
+	"end_turn":      FinishStop,
+	"stop_sequence": FinishStop,
+	"max_tokens":    FinishLength,
+	"tool_use":      FinishToolCalls,
+	"refusal":       FinishFilter,
+	// gemini
+	"safety":             FinishFilter,
+	"recitation":         FinishFilter,
+	"blocklist":          FinishFilter,
+	"prohibited_content": FinishFilter,
+	"spii":               FinishFilter,
+	// bedrock converse
+	"guardrail_intervened": FinishFilter,
+	"content_filtered":     FinishFilter,
+}
+
+// NormalizeFinish maps a provider stop reason onto the Finish constants, unknown reasons are kept lowercased.
+func NormalizeFinish(reason string) string {
+	reason = strings.ToLower(strings.TrimSpace(reason))
+	if normalized, ok := finishReasons[reason]; ok {
+		return normalized
+	}
+	return reason
+}
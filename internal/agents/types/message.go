@@ -7,6 +7,88 @@ type Message struct {
 	Content    any        `json:"content,omitempty"`
 	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
 	ToolCallID string     `json:"tool_call_id,omitempty"`
+	// * readable reasoning, openai compatible servers return it as reasoning_content
+	Reasoning string `json:"reasoning_content,omitempty"`
+	// * opaque blocks a provider needs back on the next request of the same turn
+	Thinking []ThinkingBlock `json:"thinking,omitempty"`
+}
+
+type ThinkingBlock struct {
+	Type      string `json:"type"`
+	Text      string `json:"text,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"`
+}
+
+// ChatMessages prepares messages for an openai compatible api: reasoning is dropped as it is
+// rejected as input, and content parts are turned into its image_url and file parts.
+func ChatMessages(messages []Message) []Message {
+	messages = SplitToolMedia(messages)
+	result := make([]Message, len(messages))
+	for i, m := range messages {
+		m.Reasoning = ""
+		m.Thinking = nil
+		if _, ok := m.Content.(string); !ok && m.Content != nil {
+			m.Content = chatParts(ContentParts(m.Content))
+		}
+		result[i] = m
+	}
+	return result
+}
+
+func chatParts(parts []ContentPart) []map[string]any {
+	result := make([]map[string]any, 0, len(parts))
+	for _, part := range parts {
+		switch part.Type {
+		case PartText:
+			result = append(result, map[string]any{"type": "text", "text": part.Text})
+		case PartImage:
+			result = append(result, map[string]any{
+				"type":      "image_url",
+				"image_url": map[string]any{"url": part.DataURL()},
+			})
+		case PartFile:
+			result = append(result, map[string]any{
+				"type": "file",
+				"file": map[string]any{"filename": part.Name, "file_data": part.DataURL()},
+			})
+		}
+	}
+	return result
+}
+
+// SplitToolMedia keeps tool results text only and moves their media into a user message
+// right after the tool results, for apis that take media from users only.
+func SplitToolMedia(messages []Message) []Message {
+	result := make([]Message, 0, len(messages))
+	var media []ContentPart
+	flush := func() {
+		if len(media) == 0 {
+			return
+		}
+		parts := append([]ContentPart{{Type: PartText, Text: "以下是工具回傳的檔案內容："}}, media...)
+		result = append(result, Message{Role: "user", Content: parts})
+		media = nil
+	}
+
+	for _, m := range messages {
+		if m.Role != "tool" {
+			flush()
+			result = append(result, m)
+			continue
+		}
+		if HasMedia(m.Content) {
+			for _, part := range ContentParts(m.Content) {
+				if part.Type != PartText {
+					media = append(media, part)
+				}
+			}
+			m.Content = ContentText(m.Content)
+		}
+		result = append(result, m)
+	}
+	flush()
+	return result
 }
 
 type ToolCall struct {
@@ -20,6 +102,7 @@ type ToolCall struct {
 
 type Output struct {
 	Choices []OutputChoices `json:"choices"`
+	Usage   *UsageData      `json:"usage,omitempty"`
 	Error   *struct {
 		Message string      `json:"message"`
 		Type    string      `json:"type"`
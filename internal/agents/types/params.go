@@ -0,0 +1,152 @@
+package agentTypes
+
+import (
+	"fmt"
+	"strings"
+)
+
+// * unset fields keep the provider default, pointers tell 0 apart from unset
+type ParamsData struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	Seed        *int     `json:"seed,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	// * provider neutral reasoning, budget wins where the api takes tokens
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
+	ReasoningBudget int    `json:"reasoning_budget,omitempty"`
+}
+
+var reasoningBudgets = map[string]int{
+	"low":    2048,
+	"medium": 8192,
+	"high":   24576,
+}
+
+// Merge returns p with every field set in override replacing its own.
+func (p ParamsData) Merge(override ParamsData) ParamsData {
+	if override.Temperature != nil {
+		p.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		p.TopP = override.TopP
+	}
+	if override.MaxTokens > 0 {
+		p.MaxTokens = override.MaxTokens
+	}
+	if override.Seed != nil {
+		p.Seed = override.Seed
+	}
+	if len(override.Stop) > 0 {
+		p.Stop = override.Stop
+	}
+	if override.ReasoningEffort != "" {
+		p.ReasoningEffort = override.ReasoningEffort
+	}
+	if override.ReasoningBudget > 0 {
+		p.ReasoningBudget = override.ReasoningBudget
+	}
+	return p
+}
+
+func (p ParamsData) Reasoning() bool {
+	return p.ReasoningEffort != "" || p.ReasoningBudget > 0
+}
+
+// ReasoningTokens returns the thinking budget, derived from the effort when no budget is set.
+func (p ParamsData) ReasoningTokens() int {
+	if p.ReasoningBudget > 0 {
+		return p.ReasoningBudget
+	}
+	if budget, ok := reasoningBudgets[p.ReasoningEffort]; ok {
+		return budget
+	}
+	return reasoningBudgets["medium"]
+}
+
+// ReasoningLevel returns the effort, derived from the budget when no effort is set.
+func (p ParamsData) ReasoningLevel() string {
+	switch {
+	case p.ReasoningEffort != "":
+		return p.ReasoningEffort
+	case p.ReasoningBudget >= reasoningBudgets["high"]:
+		return "high"
+	case p.ReasoningBudget > reasoningBudgets["low"]:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+func (p ParamsData) Validate() error {
+	if p.Temperature != nil && (*p.Temperature < 0 || *p.Temperature > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2, got %g", *p.Temperature)
+	}
+	if p.TopP != nil && (*p.TopP <= 0 || *p.TopP > 1) {
+		return fmt.Errorf("top_p must be in (0, 1], got %g", *p.TopP)
+	}
+	if p.MaxTokens < 0 {
+		return fmt.Errorf("max_tokens must be positive, got %d", p.MaxTokens)
+	}
+	if _, ok := reasoningBudgets[p.ReasoningEffort]; p.ReasoningEffort != "" && !ok {
+		return fmt.Errorf("reasoning_effort must be low, medium or high, got %q", p.ReasoningEffort)
+	}
+	if p.ReasoningBudget < 0 {
+		return fmt.Errorf("reasoning_budget must be positive, got %d", p.ReasoningBudget)
+	}
+	for _, s := range p.Stop {
+		if s == "" {
+			return fmt.Errorf("stop sequences must not be empty")
+		}
+	}
+	return nil
+}
+
+// LimitsData is what one provider api accepts beyond Validate, zero fields are not checked.
+type LimitsData struct {
+	MaxStop        int
+	MaxTemperature float64
+}
+
+// Check rejects params the provider api would refuse or silently cut.
+func (p ParamsData) Check(provider string, limits LimitsData) error {
+	if limits.MaxStop > 0 && len(p.Stop) > limits.MaxStop {
+		return fmt.Errorf("%s allows at most %d stop sequences, got %d", provider, limits.MaxStop, len(p.Stop))
+	}
+	if limits.MaxTemperature > 0 && p.Temperature != nil && *p.Temperature > limits.MaxTemperature {
+		return fmt.Errorf("%s temperature must be between 0 and %g, got %g", provider, limits.MaxTemperature, *p.Temperature)
+	}
+	return nil
+}
+
+// ReasoningModel reports whether an OpenAI model takes reasoning_effort, older chat models reject it.
+func ReasoningModel(model string) bool {
+	for _, prefix := range []string{"o1", "o3", "o4", "gpt-5"} {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetChatParams writes params into an OpenAI style chat completions body, reasoning_effort only where the api takes it.
+func (p ParamsData) SetChatParams(body map[string]any, maxTokensKey string, reasoning bool) {
+	if p.Temperature != nil {
+		body["temperature"] = *p.Temperature
+	}
+	if p.TopP != nil {
+		body["top_p"] = *p.TopP
+	}
+	if p.MaxTokens > 0 {
+		body[maxTokensKey] = p.MaxTokens
+	}
+	if p.Seed != nil {
+		body["seed"] = *p.Seed
+	}
+	if len(p.Stop) > 0 {
+		body["stop"] = p.Stop
+	}
+	if reasoning && p.Reasoning() {
+		body["reasoning_effort"] = p.ReasoningLevel()
+	}
+}
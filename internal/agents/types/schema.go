@@ -0,0 +1,144 @@
+package agentTypes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// SchemaData asks Send for an answer that is a single JSON value matching Schema.
+type SchemaData struct {
+	Name   string         `json:"name"`
+	Schema map[string]any `json:"schema"`
+}
+
+// * openai and claude both limit names to this
+var schemaNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// NewSchema parses a JSON schema document, the name is used where providers need one.
+func NewSchema(data []byte, name string) (*SchemaData, error) {
+	if name == "" {
+		name = "response"
+	}
+	if !schemaNameRegex.MatchString(name) {
+		return nil, fmt.Errorf("schema name must match %s, got %q", schemaNameRegex, name)
+	}
+
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+	delete(schema, "$schema")
+	return &SchemaData{Name: name, Schema: schema}, nil
+}
+
+// SetChatFormat writes response_format into an OpenAI style chat completions body.
+func (s *SchemaData) SetChatFormat(body map[string]any) {
+	if s == nil {
+		return
+	}
+	// * strict mode rejects schemas with optional or open properties, those are only guided
+	body["response_format"] = map[string]any{
+		"type": "json_schema",
+		"json_schema": map[string]any{
+			"name":   s.Name,
+			"schema": s.Schema,
+			"strict": isStrict(s.Schema),
+		},
+	}
+}
+
+// ToolSchema returns the schema as a tool input, other roots than object are wrapped in value.
+func (s *SchemaData) ToolSchema() map[string]any {
+	if s.Schema["type"] == "object" {
+		return s.Schema
+	}
+	return map[string]any{
+		"type":                 "object",
+		"properties":           map[string]any{"value": s.Schema},
+		"required":             []string{"value"},
+		"additionalProperties": false,
+	}
+}
+
+// ToolAnswer turns the input of a forced tool call back into the answer.
+func (s *SchemaData) ToolAnswer(input json.RawMessage) string {
+	if s.Schema["type"] != "object" {
+		var wrapped struct {
+			Value json.RawMessage `json:"value"`
+		}
+		if json.Unmarshal(input, &wrapped) == nil && wrapped.Value != nil {
+			input = wrapped.Value
+		}
+	}
+	return string(input)
+}
+
+// Prompt describes the schema for models that only see it as text.
+func (s *SchemaData) Prompt() string {
+	data, _ := json.Marshal(s.Schema)
+	return fmt.Sprintf("最終回覆必須是符合以下 JSON Schema 的 JSON，不要使用 markdown code block，也不要加上任何說明文字：\n%s", data)
+}
+
+// Validate checks an answer against the schema and returns it as compact JSON.
+func (s *SchemaData) Validate(text string) (string, error) {
+	text = strings.TrimSpace(text)
+	// * models without native support like to fence the json
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```json")
+		text = strings.TrimPrefix(text, "```")
+		text = strings.TrimSuffix(strings.TrimSpace(text), "```")
+		text = strings.TrimSpace(text)
+	}
+
+	var value any
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return "", fmt.Errorf("invalid json: %w", err)
+	}
+	if decoder.More() {
+		return "", fmt.Errorf("invalid json: more than one value")
+	}
+	if err := validateValue(s.Schema, value, "$"); err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(text)); err != nil {
+		return "", fmt.Errorf("json.Compact: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// * every object closed and every property required, what openai strict mode accepts
+func isStrict(schema map[string]any) bool {
+	if props, ok := schema["properties"].(map[string]any); ok || schema["type"] == "object" {
+		if schema["additionalProperties"] != false {
+			return false
+		}
+		required := make(map[string]bool)
+		for _, name := range toStrings(schema["required"]) {
+			required[name] = true
+		}
+		for name, prop := range props {
+			sub, ok := prop.(map[string]any)
+			if !ok || !required[name] || !isStrict(sub) {
+				return false
+			}
+		}
+	}
+	if items, ok := schema["items"].(map[string]any); ok && !isStrict(items) {
+		return false
+	}
+	for _, key := range []string{"anyOf", "oneOf"} {
+		for _, option := range toMaps(schema[key]) {
+			if !isStrict(option) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,251 @@
+package agentTypes
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"unicode/utf8"
+)
+
+// * the subset of json schema that structured output apis accept, unknown keywords are ignored
+func validateValue(schema map[string]any, value any, path string) error {
+	if types := toStrings(schema["type"]); len(types) > 0 {
+		if !slices.ContainsFunc(types, func(t string) bool { return isType(value, t) }) {
+			return fmt.Errorf("%s: must be %v", path, schema["type"])
+		}
+	}
+	if enum := toList(schema["enum"]); enum != nil {
+		if !slices.ContainsFunc(enum, func(e any) bool { return equalJSON(e, value) }) {
+			return fmt.Errorf("%s: must be one of %v", path, enum)
+		}
+	}
+	if constant, ok := schema["const"]; ok && !equalJSON(constant, value) {
+		return fmt.Errorf("%s: must be %v", path, constant)
+	}
+
+	for _, sub := range toMaps(schema["allOf"]) {
+		if err := validateValue(sub, value, path); err != nil {
+			return err
+		}
+	}
+	if options := toMaps(schema["anyOf"]); len(options) > 0 {
+		if !slices.ContainsFunc(options, func(sub map[string]any) bool { return validateValue(sub, value, path) == nil }) {
+			return fmt.Errorf("%s: does not match any allowed schema", path)
+		}
+	}
+	if options := toMaps(schema["oneOf"]); len(options) > 0 {
+		matched := 0
+		for _, sub := range options {
+			if validateValue(sub, value, path) == nil {
+				matched++
+			}
+		}
+		if matched != 1 {
+			return fmt.Errorf("%s: must match exactly one schema, matched %d", path, matched)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		return validateObject(schema, v, path)
+
+	case []any:
+		if n, ok := toInt(schema["minItems"]); ok && len(v) < n {
+			return fmt.Errorf("%s: must have at least %d items", path, n)
+		}
+		if n, ok := toInt(schema["maxItems"]); ok && len(v) > n {
+			return fmt.Errorf("%s: must have at most %d items", path, n)
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				if err := validateValue(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+
+	case string:
+		length := utf8.RuneCountInString(v)
+		if n, ok := toInt(schema["minLength"]); ok && length < n {
+			return fmt.Errorf("%s: must be at least %d characters", path, n)
+		}
+		if n, ok := toInt(schema["maxLength"]); ok && length > n {
+			return fmt.Errorf("%s: must be at most %d characters", path, n)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			re, err := regexp.Compile(pattern)
+			if err == nil && !re.MatchString(v) {
+				return fmt.Errorf("%s: must match %s", path, pattern)
+			}
+		}
+
+	case json.Number:
+		n, _ := v.Float64()
+		if min, ok := toFloat(schema["minimum"]); ok && n < min {
+			return fmt.Errorf("%s: must be >= %g", path, min)
+		}
+		if max, ok := toFloat(schema["maximum"]); ok && n > max {
+			return fmt.Errorf("%s: must be <= %g", path, max)
+		}
+		if min, ok := toFloat(schema["exclusiveMinimum"]); ok && n <= min {
+			return fmt.Errorf("%s: must be > %g", path, min)
+		}
+		if max, ok := toFloat(schema["exclusiveMaximum"]); ok && n >= max {
+			return fmt.Errorf("%s: must be < %g", path, max)
+		}
+	}
+	return nil
+}
+
+func validateObject(schema map[string]any, object map[string]any, path string) error {
+	for _, name := range toStrings(schema["required"]) {
+		if _, ok := object[name]; !ok {
+			return fmt.Errorf("%s: missing required property %q", path, name)
+		}
+	}
+
+	props, _ := schema["properties"].(map[string]any)
+	// * sorted so the first error is the same on every run
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		subPath := path + "." + name
+		if prop, ok := props[name].(map[string]any); ok {
+			if err := validateValue(prop, object[name], subPath); err != nil {
+				return err
+			}
+			continue
+		}
+		switch extra := schema["additionalProperties"].(type) {
+		case bool:
+			if !extra {
+				return fmt.Errorf("%s: unknown property", subPath)
+			}
+		case map[string]any:
+			if err := validateValue(extra, object[name], subPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func isType(value any, t string) bool {
+	switch t {
+	case "null":
+		return value == nil
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		f, err := n.Float64()
+		return err == nil && f == math.Trunc(f)
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	}
+	return false
+}
+
+// * schema values decode as float64, answers as json.Number
+func equalJSON(a, b any) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+	left, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	right, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return string(left) == string(right)
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func toInt(v any) (int, bool) {
+	f, ok := toFloat(v)
+	return int(f), ok
+}
+
+func toList(v any) []any {
+	switch s := v.(type) {
+	case []any:
+		return s
+	case []string:
+		result := make([]any, len(s))
+		for i, item := range s {
+			result[i] = item
+		}
+		return result
+	}
+	return nil
+}
+
+// * type and required may be a string, a []any from json or a []string built in code
+func toStrings(v any) []string {
+	switch s := v.(type) {
+	case string:
+		return []string{s}
+	case []string:
+		return s
+	case []any:
+		result := make([]string, 0, len(s))
+		for _, item := range s {
+			if str, ok := item.(string); ok {
+				result = append(result, str)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+func toMaps(v any) []map[string]any {
+	switch s := v.(type) {
+	case []map[string]any:
+		return s
+	case []any:
+		result := make([]map[string]any, 0, len(s))
+		for _, item := range s {
+			if m, ok := item.(map[string]any); ok {
+				result = append(result, m)
+			}
+		}
+		return result
+	}
+	return nil
+}
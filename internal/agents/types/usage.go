@@ -0,0 +1,34 @@
+package agentTypes
+
+// * InputTokens counts every prompt token, cached ones included, as openai reports it
+type UsageData struct {
+	InputTokens      int `json:"prompt_tokens"`
+	OutputTokens     int `json:"completion_tokens"`
+	CacheReadTokens  int `json:"cache_read_tokens,omitempty"`
+	CacheWriteTokens int `json:"cache_write_tokens,omitempty"`
+	// * openai nests cached tokens here, Normalize moves them into CacheReadTokens
+	PromptDetails *struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details,omitempty"`
+}
+
+func (u *UsageData) Add(other *UsageData) {
+	if other == nil {
+		return
+	}
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.CacheReadTokens += other.CacheReadTokens
+	u.CacheWriteTokens += other.CacheWriteTokens
+}
+
+// Normalize maps finish reasons and usage of an openai compatible response onto the shared form.
+func (o *Output) Normalize() {
+	for i := range o.Choices {
+		o.Choices[i].FinishReason = NormalizeFinish(o.Choices[i].FinishReason)
+	}
+	if o.Usage != nil && o.Usage.PromptDetails != nil {
+		o.Usage.CacheReadTokens = o.Usage.PromptDetails.CachedTokens
+		o.Usage.PromptDetails = nil
+	}
+}
@@ -0,0 +1,128 @@
+package credential
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func sandbox(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Chdir(t.TempDir())
+	t.Setenv("AGENVOY_PASSPHRASE", "")
+	cache.path = ""
+	return filepath.Join(home, ".config", "agenvoy")
+}
+
+func TestStore_KeyFile(t *testing.T) {
+	dir := sandbox(t)
+
+	if _, err := Get("openai"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() on an empty store = %v, want ErrNotFound", err)
+	}
+	if err := Set("openai", "sk-secret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set("gpu", "local"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "credentials.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "sk-secret") {
+		t.Error("store holds the secret in plain text")
+	}
+	for _, name := range []string{"credentials.json", "credentials.key"} {
+		if info, err := os.Stat(filepath.Join(dir, name)); err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("%s mode = %v, %v", name, info.Mode().Perm(), err)
+		}
+	}
+
+	// * a fresh process has to decrypt from disk
+	cache.path = ""
+	if value, err := Get("openai"); err != nil || value != "sk-secret" {
+		t.Errorf("Get() = %q, %v", value, err)
+	}
+	if removed, err := Delete("openai"); err != nil || !removed {
+		t.Errorf("Delete() = %v, %v", removed, err)
+	}
+	if names, _ := List(); !slices.Equal(names, []string{"gpu"}) {
+		t.Errorf("List() = %v", names)
+	}
+
+	if err := os.Chmod(filepath.Join(dir, "credentials.key"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cache.path = ""
+	if _, err := Get("gpu"); err == nil || !strings.Contains(err.Error(), "chmod 600") {
+		t.Errorf("Get() with a readable key file = %v", err)
+	}
+}
+
+func TestStore_Passphrase(t *testing.T) {
+	dir := sandbox(t)
+	iterations = 1000
+	t.Cleanup(func() { iterations = 600000 })
+	t.Setenv("AGENVOY_PASSPHRASE", "correct horse")
+
+	if err := Set("copilot", `{"access_token":"gho_x"}`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "credentials.key")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("a passphrase store should not create a key file")
+	}
+	if status, _ := Status(); status.KDF != kdfPassphrase {
+		t.Errorf("Status() = %+v", status)
+	}
+
+	cache.path = ""
+	t.Setenv("AGENVOY_PASSPHRASE", "wrong")
+	if _, err := Get("copilot"); err == nil {
+		t.Error("Get() with a wrong passphrase should fail")
+	}
+	t.Setenv("AGENVOY_PASSPHRASE", "")
+	if _, err := Get("copilot"); err == nil || !strings.Contains(err.Error(), "AGENVOY_PASSPHRASE") {
+		t.Errorf("Get() without passphrase = %v", err)
+	}
+
+	t.Setenv("AGENVOY_PASSPHRASE", "correct horse")
+	if value, err := Get("copilot"); err != nil || value != `{"access_token":"gho_x"}` {
+		t.Errorf("Get() = %q, %v", value, err)
+	}
+}
+
+func TestStore_MigrateKeychain(t *testing.T) {
+	dir := sandbox(t)
+	if err := Set("openai", "sk-new"); err != nil {
+		t.Fatal(err)
+	}
+	legacy := filepath.Join(dir, "keychain.json")
+	if err := os.WriteFile(legacy, []byte(`{"openai":"sk-old","gpu":"gpu-key"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cache.path = ""
+	if value, err := Get("gpu"); err != nil || value != "gpu-key" {
+		t.Errorf("Get() = %q, %v, want the migrated entry", value, err)
+	}
+	if value, _ := Get("openai"); value != "sk-new" {
+		t.Errorf("Get() = %q, the stored entry should win", value)
+	}
+	if _, err := os.Stat(legacy); !errors.Is(err, os.ErrNotExist) {
+		t.Error("keychain.json should be removed after the migration")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "credentials.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "gpu-key") {
+		t.Error("migrated entry is stored in plain text")
+	}
+}
@@ -0,0 +1,168 @@
+package credential
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	kdfPassphrase = "pbkdf2-sha256"
+	kdfKeyFile    = "key-file"
+	keySize       = 32
+)
+
+// * owasp recommendation for pbkdf2-sha256, lowered in tests
+var iterations = 600000
+
+type sealedData struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations,omitempty"`
+	Salt       string `json:"salt,omitempty"`
+	Nonce      string `json:"nonce"`
+	Data       string `json:"data"`
+}
+
+// * a passphrase is used whenever it is set, otherwise the local key file
+func seal(plain []byte, keyPath string) (*sealedData, error) {
+	sealed := &sealedData{Version: 1}
+
+	var key []byte
+	if passphrase := os.Getenv("AGENVOY_PASSPHRASE"); passphrase != "" {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, fmt.Errorf("rand.Read: %w", err)
+		}
+		derived, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, keySize)
+		if err != nil {
+			return nil, fmt.Errorf("pbkdf2.Key: %w", err)
+		}
+		key = derived
+		sealed.KDF = kdfPassphrase
+		sealed.Iterations = iterations
+		sealed.Salt = base64.StdEncoding.EncodeToString(salt)
+	} else {
+		fileKey, err := readKeyFile(keyPath, true)
+		if err != nil {
+			return nil, fmt.Errorf("readKeyFile: %w", err)
+		}
+		key = fileKey
+		sealed.KDF = kdfKeyFile
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("rand.Read: %w", err)
+	}
+	sealed.Nonce = base64.StdEncoding.EncodeToString(nonce)
+	sealed.Data = base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, plain, nil))
+	return sealed, nil
+}
+
+func open(sealed *sealedData, keyPath string) ([]byte, error) {
+	var key []byte
+	switch sealed.KDF {
+	case kdfPassphrase:
+		passphrase := os.Getenv("AGENVOY_PASSPHRASE")
+		if passphrase == "" {
+			return nil, fmt.Errorf("AGENVOY_PASSPHRASE is required to unlock the credential store")
+		}
+		salt, err := base64.StdEncoding.DecodeString(sealed.Salt)
+		if err != nil {
+			return nil, fmt.Errorf("base64.DecodeString: %w", err)
+		}
+		key, err = pbkdf2.Key(sha256.New, passphrase, salt, sealed.Iterations, keySize)
+		if err != nil {
+			return nil, fmt.Errorf("pbkdf2.Key: %w", err)
+		}
+
+	case kdfKeyFile:
+		fileKey, err := readKeyFile(keyPath, false)
+		if err != nil {
+			return nil, fmt.Errorf("readKeyFile: %w", err)
+		}
+		key = fileKey
+
+	default:
+		return nil, fmt.Errorf("unsupported kdf: %s", sealed.KDF)
+	}
+
+	nonce, err := base64.StdEncoding.DecodeString(sealed.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("base64.DecodeString: %w", err)
+	}
+	data, err := base64.StdEncoding.DecodeString(sealed.Data)
+	if err != nil {
+		return nil, fmt.Errorf("base64.DecodeString: %w", err)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce size")
+	}
+	plain, err := gcm.Open(nil, nonce, data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("wrong passphrase or key file, or the store is corrupted")
+	}
+	return plain, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("aes.NewCipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("cipher.NewGCM: %w", err)
+	}
+	return gcm, nil
+}
+
+// * the key file is generated on first write and refused once others can read it
+func readKeyFile(path string, create bool) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && create {
+		key := make([]byte, keySize)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("rand.Read: %w", err)
+		}
+		encoded := base64.StdEncoding.EncodeToString(key)
+		if err := os.WriteFile(path, []byte(encoded+"\n"), 0600); err != nil {
+			return nil, fmt.Errorf("os.WriteFile: %w", err)
+		}
+		return key, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile: %w", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.Stat: %w", err)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("%s must not be readable by others, run chmod 600", path)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != keySize {
+		return nil, fmt.Errorf("%s is not a valid key file", path)
+	}
+	return key, nil
+}
@@ -0,0 +1,258 @@
+package credential
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pardnchiu/agenvoy/internal/utils"
+)
+
+var ErrNotFound = errors.New("credential not found")
+
+// * deriving the key is slow on purpose, the decrypted store is kept until the file changes
+var (
+	mu    sync.Mutex
+	cache struct {
+		path    string
+		modTime time.Time
+		values  map[string]string
+	}
+)
+
+// * only the home config holds credentials, a project config cannot ship any
+func getPaths() (string, string, error) {
+	configDir, err := utils.GetConfigDir()
+	if err != nil {
+		return "", "", fmt.Errorf("utils.GetConfigDir: %w", err)
+	}
+	return filepath.Join(configDir.Home, "credentials.json"), filepath.Join(configDir.Home, "credentials.key"), nil
+}
+
+func load(path, keyPath string) (map[string]string, error) {
+	values, err := read(path, keyPath)
+	if err != nil {
+		return nil, err
+	}
+	return migrateKeychain(path, keyPath, values)
+}
+
+func read(path, keyPath string) (map[string]string, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("os.Stat: %w", err)
+	}
+	if cache.path == path && cache.modTime.Equal(info.ModTime()) {
+		return cache.values, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile: %w", err)
+	}
+	var sealed sealedData
+	if err := json.Unmarshal(data, &sealed); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+	plain, err := open(&sealed, keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+
+	values := make(map[string]string)
+	if err := json.Unmarshal(plain, &values); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+	cache.path, cache.modTime, cache.values = path, info.ModTime(), values
+	return values, nil
+}
+
+// * the plain keychain.json of older versions is moved in once, a name already in the store wins
+func migrateKeychain(path, keyPath string, values map[string]string) (map[string]string, error) {
+	legacyPath := filepath.Join(filepath.Dir(path), "keychain.json")
+	data, err := os.ReadFile(legacyPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return values, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile: %w", err)
+	}
+	var legacy map[string]string
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return nil, fmt.Errorf("%s: %w", legacyPath, err)
+	}
+
+	next := make(map[string]string, len(values)+len(legacy))
+	for k, v := range values {
+		next[k] = v
+	}
+	for k, v := range legacy {
+		if _, ok := next[k]; !ok && v != "" {
+			next[k] = v
+		}
+	}
+	if err := save(path, keyPath, next); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(legacyPath); err != nil {
+		return nil, fmt.Errorf("os.Remove: %w", err)
+	}
+	return next, nil
+}
+
+func save(path, keyPath string, values map[string]string) error {
+	plain, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+	sealed, err := seal(plain, keyPath)
+	if err != nil {
+		return fmt.Errorf("seal: %w", err)
+	}
+	data, err := json.MarshalIndent(sealed, "", "  ")
+	if err != nil {
+		return fmt.Errorf("json.MarshalIndent: %w", err)
+	}
+
+	// * written beside and renamed, a crash never leaves half a store
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("os.WriteFile: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("os.Rename: %w", err)
+	}
+
+	cache.path = ""
+	if info, err := os.Stat(path); err == nil {
+		cache.path, cache.modTime, cache.values = path, info.ModTime(), values
+	}
+	return nil
+}
+
+// Get returns the stored secret for name, or ErrNotFound.
+func Get(name string) (string, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	path, keyPath, err := getPaths()
+	if err != nil {
+		return "", err
+	}
+	values, err := load(path, keyPath)
+	if err != nil {
+		return "", err
+	}
+	value, ok := values[name]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+// Set stores the secret for name, re-encrypting the whole store.
+func Set(name, value string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	path, keyPath, err := getPaths()
+	if err != nil {
+		return err
+	}
+	values, err := load(path, keyPath)
+	if err != nil {
+		return err
+	}
+
+	next := make(map[string]string, len(values)+1)
+	for k, v := range values {
+		next[k] = v
+	}
+	next[name] = value
+	return save(path, keyPath, next)
+}
+
+// Delete removes name from the store, reporting whether it was there.
+func Delete(name string) (bool, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	path, keyPath, err := getPaths()
+	if err != nil {
+		return false, err
+	}
+	values, err := load(path, keyPath)
+	if err != nil {
+		return false, err
+	}
+	if _, ok := values[name]; !ok {
+		return false, nil
+	}
+
+	next := make(map[string]string, len(values))
+	for k, v := range values {
+		if k != name {
+			next[k] = v
+		}
+	}
+	return true, save(path, keyPath, next)
+}
+
+// List returns the stored names, sorted.
+func List() ([]string, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	path, keyPath, err := getPaths()
+	if err != nil {
+		return nil, err
+	}
+	values, err := load(path, keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+type StatusData struct {
+	Path string
+	// * empty until the first secret is stored
+	KDF string
+}
+
+// Status reports where the store lives and how it is locked, without unlocking it.
+func Status() (StatusData, error) {
+	path, _, err := getPaths()
+	if err != nil {
+		return StatusData{}, err
+	}
+	status := StatusData{Path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return status, nil
+	}
+	if err != nil {
+		return status, fmt.Errorf("os.ReadFile: %w", err)
+	}
+	var sealed sealedData
+	if err := json.Unmarshal(data, &sealed); err != nil {
+		return status, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+	status.KDF = sealed.KDF
+	return status, nil
+}
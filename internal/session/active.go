@@ -0,0 +1,74 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/pardnchiu/agenvoy/internal/utils"
+)
+
+// LockConfig takes the lock guarding config.json in dir, the returned func releases it.
+func LockConfig(dir string) (func(), error) {
+	lockPath := filepath.Join(dir, "config.json.lock")
+	file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("os.OpenFile: %w", err)
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("syscall.Flock: %w", err)
+	}
+
+	return func() {
+		_ = syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		file.Close()
+	}, nil
+}
+
+// SetActive makes sessionID the session the next turn continues.
+func SetActive(sessionID string) error {
+	configDir, err := utils.GetConfigDir("sessions")
+	if err != nil {
+		return fmt.Errorf("utils.GetConfigDir: %w", err)
+	}
+
+	path := filepath.Join(configDir.Home, "..", "config.json")
+	unlock, err := LockConfig(filepath.Dir(path))
+	if err != nil {
+		return fmt.Errorf("LockConfig: %w", err)
+	}
+	defer unlock()
+
+	// * other keys in config.json are kept as they are
+	config := map[string]json.RawMessage{}
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &config); err != nil {
+			return fmt.Errorf("json.Unmarshal: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("os.ReadFile: %w", err)
+	}
+
+	id, err := json.Marshal(sessionID)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+	config["session_id"] = id
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("os.WriteFile: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("os.Rename: %w", err)
+	}
+	return nil
+}
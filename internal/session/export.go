@@ -0,0 +1,94 @@
+package session
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+)
+
+//go:embed embed/transcript.html
+var transcriptHTML string
+
+var htmlTemplate = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"formatTime":  formatTime,
+	"contentText": agentTypes.ContentText,
+}).Parse(transcriptHTML))
+
+func Export(t *Transcript, format string) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "md", "markdown":
+		return exportMarkdown(t), nil
+
+	case "json":
+		data, err := json.MarshalIndent(t, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("json.MarshalIndent: %w", err)
+		}
+		return data, nil
+
+	case "html":
+		var buf bytes.Buffer
+		if err := htmlTemplate.Execute(&buf, t); err != nil {
+			return nil, fmt.Errorf("htmlTemplate.Execute: %w", err)
+		}
+		return buf.Bytes(), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+func exportMarkdown(t *Transcript) []byte {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# Session %s\n", t.ID))
+
+	for _, turn := range t.Turns {
+		sb.WriteString("\n---\n\n")
+		if turn.Time > 0 {
+			sb.WriteString(fmt.Sprintf("_%s_\n\n", formatTime(turn.Time)))
+		}
+
+		if turn.User != "" {
+			sb.WriteString("## User\n\n")
+			sb.WriteString(strings.TrimSpace(turn.User))
+			sb.WriteString("\n\n")
+		}
+
+		for _, tool := range turn.Tools {
+			content := agentTypes.ContentText(tool.Content)
+			sb.WriteString(fmt.Sprintf("<details><summary>Tool %s</summary>\n\n", tool.ToolCallID))
+			sb.WriteString("```\n")
+			sb.WriteString(strings.TrimSpace(content))
+			sb.WriteString("\n```\n\n</details>\n\n")
+		}
+
+		if turn.Assistant != "" {
+			sb.WriteString("## Assistant\n\n")
+			sb.WriteString(strings.TrimSpace(turn.Assistant))
+			sb.WriteString("\n")
+		}
+	}
+
+	if len(t.Summary) > 0 {
+		var buf bytes.Buffer
+		if json.Indent(&buf, t.Summary, "", "  ") != nil {
+			buf.Reset()
+			buf.Write(t.Summary)
+		}
+		sb.WriteString("\n---\n\n## Summary\n\n```json\n")
+		sb.WriteString(buf.String())
+		sb.WriteString("\n```\n")
+	}
+
+	return []byte(sb.String())
+}
+
+func formatTime(ts int64) string {
+	return time.Unix(ts, 0).Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,149 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+	"github.com/pardnchiu/agenvoy/internal/utils"
+)
+
+// * only the json export keeps enough structure to be restored, the imported session becomes active
+func Import(data []byte, force bool) (string, error) {
+	var t Transcript
+	if err := json.Unmarshal(data, &t); err != nil {
+		return "", fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	sessionID, err := checkID(t.ID)
+	if err != nil {
+		return "", err
+	}
+
+	configDir, err := utils.GetConfigDir("sessions")
+	if err != nil {
+		return "", fmt.Errorf("utils.GetConfigDir: %w", err)
+	}
+
+	sessionDir := filepath.Join(configDir.Home, sessionID)
+	if _, err := os.Stat(sessionDir); err == nil {
+		if !force {
+			return "", fmt.Errorf("session already exists: %s", sessionID)
+		}
+		// * files the transcript does not replace, like events.jsonl, would mix into the import
+		if err := os.RemoveAll(sessionDir); err != nil {
+			return "", fmt.Errorf("os.RemoveAll: %w", err)
+		}
+	}
+	if err := os.MkdirAll(sessionDir, 0755); err != nil {
+		return "", fmt.Errorf("os.MkdirAll: %w", err)
+	}
+
+	histories := make([]agentTypes.Message, 0, len(t.Turns)*2)
+	var records []Record
+	for _, turn := range t.Turns {
+		if turn.User != "" {
+			histories = append(histories, agentTypes.Message{
+				Role:    "user",
+				Content: fmt.Sprintf("ts:%d\n%s", turn.Time, turn.User),
+			})
+		}
+		if turn.Assistant != "" {
+			histories = append(histories, agentTypes.Message{
+				Role:    "assistant",
+				Content: fmt.Sprintf("ts:%d\n%s", turn.Time, turn.Assistant),
+			})
+		}
+
+		if len(turn.Tools) == 0 {
+			continue
+		}
+		records = append(records, turnRecords(turn)...)
+	}
+
+	if len(records) > 0 {
+		if err := writeLog(sessionDir, records); err != nil {
+			return "", fmt.Errorf("writeLog: %w", err)
+		}
+	}
+
+	historyData, err := json.Marshal(histories)
+	if err != nil {
+		return "", fmt.Errorf("json.Marshal: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(sessionDir, "history.json"), historyData, 0644); err != nil {
+		return "", fmt.Errorf("os.WriteFile: %w", err)
+	}
+
+	if len(t.Summary) > 0 && json.Valid(t.Summary) {
+		if err := os.WriteFile(filepath.Join(sessionDir, "summary.json"), t.Summary, 0644); err != nil {
+			return "", fmt.Errorf("os.WriteFile: %w", err)
+		}
+	}
+
+	if err := SetActive(sessionID); err != nil {
+		return "", fmt.Errorf("SetActive: %w", err)
+	}
+	return sessionID, nil
+}
+
+// * rebuild a minimal event log so tool actions stay attached to their turn
+func turnRecords(turn Turn) []Record {
+	turnID := fmt.Sprintf("import-%d", turn.Time)
+	at := turn.Time * 1000
+
+	records := []Record{
+		{Type: RecordTurnStart, Time: at, Turn: turnID, Input: turn.User},
+	}
+	if turn.User != "" {
+		records = append(records, Record{
+			Type: RecordMessage,
+			Time: at,
+			Turn: turnID,
+			Message: &agentTypes.Message{
+				Role:    "user",
+				Content: fmt.Sprintf("ts:%d\n%s", turn.Time, turn.User),
+			},
+		})
+	}
+	for _, tool := range turn.Tools {
+		message := tool
+		records = append(records, Record{
+			Type:    RecordMessage,
+			Time:    at,
+			Turn:    turnID,
+			Message: &message,
+		})
+	}
+	records = append(records,
+		Record{
+			Type: RecordMessage,
+			Time: at,
+			Turn: turnID,
+			Message: &agentTypes.Message{
+				Role:    "assistant",
+				Content: fmt.Sprintf("ts:%d\n%s", turn.Time, turn.Assistant),
+			},
+		},
+		Record{Type: RecordTurnEnd, Time: at, Turn: turnID, Result: turn.Assistant},
+	)
+	return records
+}
+
+func writeLog(sessionDir string, records []Record) error {
+	file, err := os.Create(filepath.Join(sessionDir, logFile))
+	if err != nil {
+		return fmt.Errorf("os.Create: %w", err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	for _, r := range records {
+		if err := encoder.Encode(r); err != nil {
+			return fmt.Errorf("encoder.Encode: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+	"github.com/pardnchiu/agenvoy/internal/utils"
+)
+
+const (
+	toolFileLayout = "2006-01-02-15-04-05"
+)
+
+type Transcript struct {
+	ID      string          `json:"id"`
+	Summary json.RawMessage `json:"summary,omitempty"`
+	Turns   []Turn          `json:"turns"`
+}
+
+type Turn struct {
+	Time      int64                `json:"time"`
+	User      string               `json:"user"`
+	Assistant string               `json:"assistant"`
+	Tools     []agentTypes.Message `json:"tools,omitempty"`
+}
+
+type toolAction struct {
+	time  int64
+	tools []agentTypes.Message
+}
+
+// * ids come from the command line or an imported file, only a plain folder name is accepted
+func checkID(sessionID string) (string, error) {
+	sessionID = strings.TrimSpace(sessionID)
+	if sessionID == "" {
+		return "", fmt.Errorf("session id is required")
+	}
+	if sessionID != filepath.Base(sessionID) || strings.HasPrefix(sessionID, ".") {
+		return "", fmt.Errorf("invalid session id: %s", sessionID)
+	}
+	return sessionID, nil
+}
+
+func Load(sessionID string) (*Transcript, error) {
+	sessionID, err := checkID(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	configDir, err := utils.GetConfigDir("sessions")
+	if err != nil {
+		return nil, fmt.Errorf("utils.GetConfigDir: %w", err)
+	}
+
+	historyData, err := os.ReadFile(filepath.Join(configDir.Home, sessionID, "history.json"))
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile: %w", err)
+	}
+
+	var histories []agentTypes.Message
+	if err := json.Unmarshal(historyData, &histories); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	transcript := &Transcript{
+		ID:    sessionID,
+		Turns: []Turn{},
+	}
+
+	if summaryData, err := os.ReadFile(filepath.Join(configDir.Home, sessionID, "summary.json")); err == nil && json.Valid(summaryData) {
+		transcript.Summary = summaryData
+	}
+
+	for _, m := range histories {
+		content, _ := m.Content.(string)
+		ts, text := splitTimestamp(content)
+
+		switch m.Role {
+		case "user":
+			transcript.Turns = append(transcript.Turns, Turn{
+				Time: ts,
+				User: text,
+			})
+		case "assistant":
+			// * reply without preceding user turn, keep it as a standalone turn
+			if len(transcript.Turns) == 0 || transcript.Turns[len(transcript.Turns)-1].Assistant != "" {
+				transcript.Turns = append(transcript.Turns, Turn{Time: ts})
+			}
+			last := &transcript.Turns[len(transcript.Turns)-1]
+			last.Assistant = text
+			if ts > 0 {
+				last.Time = ts
+			}
+		}
+	}
+
+	// * event log is authoritative, timestamped tool files only exist for legacy sessions
+	actions := loadLogActions(filepath.Join(configDir.Home, sessionID))
+	if len(actions) == 0 {
+		actions = loadToolActions(configDir, sessionID)
+	}
+
+	// * attach each action to the latest turn not after it
+	for _, action := range actions {
+		idx := -1
+		for i, turn := range transcript.Turns {
+			if turn.Time <= action.time+1 {
+				idx = i
+			}
+		}
+		if idx == -1 {
+			continue
+		}
+		transcript.Turns[idx].Tools = append(transcript.Turns[idx].Tools, action.tools...)
+	}
+
+	return transcript, nil
+}
+
+func loadLogActions(sessionDir string) []toolAction {
+	records, err := ReadLog(sessionDir)
+	if err != nil {
+		return nil
+	}
+
+	var actions []toolAction
+	index := make(map[string]int)
+	for _, r := range records {
+		if r.Type != RecordMessage || r.Message == nil {
+			continue
+		}
+
+		idx, ok := index[r.Turn]
+		if !ok {
+			idx = len(actions)
+			index[r.Turn] = idx
+			actions = append(actions, toolAction{})
+		}
+
+		switch r.Message.Role {
+		case "tool":
+			actions[idx].tools = append(actions[idx].tools, *r.Message)
+		case "assistant":
+			// * reply carries the same ts as history.json, use it to pin the turn
+			content, _ := r.Message.Content.(string)
+			if ts, _ := splitTimestamp(content); ts > 0 {
+				actions[idx].time = ts
+			}
+		}
+	}
+
+	// * turns without a recorded reply never reached history.json, nothing to attach to
+	result := actions[:0]
+	for _, action := range actions {
+		if len(action.tools) > 0 && action.time > 0 {
+			result = append(result, action)
+		}
+	}
+	return result
+}
+
+func loadToolActions(configDir *utils.ConfigDirData, sessionID string) []toolAction {
+	var actions []toolAction
+	seen := make(map[string]struct{})
+	for _, dir := range configDir.Dirs {
+		root := filepath.Join(dir, sessionID)
+		dateDirs, err := os.ReadDir(root)
+		if err != nil {
+			continue
+		}
+
+		for _, dateDir := range dateDirs {
+			if !dateDir.IsDir() {
+				continue
+			}
+
+			files, err := os.ReadDir(filepath.Join(root, dateDir.Name()))
+			if err != nil {
+				continue
+			}
+
+			for _, file := range files {
+				name := file.Name()
+				if file.IsDir() || filepath.Ext(name) != ".json" {
+					continue
+				}
+				if _, ok := seen[name]; ok {
+					continue
+				}
+
+				t, err := time.ParseInLocation(toolFileLayout, strings.TrimSuffix(name, ".json"), time.Local)
+				if err != nil {
+					continue
+				}
+
+				data, err := os.ReadFile(filepath.Join(root, dateDir.Name(), name))
+				if err != nil {
+					continue
+				}
+
+				var tools []agentTypes.Message
+				if json.Unmarshal(data, &tools) != nil {
+					continue
+				}
+				seen[name] = struct{}{}
+				actions = append(actions, toolAction{
+					time:  t.Unix(),
+					tools: tools,
+				})
+			}
+		}
+	}
+
+	sort.Slice(actions, func(i, j int) bool {
+		return actions[i].time < actions[j].time
+	})
+	return actions
+}
+
+// * history content is stored as "ts:<unix>\n<text>"
+func splitTimestamp(content string) (int64, string) {
+	if !strings.HasPrefix(content, "ts:") {
+		return 0, content
+	}
+	rest := content[3:]
+	idx := strings.IndexByte(rest, '\n')
+	if idx < 0 {
+		return 0, content
+	}
+	ts, err := strconv.ParseInt(rest[:idx], 10, 64)
+	if err != nil {
+		return 0, content
+	}
+	return ts, rest[idx+1:]
+}
@@ -0,0 +1,348 @@
+package session
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+)
+
+const (
+	logFile = "events.jsonl"
+	blobDir = "blobs"
+)
+
+type RecordType string
+
+const (
+	RecordTurnStart  RecordType = "turn_start"
+	RecordMessage    RecordType = "message"
+	RecordToolCall   RecordType = "tool_call"
+	RecordToolResult RecordType = "tool_result"
+	RecordTurnEnd    RecordType = "turn_end"
+	RecordError      RecordType = "error"
+)
+
+type Record struct {
+	Type     RecordType            `json:"type"`
+	Time     int64                 `json:"time"` // unix milli
+	Turn     string                `json:"turn"`
+	Agent    string                `json:"agent,omitempty"`
+	Skill    string                `json:"skill,omitempty"`
+	Input    string                `json:"input,omitempty"`
+	Message  *agentTypes.Message   `json:"message,omitempty"`
+	ToolName string                `json:"tool_name,omitempty"`
+	ToolArgs string                `json:"tool_args,omitempty"`
+	ToolID   string                `json:"tool_id,omitempty"`
+	Result   string                `json:"result,omitempty"`
+	Status   string                `json:"status,omitempty"` // tool_result: ok / cached / skipped / error
+	Duration int64                 `json:"duration,omitempty"`
+	Error    string                `json:"error,omitempty"`
+	Usage    *agentTypes.UsageData `json:"usage,omitempty"`   // turn_end
+	Context  []string              `json:"context,omitempty"` // turn_start: blob hashes of the messages sent before the input
+
+	Schema      *agentTypes.SchemaData   `json:"schema,omitempty"`      // turn_start: the answer must match it
+	Attachments []agentTypes.ContentPart `json:"attachments,omitempty"` // turn_start: media sent with the input, data kept as blobs
+}
+
+type Log struct {
+	mu   sync.Mutex
+	dir  string
+	file *os.File
+	turn string
+}
+
+func OpenLog(sessionDir, turn string) (*Log, error) {
+	if err := os.MkdirAll(sessionDir, 0755); err != nil {
+		return nil, fmt.Errorf("os.MkdirAll: %w", err)
+	}
+
+	file, err := os.OpenFile(filepath.Join(sessionDir, logFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("os.OpenFile: %w", err)
+	}
+
+	return &Log{
+		dir:  sessionDir,
+		file: file,
+		turn: turn,
+	}, nil
+}
+
+// * nil log is a no-op, callers do not need to guard every write
+func (l *Log) Append(r Record) error {
+	if l == nil {
+		return nil
+	}
+
+	if r.Time == 0 {
+		r.Time = time.Now().UnixMilli()
+	}
+	if r.Turn == "" {
+		r.Turn = l.turn
+	}
+	if r.Message != nil {
+		message := l.storeMedia(*r.Message)
+		r.Message = &message
+	}
+	if len(r.Attachments) > 0 {
+		r.Attachments = l.storeParts(r.Attachments)
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("file.Write: %w", err)
+	}
+	return nil
+}
+
+// StoreContext keeps messages that repeat across turns, like the system prompt and recent history, as blobs and returns their hashes.
+func (l *Log) StoreContext(messages []agentTypes.Message) []string {
+	if l == nil {
+		return nil
+	}
+
+	hashes := make([]string, 0, len(messages))
+	for _, m := range messages {
+		data, err := json.Marshal(l.storeMedia(m))
+		if err != nil {
+			continue
+		}
+		hash, err := writeBlob(l.dir, data)
+		if err != nil {
+			continue
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes
+}
+
+// * base64 media goes to a blob once, the record keeps its hash
+func (l *Log) storeMedia(message agentTypes.Message) agentTypes.Message {
+	parts, ok := message.Content.([]agentTypes.ContentPart)
+	if !ok {
+		return message
+	}
+	message.Content = l.storeParts(parts)
+	return message
+}
+
+func (l *Log) storeParts(parts []agentTypes.ContentPart) []agentTypes.ContentPart {
+	stored := make([]agentTypes.ContentPart, len(parts))
+	for i, part := range parts {
+		stored[i] = part
+		if part.Data == "" {
+			continue
+		}
+		hash, err := writeBlob(l.dir, []byte(part.Data))
+		if err != nil {
+			continue
+		}
+		stored[i].Data = ""
+		stored[i].Ref = hash
+	}
+	return stored
+}
+
+// * content addressed, the same prompt or image is written once per session
+func writeBlob(sessionDir string, data []byte) (string, error) {
+	hash := fmt.Sprintf("%x", sha256.Sum256(data))
+	dir := filepath.Join(sessionDir, blobDir)
+	path := filepath.Join(dir, hash)
+	if _, err := os.Stat(path); err == nil {
+		return hash, nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("os.MkdirAll: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return "", fmt.Errorf("os.WriteFile: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", fmt.Errorf("os.Rename: %w", err)
+	}
+	return hash, nil
+}
+
+func restoreParts(sessionDir string, parts []agentTypes.ContentPart) ([]agentTypes.ContentPart, error) {
+	for i, part := range parts {
+		if part.Ref == "" {
+			continue
+		}
+		data, err := readBlob(sessionDir, part.Ref)
+		if err != nil {
+			return nil, fmt.Errorf("readBlob: %w", err)
+		}
+		parts[i].Data = string(data)
+		parts[i].Ref = ""
+	}
+	return parts, nil
+}
+
+func readBlob(sessionDir, hash string) ([]byte, error) {
+	if hash != filepath.Base(hash) {
+		return nil, fmt.Errorf("invalid blob %q", hash)
+	}
+	data, err := os.ReadFile(filepath.Join(sessionDir, blobDir, hash))
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile: %w", err)
+	}
+	return data, nil
+}
+
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+func ReadLog(sessionDir string) ([]Record, error) {
+	file, err := os.Open(filepath.Join(sessionDir, logFile))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var r Record
+		// * a crash can leave a partial trailing line, skip it
+		if json.Unmarshal(line, &r) != nil {
+			continue
+		}
+		records = append(records, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return records, fmt.Errorf("scanner.Err: %w", err)
+	}
+	return records, nil
+}
+
+type PendingTurn struct {
+	Turn     string
+	Agent    string
+	Skill    string
+	Input    string
+	Context  []string // blob hashes, RestorePending puts them in front of Messages
+	Messages []agentTypes.Message
+
+	Schema      *agentTypes.SchemaData
+	Attachments []agentTypes.ContentPart // RestorePending fills their data back in
+}
+
+// * last turn without turn_end, trimmed to the last point every tool call has its result
+func GetPendingTurn(records []Record) *PendingTurn {
+	start := -1
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].Type == RecordTurnEnd {
+			return nil
+		}
+		if records[i].Type == RecordTurnStart {
+			start = i
+			break
+		}
+	}
+	if start == -1 {
+		return nil
+	}
+
+	head := records[start]
+	pending := &PendingTurn{
+		Turn:    head.Turn,
+		Agent:   head.Agent,
+		Skill:   head.Skill,
+		Input:   head.Input,
+		Context: head.Context,
+
+		Schema:      head.Schema,
+		Attachments: head.Attachments,
+	}
+	for _, r := range records[start+1:] {
+		if r.Turn == head.Turn && r.Type == RecordMessage && r.Message != nil {
+			pending.Messages = append(pending.Messages, *r.Message)
+		}
+	}
+
+	for i := len(pending.Messages) - 1; i >= 0; i-- {
+		m := pending.Messages[i]
+		if m.Role != "assistant" || len(m.ToolCalls) == 0 {
+			continue
+		}
+		answered := make(map[string]struct{})
+		for _, r := range pending.Messages[i+1:] {
+			if r.Role == "tool" {
+				answered[r.ToolCallID] = struct{}{}
+			}
+		}
+		for _, call := range m.ToolCalls {
+			if _, ok := answered[call.ID]; !ok {
+				pending.Messages = pending.Messages[:i]
+				break
+			}
+		}
+		break
+	}
+
+	if len(pending.Messages) == 0 {
+		return nil
+	}
+	return pending
+}
+
+// RestorePending loads the context blobs in front of the messages and fills media back in from their refs.
+func RestorePending(sessionDir string, pending *PendingTurn) error {
+	messages := make([]agentTypes.Message, 0, len(pending.Context)+len(pending.Messages))
+	for _, hash := range pending.Context {
+		data, err := readBlob(sessionDir, hash)
+		if err != nil {
+			return fmt.Errorf("readBlob: %w", err)
+		}
+		var m agentTypes.Message
+		if err := json.Unmarshal(data, &m); err != nil {
+			return fmt.Errorf("json.Unmarshal: %w", err)
+		}
+		messages = append(messages, m)
+	}
+	messages = append(messages, pending.Messages...)
+
+	for i, m := range messages {
+		if _, ok := m.Content.(string); ok || m.Content == nil {
+			continue
+		}
+		parts, err := restoreParts(sessionDir, agentTypes.ContentParts(m.Content))
+		if err != nil {
+			return err
+		}
+		messages[i].Content = parts
+	}
+	attachments, err := restoreParts(sessionDir, pending.Attachments)
+	if err != nil {
+		return err
+	}
+
+	pending.Attachments = attachments
+	pending.Context = nil
+	pending.Messages = messages
+	return nil
+}
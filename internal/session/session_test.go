@@ -0,0 +1,289 @@
+package session
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+	"github.com/pardnchiu/agenvoy/internal/utils"
+)
+
+// ---------- splitTimestamp ----------
+
+func TestSplitTimestamp(t *testing.T) {
+	tests := []struct {
+		input    string
+		wantTs   int64
+		wantText string
+	}{
+		{"ts:1700000000\nhello", 1700000000, "hello"},
+		{"no prefix", 0, "no prefix"},
+		{"ts:abc\nhello", 0, "ts:abc\nhello"},
+		{"ts:1700000000", 0, "ts:1700000000"},
+	}
+	for _, tt := range tests {
+		ts, text := splitTimestamp(tt.input)
+		if ts != tt.wantTs || text != tt.wantText {
+			t.Errorf("splitTimestamp(%q) = (%d, %q), want (%d, %q)", tt.input, ts, text, tt.wantTs, tt.wantText)
+		}
+	}
+}
+
+// ---------- Import / Load ----------
+
+func TestImportLoad_RoundTrip(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+
+	src := Transcript{
+		ID:      "11111111-2222-4333-8444-555555555555",
+		Summary: json.RawMessage(`{"core_discussion":"weather"}`),
+		Turns: []Turn{
+			{Time: 1700000000, User: "hi", Assistant: "hello"},
+			{
+				Time:      1700000100,
+				User:      "weather?",
+				Assistant: "sunny",
+				Tools: []agentTypes.Message{
+					{Role: "tool", Content: "[fetch_weather] sunny", ToolCallID: "call_1"},
+				},
+			},
+		},
+	}
+	data, _ := json.Marshal(src)
+
+	id, err := Import(data, false)
+	if err != nil {
+		t.Fatalf("Import() error: %v", err)
+	}
+	if id != src.ID {
+		t.Errorf("Import() id = %q, want %q", id, src.ID)
+	}
+
+	if _, err := Import(data, false); err == nil {
+		t.Error("Import() existing session without force should fail")
+	}
+
+	// * force starts from an empty folder, the stale log must not survive
+	configDir, _ := utils.GetConfigDir("sessions")
+	stale := filepath.Join(configDir.Home, id, "stale.txt")
+	os.WriteFile(stale, []byte("x"), 0644)
+	if _, err := Import(data, true); err != nil {
+		t.Errorf("Import() with force error: %v", err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("Import() with force kept a stale file")
+	}
+
+	config, _ := os.ReadFile(filepath.Join(configDir.Home, "..", "config.json"))
+	if !strings.Contains(string(config), id) {
+		t.Errorf("config.json = %s, want the imported session active", config)
+	}
+
+	got, err := Load(id)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if len(got.Turns) != 2 {
+		t.Fatalf("Load() turns = %d, want 2", len(got.Turns))
+	}
+	if got.Turns[1].User != "weather?" || got.Turns[1].Assistant != "sunny" {
+		t.Errorf("Load() turn[1] = %+v", got.Turns[1])
+	}
+	if len(got.Turns[0].Tools) != 0 {
+		t.Errorf("Load() turn[0] tools = %d, want 0", len(got.Turns[0].Tools))
+	}
+	if len(got.Turns[1].Tools) != 1 || got.Turns[1].Tools[0].ToolCallID != "call_1" {
+		t.Errorf("Load() turn[1] tools = %+v", got.Turns[1].Tools)
+	}
+	if string(got.Summary) != `{"core_discussion":"weather"}` {
+		t.Errorf("Load() summary = %s", got.Summary)
+	}
+}
+
+func TestInvalidID(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Chdir(t.TempDir())
+
+	// * a history outside the store that a crafted id could reach
+	outside := filepath.Join(home, ".config", "agenvoy", "x")
+	os.MkdirAll(outside, 0755)
+	os.WriteFile(filepath.Join(outside, "history.json"), []byte(`[]`), 0644)
+
+	for _, id := range []string{"", "../escape", ".hidden", "../x", "a/../../x", ".."} {
+		data, _ := json.Marshal(Transcript{ID: id})
+		if _, err := Import(data, false); err == nil {
+			t.Errorf("Import(id=%q) should fail", id)
+		}
+		if _, err := Load(id); err == nil || (id != "" && !strings.Contains(err.Error(), "invalid session id")) {
+			t.Errorf("Load(id=%q) error = %v, want invalid id", id, err)
+		}
+	}
+}
+
+// ---------- Export ----------
+
+func TestExport(t *testing.T) {
+	tr := &Transcript{
+		ID: "abc",
+		Turns: []Turn{
+			{Time: 1700000000, User: "<b>hi</b>", Assistant: "hello"},
+		},
+	}
+
+	md, err := Export(tr, "md")
+	if err != nil {
+		t.Fatalf("Export(md) error: %v", err)
+	}
+	if !strings.Contains(string(md), "## User") || !strings.Contains(string(md), "hello") {
+		t.Errorf("Export(md) missing content: %s", md)
+	}
+
+	html, err := Export(tr, "html")
+	if err != nil {
+		t.Fatalf("Export(html) error: %v", err)
+	}
+	if strings.Contains(string(html), "<b>hi</b>") {
+		t.Error("Export(html) should escape user content")
+	}
+
+	js, err := Export(tr, "json")
+	if err != nil {
+		t.Fatalf("Export(json) error: %v", err)
+	}
+	var back Transcript
+	if err := json.Unmarshal(js, &back); err != nil || back.ID != "abc" {
+		t.Errorf("Export(json) not decodable: %v", err)
+	}
+
+	if _, err := Export(tr, "pdf"); err == nil {
+		t.Error("Export(pdf) should fail")
+	}
+}
+
+// ---------- GetPendingTurn ----------
+
+func TestGetPendingTurn(t *testing.T) {
+	msg := func(role, content, callID string) *agentTypes.Message {
+		return &agentTypes.Message{Role: role, Content: content, ToolCallID: callID}
+	}
+	call := agentTypes.ToolCall{ID: "call_1", Type: "function"}
+	call.Function.Name = "read_file"
+	assistantCall := &agentTypes.Message{Role: "assistant", ToolCalls: []agentTypes.ToolCall{call}}
+
+	completed := []Record{
+		{Type: RecordTurnStart, Turn: "t1", Agent: "claude@x", Input: "hi"},
+		{Type: RecordMessage, Turn: "t1", Message: msg("user", "hi", "")},
+		{Type: RecordTurnEnd, Turn: "t1"},
+	}
+	if got := GetPendingTurn(completed); got != nil {
+		t.Errorf("GetPendingTurn(completed) = %+v, want nil", got)
+	}
+
+	interrupted := append(completed,
+		Record{Type: RecordTurnStart, Turn: "t2", Agent: "claude@x", Skill: "readme", Input: "go"},
+		Record{Type: RecordMessage, Turn: "t2", Message: msg("system", "prompt", "")},
+		Record{Type: RecordMessage, Turn: "t2", Message: msg("user", "go", "")},
+		Record{Type: RecordMessage, Turn: "t2", Message: assistantCall},
+	)
+	got := GetPendingTurn(interrupted)
+	if got == nil {
+		t.Fatal("GetPendingTurn(interrupted) = nil")
+	}
+	if got.Turn != "t2" || got.Agent != "claude@x" || got.Skill != "readme" || got.Input != "go" {
+		t.Errorf("GetPendingTurn() header = %+v", got)
+	}
+	// unanswered tool call must be trimmed so the model re-issues it
+	if len(got.Messages) != 2 {
+		t.Errorf("GetPendingTurn() messages = %d, want 2", len(got.Messages))
+	}
+
+	answered := append(interrupted,
+		Record{Type: RecordMessage, Turn: "t2", Message: msg("tool", "[read_file] ok", "call_1")},
+	)
+	got = GetPendingTurn(answered)
+	if got == nil || len(got.Messages) != 4 {
+		t.Errorf("GetPendingTurn(answered) messages = %v, want 4", got)
+	}
+}
+
+// ---------- Log ----------
+
+func TestLog_AppendRead(t *testing.T) {
+	dir := t.TempDir()
+
+	var nilLog *Log
+	if err := nilLog.Append(Record{Type: RecordTurnStart}); err != nil {
+		t.Errorf("nil Log.Append() error: %v", err)
+	}
+
+	l, err := OpenLog(dir, "t1")
+	if err != nil {
+		t.Fatalf("OpenLog() error: %v", err)
+	}
+	l.Append(Record{Type: RecordTurnStart, Input: "hi"})
+	l.Append(Record{Type: RecordTurnEnd})
+	l.Close()
+
+	records, err := ReadLog(dir)
+	if err != nil {
+		t.Fatalf("ReadLog() error: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("ReadLog() = %d records, want 2", len(records))
+	}
+	if records[0].Turn != "t1" || records[0].Time == 0 {
+		t.Errorf("Append() should fill turn and time, got %+v", records[0])
+	}
+}
+
+func TestLog_Blobs(t *testing.T) {
+	dir := t.TempDir()
+	system := agentTypes.Message{Role: "system", Content: "prompt"}
+	user := agentTypes.Message{Role: "user", Content: []agentTypes.ContentPart{
+		{Type: agentTypes.PartText, Text: "what is this"},
+		{Type: agentTypes.PartImage, MediaType: "image/png", Data: "aGVsbG8=", Name: "a.png"},
+	}}
+
+	// * two turns with the same prompt and image store each blob once
+	for _, turn := range []string{"t1", "t2"} {
+		l, err := OpenLog(dir, turn)
+		if err != nil {
+			t.Fatalf("OpenLog() error: %v", err)
+		}
+		l.Append(Record{Type: RecordTurnStart, Input: "what is this", Context: l.StoreContext([]agentTypes.Message{system})})
+		l.Append(Record{Type: RecordMessage, Message: &user})
+		l.Close()
+	}
+
+	blobs, _ := os.ReadDir(filepath.Join(dir, blobDir))
+	if len(blobs) != 2 {
+		t.Errorf("blobs = %d, want prompt and image once", len(blobs))
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, logFile))
+	if strings.Contains(string(data), "aGVsbG8=") || strings.Contains(string(data), "prompt") {
+		t.Errorf("events.jsonl keeps inline content: %s", data)
+	}
+
+	records, err := ReadLog(dir)
+	if err != nil {
+		t.Fatalf("ReadLog() error: %v", err)
+	}
+	pending := GetPendingTurn(records)
+	if pending == nil {
+		t.Fatal("GetPendingTurn() = nil")
+	}
+	if err := RestorePending(dir, pending); err != nil {
+		t.Fatalf("RestorePending() error: %v", err)
+	}
+	if len(pending.Messages) != 2 || pending.Messages[0].Content != "prompt" {
+		t.Fatalf("RestorePending() messages = %+v", pending.Messages)
+	}
+	if parts := agentTypes.ContentParts(pending.Messages[1].Content); len(parts) != 2 || parts[1].Data != "aGVsbG8=" || parts[1].Ref != "" {
+		t.Errorf("RestorePending() parts = %+v, want the image data back", parts)
+	}
+}
@@ -0,0 +1,66 @@
+package skill
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+func (a Argument) Check(value string) error {
+	switch a.Type {
+	case "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("%s must be a number, got %q", a.Name, value)
+		}
+	case "boolean":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%s must be a boolean, got %q", a.Name, value)
+		}
+	}
+	if len(a.Enum) > 0 && !slices.Contains(a.Enum, value) {
+		return fmt.Errorf("%s must be one of %s, got %q", a.Name, strings.Join(a.Enum, ", "), value)
+	}
+	return nil
+}
+
+// * validate given values against declared arguments and fill defaults
+func (s *Skill) ResolveArgs(values map[string]string) (map[string]string, error) {
+	return resolveArgs("skill "+s.Name, s.Arguments, values)
+}
+
+func (s Script) ResolveArgs(values map[string]string) (map[string]string, error) {
+	return resolveArgs("script "+s.Name, s.Arguments, values)
+}
+
+func resolveArgs(owner string, arguments []Argument, values map[string]string) (map[string]string, error) {
+	declared := make(map[string]Argument, len(arguments))
+	for _, a := range arguments {
+		declared[a.Name] = a
+	}
+
+	for name := range values {
+		if _, ok := declared[name]; !ok {
+			return nil, fmt.Errorf("%s has no argument %s", owner, name)
+		}
+	}
+
+	resolved := make(map[string]string, len(arguments))
+	for _, a := range arguments {
+		value, ok := values[a.Name]
+		if !ok || value == "" {
+			value = a.Default
+		}
+		if value == "" {
+			if a.Required {
+				return nil, fmt.Errorf("%s requires argument %s", owner, a.Name)
+			}
+			continue
+		}
+		if err := a.Check(value); err != nil {
+			return nil, err
+		}
+		resolved[a.Name] = value
+	}
+	return resolved, nil
+}
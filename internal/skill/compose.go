@@ -0,0 +1,97 @@
+package skill
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ResolvedContent returns Content with scripts/, templates/ and assets/ pointing at the skill folder.
+func (s *Skill) ResolvedContent() string {
+	// * parts were resolved one by one when composed
+	if len(s.Parts) > 0 {
+		return s.Content
+	}
+
+	content := s.Content
+	for _, prefix := range []string{"scripts/", "templates/", "assets/"} {
+		resolved := filepath.Join(s.Path, prefix)
+
+		if _, err := os.Stat(resolved); err == nil {
+			content = strings.ReplaceAll(content, prefix, resolved+string(filepath.Separator))
+		}
+	}
+	return content
+}
+
+// Compose merges several skills into one whose instructions follow the given order.
+func Compose(skills []*Skill) *Skill {
+	if len(skills) == 1 {
+		return skills[0]
+	}
+
+	names := make([]string, 0, len(skills))
+	hash := sha256.New()
+	for _, s := range skills {
+		names = append(names, s.Name)
+		hash.Write([]byte(s.Hash))
+	}
+
+	first := skills[0]
+	composed := &Skill{
+		Name:        strings.Join(names, "+"),
+		Description: first.Description,
+		AbsPath:     first.AbsPath,
+		Path:        first.Path,
+		Hash:        fmt.Sprintf("%x", hash.Sum(nil)),
+		Model:       first.Model,
+		Params:      first.Params,
+		Scope:       first.Scope,
+		Parts:       skills,
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("本次請求依序組合 %d 個技能，請按照順序完成每個技能負責的部分，前一個技能的產出可作為後一個技能的輸入。\n", len(skills)))
+
+	restricted := true
+	seenArgs := make(map[string]bool)
+	seenScripts := make(map[string]bool)
+	for i, s := range skills {
+		sb.WriteString(fmt.Sprintf("\n## 技能 %d：%s（%s）\n\n", i+1, s.Name, s.Path))
+		sb.WriteString(strings.TrimSpace(s.ResolvedContent()))
+		sb.WriteString("\n")
+
+		// * one unrestricted part means the composition cannot be restricted either
+		if len(s.AllowedTools) == 0 {
+			restricted = false
+		}
+		composed.AllowedTools = append(composed.AllowedTools, s.AllowedTools...)
+
+		for _, a := range s.Arguments {
+			if !seenArgs[a.Name] {
+				seenArgs[a.Name] = true
+				composed.Arguments = append(composed.Arguments, a)
+			}
+		}
+		for _, sc := range s.Scripts {
+			if seenScripts[sc.Name] {
+				slog.Warn("duplicate script in composed skills",
+					slog.String("skill", s.Name),
+					slog.String("script", sc.Name))
+				continue
+			}
+			seenScripts[sc.Name] = true
+			composed.Scripts = append(composed.Scripts, sc)
+		}
+	}
+	if !restricted {
+		composed.AllowedTools = nil
+	}
+
+	composed.Content = sb.String()
+	composed.Body = composed.Content
+	return composed
+}
@@ -0,0 +1,435 @@
+package skill
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var nameReplacer = strings.NewReplacer("/", "", "\\", "", ":", "")
+
+const downloadTimeout = 5 * time.Minute
+
+// * vars so tests can lower them
+var (
+	maxTarballSize int64 = 64 << 20
+	maxExtractSize int64 = 256 << 20
+)
+
+// * source is a local folder, a git url or a .tar.gz/.tgz file or url, the remote ones may end with #subdir
+func SourceType(source string) string {
+	base, _, _ := strings.Cut(source, "#")
+	switch {
+	case strings.HasSuffix(base, ".tar.gz") || strings.HasSuffix(base, ".tgz"):
+		return SourceTarball
+	case strings.HasPrefix(base, "git@"),
+		strings.HasPrefix(base, "git://"),
+		strings.HasPrefix(base, "ssh://"),
+		strings.HasPrefix(base, "file://"),
+		strings.HasPrefix(base, "http://"),
+		strings.HasPrefix(base, "https://"),
+		strings.HasSuffix(base, ".git"):
+		return SourceGit
+	default:
+		return SourcePath
+	}
+}
+
+// Install copies the skill found at source into target.Dir and records it in the lockfile.
+func Install(ctx context.Context, source string, target TargetData, force bool) (*Skill, error) {
+	kind := SourceType(source)
+	// * local sources are locked by absolute path so update works from any directory
+	if kind == SourcePath || (kind == SourceTarball && !strings.Contains(source, "://")) {
+		abs, err := filepath.Abs(source)
+		if err != nil {
+			return nil, fmt.Errorf("filepath.Abs: %w", err)
+		}
+		source = abs
+	}
+
+	root, cleanup, err := fetch(ctx, kind, source)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	root, err = findSkillRoot(root)
+	if err != nil {
+		return nil, err
+	}
+	return install(root, source, kind, target, force)
+}
+
+func install(root, source, kind string, target TargetData, force bool) (*Skill, error) {
+	skill, err := parser(filepath.Join(root, "SKILL.md"))
+	if err != nil {
+		return nil, fmt.Errorf("parser: %w", err)
+	}
+	if err := checkName(skill.Name); err != nil {
+		return nil, err
+	}
+
+	dest := filepath.Join(target.Dir, skill.Name)
+	if _, err := os.Stat(dest); err == nil && !force {
+		return nil, fmt.Errorf("skill %s already exists at %s, use --force to overwrite", skill.Name, dest)
+	}
+
+	if err := os.MkdirAll(target.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("os.MkdirAll: %w", err)
+	}
+	// * copy next to the destination first so a failed copy never leaves half a skill
+	tmp, err := os.MkdirTemp(target.Dir, "."+skill.Name+"-")
+	if err != nil {
+		return nil, fmt.Errorf("os.MkdirTemp: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := copyDir(root, tmp); err != nil {
+		return nil, fmt.Errorf("copyDir: %w", err)
+	}
+	restore, err := moveAside(dest)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		restore(false)
+		return nil, fmt.Errorf("os.Rename: %w", err)
+	}
+	// * the previous copy comes back unless the lockfile records the new one
+	done := false
+	defer func() {
+		if !done {
+			os.RemoveAll(dest)
+		}
+		restore(done)
+	}()
+
+	installed, err := parser(filepath.Join(dest, "SKILL.md"))
+	if err != nil {
+		return nil, fmt.Errorf("parser: %w", err)
+	}
+	installed.Scope = target.Scope
+
+	lock, err := ReadLock(target.Lock)
+	if err != nil {
+		return nil, fmt.Errorf("ReadLock: %w", err)
+	}
+	lock.Skills[installed.Name] = LockEntry{
+		Source:      source,
+		Type:        kind,
+		Hash:        installed.Hash,
+		Version:     installed.Version,
+		InstalledAt: time.Now().Unix(),
+	}
+	if err := lock.write(target.Lock); err != nil {
+		return nil, fmt.Errorf("lock.write: %w", err)
+	}
+	done = true
+	return installed, nil
+}
+
+// * renames an existing dest out of the way, restore(false) puts it back and restore(true) deletes it
+func moveAside(dest string) (func(keep bool), error) {
+	if _, err := os.Stat(dest); os.IsNotExist(err) {
+		return func(bool) {}, nil
+	}
+	old := fmt.Sprintf("%s.old-%d", filepath.Join(filepath.Dir(dest), "."+filepath.Base(dest)), time.Now().UnixNano())
+	if err := os.Rename(dest, old); err != nil {
+		return nil, fmt.Errorf("os.Rename: %w", err)
+	}
+	return func(done bool) {
+		if done {
+			os.RemoveAll(old)
+			return
+		}
+		os.Rename(old, dest)
+	}, nil
+}
+
+// Update reinstalls a locked skill from its recorded source, refusing to overwrite local edits unless forced.
+func Update(ctx context.Context, name string, target TargetData, force bool) (*Skill, bool, error) {
+	lock, err := ReadLock(target.Lock)
+	if err != nil {
+		return nil, false, fmt.Errorf("ReadLock: %w", err)
+	}
+	entry, ok := lock.Skills[name]
+	if !ok {
+		return nil, false, fmt.Errorf("skill %s is not in %s", name, target.Lock)
+	}
+
+	if problem, ok := lock.Verify(target.Dir)[name]; ok && problem != "missing" && !force {
+		return nil, false, fmt.Errorf("skill %s: %s, use --force to overwrite", name, problem)
+	}
+
+	root, cleanup, err := fetch(ctx, entry.Type, entry.Source)
+	if err != nil {
+		return nil, false, err
+	}
+	defer cleanup()
+
+	root, err = findSkillRoot(root)
+	if err != nil {
+		return nil, false, err
+	}
+	latest, err := parser(filepath.Join(root, "SKILL.md"))
+	if err != nil {
+		return nil, false, fmt.Errorf("parser: %w", err)
+	}
+	if latest.Name != name {
+		return nil, false, fmt.Errorf("source of %s now provides skill %s", name, latest.Name)
+	}
+
+	current, err := parser(filepath.Join(target.Dir, name, "SKILL.md"))
+	if err == nil && current.Hash == latest.Hash && current.Hash == entry.Hash {
+		current.Scope = target.Scope
+		return current, false, nil
+	}
+
+	installed, err := install(root, entry.Source, entry.Type, target, true)
+	if err != nil {
+		return nil, false, err
+	}
+	return installed, true, nil
+}
+
+func Remove(name string, target TargetData) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+
+	lock, err := ReadLock(target.Lock)
+	if err != nil {
+		return fmt.Errorf("ReadLock: %w", err)
+	}
+
+	dest := filepath.Join(target.Dir, name)
+	_, locked := lock.Skills[name]
+	if _, err := os.Stat(dest); os.IsNotExist(err) && !locked {
+		return fmt.Errorf("skill %s not found in %s", name, target.Dir)
+	}
+
+	restore, err := moveAside(dest)
+	if err != nil {
+		return err
+	}
+	if locked {
+		delete(lock.Skills, name)
+		if err := lock.write(target.Lock); err != nil {
+			restore(false)
+			return fmt.Errorf("lock.write: %w", err)
+		}
+	}
+	restore(true)
+	return nil
+}
+
+func checkName(name string) error {
+	if name == "" || name[0] == '.' || nameReplacer.Replace(name) != name {
+		return fmt.Errorf("invalid skill name %q", name)
+	}
+	return nil
+}
+
+// * returns the folder holding the source, cleanup removes anything downloaded
+func fetch(ctx context.Context, kind, source string) (string, func(), error) {
+	noop := func() {}
+	if kind == SourcePath {
+		return source, noop, nil
+	}
+	base, subdir, _ := strings.Cut(source, "#")
+
+	tmp, err := os.MkdirTemp("", "agenvoy-skill-")
+	if err != nil {
+		return "", noop, fmt.Errorf("os.MkdirTemp: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(tmp) }
+
+	switch kind {
+	case SourceGit:
+		// * -- keeps a source starting with - from being read as a git option
+		cmd := exec.CommandContext(ctx, "git", "clone", "--depth", "1", "--quiet", "--", base, tmp)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			cleanup()
+			return "", noop, fmt.Errorf("git clone: %w: %s", err, strings.TrimSpace(string(out)))
+		}
+	case SourceTarball:
+		if err := extractTarball(ctx, base, tmp); err != nil {
+			cleanup()
+			return "", noop, fmt.Errorf("extractTarball: %w", err)
+		}
+	default:
+		cleanup()
+		return "", noop, fmt.Errorf("unknown source type %q", kind)
+	}
+
+	root := filepath.Join(tmp, filepath.FromSlash(subdir))
+	if !isWithin(tmp, root) {
+		cleanup()
+		return "", noop, fmt.Errorf("subdir %q escapes the source", subdir)
+	}
+	return root, cleanup, nil
+}
+
+func extractTarball(ctx context.Context, source, dest string) error {
+	var r io.Reader
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+		if err != nil {
+			return fmt.Errorf("http.NewRequestWithContext: %w", err)
+		}
+		client := &http.Client{Timeout: downloadTimeout}
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("client.Do: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("download %s: status %d", source, resp.StatusCode)
+		}
+		// * one byte past the limit tells a full download from an oversized one
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxTarballSize+1))
+		if err != nil {
+			return fmt.Errorf("io.ReadAll: %w", err)
+		}
+		if int64(len(data)) > maxTarballSize {
+			return fmt.Errorf("download %s: larger than %d bytes", source, maxTarballSize)
+		}
+		r = bytes.NewReader(data)
+	} else {
+		f, err := os.Open(source)
+		if err != nil {
+			return fmt.Errorf("os.Open: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("gzip.NewReader: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	var total int64
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("tr.Next: %w", err)
+		}
+
+		path := filepath.Join(dest, filepath.FromSlash(header.Name))
+		if !isWithin(dest, path) {
+			return fmt.Errorf("entry %q escapes the archive", header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return fmt.Errorf("os.MkdirAll: %w", err)
+			}
+		case tar.TypeReg:
+			// * a small archive can still unpack into a huge tree
+			total += header.Size
+			if total > maxExtractSize {
+				return fmt.Errorf("archive unpacks to more than %d bytes", maxExtractSize)
+			}
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return fmt.Errorf("os.MkdirAll: %w", err)
+			}
+			if err := writeFile(path, tr, fs.FileMode(header.Mode).Perm()); err != nil {
+				return err
+			}
+		default:
+			// * links and devices are never needed by a skill
+			continue
+		}
+	}
+}
+
+// * SKILL.md at the root, or inside the only top-level folder as tarballs usually wrap one
+func findSkillRoot(root string) (string, error) {
+	if _, err := os.Stat(filepath.Join(root, "SKILL.md")); err == nil {
+		return root, nil
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return "", fmt.Errorf("os.ReadDir: %w", err)
+	}
+	var dirs []string
+	for _, e := range entries {
+		if e.IsDir() && e.Name()[0] != '.' {
+			dirs = append(dirs, e.Name())
+		}
+	}
+	if len(dirs) == 1 {
+		if _, err := os.Stat(filepath.Join(root, dirs[0], "SKILL.md")); err == nil {
+			return filepath.Join(root, dirs[0]), nil
+		}
+	}
+	return "", fmt.Errorf("SKILL.md not found in %s", root)
+}
+
+func copyDir(src, dest string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+
+		target := filepath.Join(dest, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return writeFile(target, f, info.Mode().Perm())
+	})
+}
+
+func writeFile(path string, r io.Reader, perm fs.FileMode) error {
+	if perm == 0 {
+		perm = 0644
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return fmt.Errorf("os.OpenFile: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		return fmt.Errorf("io.Copy: %w", err)
+	}
+	return nil
+}
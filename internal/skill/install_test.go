@@ -0,0 +1,239 @@
+package skill
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTarget(t *testing.T) TargetData {
+	t.Helper()
+	dir := t.TempDir()
+	return TargetData{
+		Scope: ScopeUser,
+		Dir:   filepath.Join(dir, "skills"),
+		Lock:  filepath.Join(dir, lockFile),
+	}
+}
+
+func TestInstall_Path(t *testing.T) {
+	ctx := context.Background()
+	src := t.TempDir()
+	writeSkill(t, src, "alpha", "first")
+	os.MkdirAll(filepath.Join(src, "alpha", "scripts"), 0755)
+	os.WriteFile(filepath.Join(src, "alpha", "scripts", "run.sh"), []byte("echo hi"), 0755)
+	target := newTarget(t)
+
+	s, err := Install(ctx, filepath.Join(src, "alpha"), target, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(target.Dir, "alpha", "scripts", "run.sh")); err != nil {
+		t.Errorf("scripts not copied: %v", err)
+	}
+	lock, _ := ReadLock(target.Lock)
+	if entry := lock.Skills["alpha"]; entry.Hash != s.Hash || entry.Type != SourcePath {
+		t.Errorf("lock entry = %+v, want hash %s", entry, s.Hash)
+	}
+
+	if _, err := Install(ctx, filepath.Join(src, "alpha"), target, false); err == nil {
+		t.Error("second install without force should fail")
+	}
+
+	if _, changed, err := Update(ctx, "alpha", target, false); err != nil || changed {
+		t.Errorf("Update unchanged = %v, %v", changed, err)
+	}
+
+	writeSkill(t, src, "alpha", "second")
+	s, changed, err := Update(ctx, "alpha", target, false)
+	if err != nil || !changed || s.Description != "second" {
+		t.Errorf("Update = %+v, %v, %v", s, changed, err)
+	}
+
+	// * local edits are kept unless forced
+	os.WriteFile(filepath.Join(target.Dir, "alpha", "SKILL.md"), []byte("edited"), 0644)
+	if problems := lock.Verify(target.Dir); problems["alpha"] == "" {
+		t.Error("Verify should report the edit")
+	}
+	if _, _, err := Update(ctx, "alpha", target, false); err == nil {
+		t.Error("Update over local edits should fail")
+	}
+	if _, _, err := Update(ctx, "alpha", target, true); err != nil {
+		t.Errorf("forced Update: %v", err)
+	}
+
+	if err := Remove("alpha", target); err != nil {
+		t.Fatal(err)
+	}
+	lock, _ = ReadLock(target.Lock)
+	if _, ok := lock.Skills["alpha"]; ok {
+		t.Error("lock entry not removed")
+	}
+	if _, err := os.Stat(filepath.Join(target.Dir, "alpha")); !os.IsNotExist(err) {
+		t.Error("skill folder not removed")
+	}
+}
+
+func TestInstall_Restore(t *testing.T) {
+	ctx := context.Background()
+	src := t.TempDir()
+	writeSkill(t, src, "alpha", "first")
+	target := newTarget(t)
+	if _, err := Install(ctx, filepath.Join(src, "alpha"), target, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// * an unreadable lockfile fails the forced install after the copy was swapped in
+	os.Remove(target.Lock)
+	os.MkdirAll(target.Lock, 0755)
+	writeSkill(t, src, "alpha", "second")
+	if _, err := Install(ctx, filepath.Join(src, "alpha"), target, true); err == nil {
+		t.Fatal("install with a broken lockfile should fail")
+	}
+
+	data, err := os.ReadFile(filepath.Join(target.Dir, "alpha", "SKILL.md"))
+	if err != nil || !strings.Contains(string(data), "first") {
+		t.Errorf("SKILL.md = %q, %v, want the previous copy", data, err)
+	}
+	entries, _ := os.ReadDir(target.Dir)
+	if len(entries) != 1 {
+		t.Errorf("entries = %d, want no leftover copies", len(entries))
+	}
+}
+
+func writeTarball(t *testing.T, files map[string]string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+
+	path := filepath.Join(t.TempDir(), "skill.tar.gz")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestInstall_Tarball(t *testing.T) {
+	target := newTarget(t)
+	tarball := writeTarball(t, map[string]string{
+		"beta-1.0/SKILL.md":         "---\nname: beta\ndescription: packed\n---\nbody",
+		"beta-1.0/templates/out.md": "template",
+	})
+
+	s, err := Install(context.Background(), tarball, target, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "beta" || s.Path != filepath.Join(target.Dir, "beta") {
+		t.Errorf("installed = %s at %s", s.Name, s.Path)
+	}
+
+	evil := writeTarball(t, map[string]string{"../evil/SKILL.md": "x"})
+	if _, err := Install(context.Background(), evil, target, false); err == nil || !strings.Contains(err.Error(), "escapes") {
+		t.Errorf("traversal error = %v", err)
+	}
+
+	// * downloads and unpacked archives are both capped
+	large := writeTarball(t, map[string]string{
+		"gamma/SKILL.md": "---\nname: gamma\n---\n" + strings.Repeat("x", 4096),
+	})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, large)
+	}))
+	defer srv.Close()
+
+	size, extract := maxTarballSize, maxExtractSize
+	defer func() { maxTarballSize, maxExtractSize = size, extract }()
+
+	maxTarballSize = 16
+	if _, err := Install(context.Background(), srv.URL+"/gamma.tar.gz", target, false); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("download limit error = %v", err)
+	}
+	maxTarballSize, maxExtractSize = size, 1024
+	if _, err := Install(context.Background(), srv.URL+"/gamma.tar.gz", target, false); err == nil || !strings.Contains(err.Error(), "unpacks") {
+		t.Errorf("extract limit error = %v", err)
+	}
+	maxExtractSize = extract
+	if _, err := Install(context.Background(), srv.URL+"/gamma.tar.gz", target, false); err != nil {
+		t.Errorf("download install: %v", err)
+	}
+}
+
+func TestInstall_Git(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	writeSkill(t, repo, "gamma", "from git")
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"-c", "user.name=t", "-c", "user.email=t@t", "commit", "-q", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v %s", args, err, out)
+		}
+	}
+
+	target := newTarget(t)
+	s, err := Install(context.Background(), "file://"+repo+"#gamma", target, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Description != "from git" {
+		t.Errorf("Description = %q", s.Description)
+	}
+	if _, err := os.Stat(filepath.Join(target.Dir, "gamma", ".git")); !os.IsNotExist(err) {
+		t.Error(".git should not be copied")
+	}
+
+	// * after -- git reports the source as the missing repository instead of taking it as an option
+	marker := filepath.Join(t.TempDir(), "pwned")
+	source := "--upload-pack=touch " + marker + ";x.git"
+	if _, err := Install(context.Background(), source, target, false); err == nil || !strings.Contains(err.Error(), "'"+source+"'") {
+		t.Errorf("Install() of an option-like source error = %v", err)
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Error("source was run as a git option")
+	}
+}
+
+func TestNew(t *testing.T) {
+	target := newTarget(t)
+	dest, err := New("delta", target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range scaffoldDirs {
+		if info, err := os.Stat(filepath.Join(dest, dir)); err != nil || !info.IsDir() {
+			t.Errorf("%s not created", dir)
+		}
+	}
+	s, err := parser(filepath.Join(dest, "SKILL.md"))
+	if err != nil || s.Name != "delta" {
+		t.Errorf("scaffold = %+v, %v", s, err)
+	}
+	if _, err := New("delta", target); err == nil {
+		t.Error("New over existing skill should fail")
+	}
+	if _, err := New("../x", target); err == nil {
+		t.Error("New with path name should fail")
+	}
+}
@@ -0,0 +1,128 @@
+package skill
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pardnchiu/agenvoy/internal/utils"
+)
+
+const (
+	lockFile = "skills.lock.json"
+
+	SourcePath    = "path"
+	SourceGit     = "git"
+	SourceTarball = "tarball"
+)
+
+// * where installed skills live and where their lockfile is kept
+type TargetData struct {
+	Scope string
+	Dir   string
+	Lock  string
+}
+
+type LockEntry struct {
+	Source      string `json:"source"`
+	Type        string `json:"type"`
+	Hash        string `json:"hash"`
+	Version     string `json:"version,omitempty"`
+	InstalledAt int64  `json:"installed_at"`
+}
+
+type LockData struct {
+	Skills map[string]LockEntry `json:"skills"`
+}
+
+func GetTarget(project bool) (TargetData, error) {
+	configDir, err := utils.GetConfigDir()
+	if err != nil {
+		return TargetData{}, fmt.Errorf("utils.GetConfigDir: %w", err)
+	}
+
+	if project {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return TargetData{}, fmt.Errorf("os.Getwd: %w", err)
+		}
+		return TargetData{
+			Scope: ScopeProject,
+			Dir:   filepath.Join(cwd, ".claude", "skills"),
+			Lock:  filepath.Join(configDir.Work, lockFile),
+		}, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return TargetData{}, fmt.Errorf("os.UserHomeDir: %w", err)
+	}
+	return TargetData{
+		Scope: ScopeUser,
+		Dir:   filepath.Join(home, ".claude", "skills"),
+		Lock:  filepath.Join(configDir.Home, lockFile),
+	}, nil
+}
+
+func ReadLock(path string) (*LockData, error) {
+	lock := &LockData{Skills: make(map[string]LockEntry)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return lock, nil
+		}
+		return nil, fmt.Errorf("os.ReadFile: %w", err)
+	}
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+	if lock.Skills == nil {
+		lock.Skills = make(map[string]LockEntry)
+	}
+	return lock, nil
+}
+
+func (l *LockData) write(path string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("json.MarshalIndent: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("os.WriteFile: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("os.Rename: %w", err)
+	}
+	return nil
+}
+
+// Verify compares every locked skill with the SKILL.md on disk and returns name to problem.
+func (l *LockData) Verify(dir string) map[string]string {
+	problems := make(map[string]string)
+	for name, entry := range l.Skills {
+		skill, err := parser(filepath.Join(dir, name, "SKILL.md"))
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			problems[name] = "missing"
+		case err != nil:
+			problems[name] = err.Error()
+		case skill.Hash != entry.Hash:
+			problems[name] = "modified since install"
+		}
+	}
+	return problems
+}
+
+func (l *LockData) Names() []string {
+	names := make([]string, 0, len(l.Skills))
+	for name := range l.Skills {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -7,19 +7,81 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 // ---
 // name: changelog-generate
 // description: 從 git diff 輸出生成結構化的 update.md 更新日誌，並自動進行語意化版本控制。當使用者請求生成更新日誌、發布說明，或基於未提交的 git 變更更新文件時使用。
+// allowed-tools: read_file, run_command
+// model: claude@claude-sonnet-4-5
+// version: 1.2.0
+// tags: [git, changelog]
+// arguments: [{name: since, description: 起始 tag, required: true}]
+// scripts: [{name: bump, description: 更新版本號, run: scripts/bump.sh, timeout: 30s, arguments: [{name: level, enum: [major, minor, patch]}]}]
 // ---
 var (
-	headerRegex = regexp.MustCompile(`(?s)^---\n(.*?)\n---\n?(.*)$`)
-	nameRegex   = regexp.MustCompile(`(?m)^name:\s*(.+)$`)
-	descRegex   = regexp.MustCompile(`(?m)^description:\s*(.+)$`)
+	headerRegex = regexp.MustCompile(`(?s)^---\r?\n(.*?)\r?\n---(?:\r?\n)?(.*)$`)
+	lineRegex   = regexp.MustCompile(`line (\d+)`)
+	// * becomes part of a tool name, keep it within what every provider accepts
+	scriptNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,48}$`)
 )
 
+var argumentTypes = map[string]bool{
+	"":        true,
+	"string":  true,
+	"number":  true,
+	"boolean": true,
+}
+
+type frontmatter struct {
+	Name         string      `yaml:"name"`
+	Description  string      `yaml:"description"`
+	AllowedTools stringList  `yaml:"allowed-tools"`
+	Model        string      `yaml:"model"`
+	Arguments    []yaml.Node `yaml:"arguments"`
+	Version      string      `yaml:"version"`
+	Tags         stringList  `yaml:"tags"`
+	Scripts      []yaml.Node `yaml:"scripts"`
+	Params       Params      `yaml:"params"`
+}
+
+type scriptHeader struct {
+	Name        string      `yaml:"name"`
+	Description string      `yaml:"description"`
+	Run         string      `yaml:"run"`
+	Interpreter string      `yaml:"interpreter"`
+	Cwd         string      `yaml:"cwd"`
+	Timeout     string      `yaml:"timeout"`
+	Arguments   []yaml.Node `yaml:"arguments"`
+}
+
+// * accepts both "a, b c" and a yaml sequence
+type stringList []string
+
+func (l *stringList) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		*l = strings.FieldsFunc(node.Value, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		return nil
+	case yaml.SequenceNode:
+		var items []string
+		if err := node.Decode(&items); err != nil {
+			return err
+		}
+		*l = items
+		return nil
+	default:
+		return fmt.Errorf("line %d: expected string or list", node.Line)
+	}
+}
+
 func parser(path string) (*Skill, error) {
 	absPath, err := filepath.Abs(path)
 	if err != nil {
@@ -49,19 +111,137 @@ func parser(path string) (*Skill, error) {
 	}
 	skill.Body = body
 
-	matches := nameRegex.FindSubmatch(header)
-	if matches != nil {
-		skill.Name = strings.TrimSpace(string(matches[1]))
+	// * yaml reports lines relative to the header, shift them to the file
+	offset := bytes.Count(content[:bytes.Index(content, header)], []byte("\n"))
+
+	var fm frontmatter
+	// * a skill whose header cannot be read is skipped, running it without allowed-tools would widen its access
+	if err := yaml.Unmarshal(header, &fm); err != nil {
+		return nil, headerError(err.Error(), offset)
 	}
 
-	matches = descRegex.FindSubmatch(header)
-	if matches != nil {
-		skill.Description = strings.TrimSpace(string(matches[1]))
+	if name := strings.TrimSpace(fm.Name); name != "" {
+		skill.Name = name
+	}
+	skill.Description = strings.TrimSpace(fm.Description)
+	skill.AllowedTools = fm.AllowedTools
+	skill.Model = strings.TrimSpace(fm.Model)
+	skill.Version = strings.TrimSpace(fm.Version)
+	skill.Tags = fm.Tags
+	skill.Params = fm.Params
+
+	if skill.Model != "" && !strings.Contains(skill.Model, "@") {
+		return nil, headerError(fmt.Sprintf("line %d: model must be provider@model", findLine(header, "model")), offset)
+	}
+
+	skill.Arguments, err = parseArguments(fm.Arguments, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(fm.Scripts))
+	for _, node := range fm.Scripts {
+		script, err := parseScript(node, filepath.Dir(absPath), offset)
+		if err != nil {
+			return nil, err
+		}
+		if seen[script.Name] {
+			return nil, headerError(fmt.Sprintf("line %d: duplicate script %q", node.Line, script.Name), offset)
+		}
+		seen[script.Name] = true
+		skill.Scripts = append(skill.Scripts, script)
 	}
 
 	return skill, nil
 }
 
+func parseArguments(nodes []yaml.Node, offset int) ([]Argument, error) {
+	var args []Argument
+	seen := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		var arg Argument
+		if err := node.Decode(&arg); err != nil {
+			return nil, headerError(err.Error(), offset)
+		}
+		arg.Name = strings.TrimSpace(arg.Name)
+
+		switch {
+		case arg.Name == "":
+			return nil, headerError(fmt.Sprintf("line %d: argument name is required", node.Line), offset)
+		case seen[arg.Name]:
+			return nil, headerError(fmt.Sprintf("line %d: duplicate argument %q", node.Line, arg.Name), offset)
+		case !argumentTypes[arg.Type]:
+			return nil, headerError(fmt.Sprintf("line %d: unknown argument type %q", node.Line, arg.Type), offset)
+		}
+		if arg.Default != "" {
+			if err := arg.Check(arg.Default); err != nil {
+				return nil, headerError(fmt.Sprintf("line %d: default: %s", node.Line, err.Error()), offset)
+			}
+		}
+		seen[arg.Name] = true
+		args = append(args, arg)
+	}
+	return args, nil
+}
+
+func parseScript(node yaml.Node, skillDir string, offset int) (Script, error) {
+	var header scriptHeader
+	if err := node.Decode(&header); err != nil {
+		return Script{}, headerError(err.Error(), offset)
+	}
+
+	script := Script{
+		Name:        strings.TrimSpace(header.Name),
+		Description: strings.TrimSpace(header.Description),
+		Interpreter: strings.TrimSpace(header.Interpreter),
+		Root:        skillDir,
+		Cwd:         strings.TrimSpace(header.Cwd),
+		Timeout:     DefaultScriptTimeout,
+	}
+	fail := func(msg string) (Script, error) {
+		return Script{}, headerError(fmt.Sprintf("line %d: script %s", node.Line, msg), offset)
+	}
+
+	if !scriptNameRegex.MatchString(script.Name) {
+		return fail(fmt.Sprintf("name %q must be 1-48 letters, digits, _ or -", script.Name))
+	}
+
+	run := strings.TrimSpace(header.Run)
+	if run == "" {
+		return fail(script.Name + ": run is required")
+	}
+	script.Path = filepath.Join(skillDir, filepath.FromSlash(run))
+	if !isWithin(skillDir, script.Path) {
+		return fail(fmt.Sprintf("%s: %s is outside the skill folder", script.Name, run))
+	}
+	if info, err := os.Stat(script.Path); err != nil || info.IsDir() {
+		return fail(fmt.Sprintf("%s: %s not found", script.Name, run))
+	}
+
+	switch script.Cwd {
+	case "":
+		script.Cwd = ScriptCwdWork
+	case ScriptCwdWork, ScriptCwdSkill:
+	default:
+		return fail(fmt.Sprintf("%s: cwd must be %s or %s", script.Name, ScriptCwdWork, ScriptCwdSkill))
+	}
+
+	if t := strings.TrimSpace(header.Timeout); t != "" {
+		timeout, err := time.ParseDuration(t)
+		if err != nil || timeout <= 0 || timeout > MaxScriptTimeout {
+			return fail(fmt.Sprintf("%s: timeout %q must be a duration up to %s", script.Name, t, MaxScriptTimeout))
+		}
+		script.Timeout = timeout
+	}
+
+	args, err := parseArguments(header.Arguments, offset)
+	if err != nil {
+		return Script{}, err
+	}
+	script.Arguments = args
+	return script, nil
+}
+
 func extractHeader(content []byte) ([]byte, string, error) {
 	matches := headerRegex.FindSubmatch(content)
 	if matches == nil {
@@ -73,3 +253,21 @@ func extractHeader(content []byte) ([]byte, string, error) {
 
 	return frontmatter, body, nil
 }
+
+func headerError(msg string, offset int) error {
+	msg = strings.TrimPrefix(msg, "yaml: ")
+	msg = lineRegex.ReplaceAllStringFunc(msg, func(m string) string {
+		n, _ := strconv.Atoi(strings.TrimPrefix(m, "line "))
+		return fmt.Sprintf("line %d", n+offset)
+	})
+	return fmt.Errorf("frontmatter: %s", msg)
+}
+
+func findLine(header []byte, key string) int {
+	for i, line := range strings.Split(string(header), "\n") {
+		if strings.HasPrefix(line, key+":") {
+			return i + 1
+		}
+	}
+	return 1
+}
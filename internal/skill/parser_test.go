@@ -3,6 +3,7 @@ package skill
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -251,3 +252,153 @@ func TestParser(t *testing.T) {
 		}
 	})
 }
+
+func TestParser_Frontmatter(t *testing.T) {
+	write := func(t *testing.T, content string) string {
+		t.Helper()
+		skillDir := filepath.Join(t.TempDir(), "fm-skill")
+		os.MkdirAll(skillDir, 0755)
+		path := filepath.Join(skillDir, "SKILL.md")
+		os.WriteFile(path, []byte(content), 0644)
+		return path
+	}
+
+	t.Run("full frontmatter", func(t *testing.T) {
+		path := write(t, `---
+name: changelog
+description: >
+  Generate a changelog
+  from git diff
+allowed-tools: read_file, run_command
+model: claude@claude-sonnet-4-5
+version: 1.2
+tags: [git, release]
+arguments:
+  - name: since
+    required: true
+  - name: format
+    enum: [md, json]
+    default: md
+params:
+  temperature: 0
+  max_tokens: 4096
+  stop: ["---"]
+---
+body`)
+		skill, err := parser(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if skill.Description != "Generate a changelog from git diff" {
+			t.Errorf("Description = %q", skill.Description)
+		}
+		if len(skill.AllowedTools) != 2 || skill.AllowedTools[1] != "run_command" {
+			t.Errorf("AllowedTools = %v", skill.AllowedTools)
+		}
+		if skill.Model != "claude@claude-sonnet-4-5" || skill.Version != "1.2" {
+			t.Errorf("Model = %q, Version = %q", skill.Model, skill.Version)
+		}
+		if len(skill.Tags) != 2 || len(skill.Arguments) != 2 {
+			t.Errorf("Tags = %v, Arguments = %+v", skill.Tags, skill.Arguments)
+		}
+		if p := skill.Params; p.Temperature == nil || *p.Temperature != 0 || p.MaxTokens != 4096 || len(p.Stop) != 1 {
+			t.Errorf("Params = %+v", p)
+		}
+	})
+
+	t.Run("malformed yaml reports file line", func(t *testing.T) {
+		path := write(t, "---\nname: loose\ndescription: Use when: the user asks\nallowed-tools: read_file\n---\nbody")
+		skill, err := parser(path)
+		if err == nil || skill != nil || !strings.Contains(err.Error(), "line 3") {
+			t.Errorf("parser() = %v, %v, want a line 3 error and no skill", skill, err)
+		}
+	})
+
+	t.Run("crlf line endings", func(t *testing.T) {
+		path := write(t, "---\r\nname: windows\r\ndescription: saved on windows\r\nallowed-tools: read_file\r\n---\r\nbody\r\n")
+		skill, err := parser(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if skill.Name != "windows" || skill.Description != "saved on windows" || len(skill.AllowedTools) != 1 || skill.Body != "body" {
+			t.Errorf("skill = %+v", skill)
+		}
+	})
+
+	t.Run("invalid argument reports file line", func(t *testing.T) {
+		path := write(t, "---\nname: bad\narguments:\n  - name: n\n    type: int\n---\nbody")
+		_, err := parser(path)
+		if err == nil || !strings.Contains(err.Error(), "line 4") {
+			t.Errorf("error = %v, want line 4", err)
+		}
+	})
+}
+
+func TestParser_Scripts(t *testing.T) {
+	write := func(t *testing.T, scripts string) string {
+		t.Helper()
+		skillDir := filepath.Join(t.TempDir(), "script-skill")
+		os.MkdirAll(filepath.Join(skillDir, "scripts"), 0755)
+		os.WriteFile(filepath.Join(skillDir, "scripts", "bump.sh"), []byte("echo"), 0644)
+		path := filepath.Join(skillDir, "SKILL.md")
+		os.WriteFile(path, []byte("---\nname: s\nscripts:\n"+scripts+"---\nbody"), 0644)
+		return path
+	}
+
+	path := write(t, "  - name: bump\n    run: scripts/bump.sh\n    cwd: skill\n    timeout: 30s\n    arguments: [{name: level, enum: [major, minor]}]\n")
+	skill, err := parser(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sc := skill.Scripts[0]
+	if sc.Path != filepath.Join(filepath.Dir(path), "scripts", "bump.sh") || sc.Cwd != ScriptCwdSkill || sc.Timeout.String() != "30s" || len(sc.Arguments) != 1 {
+		t.Errorf("script = %+v", sc)
+	}
+
+	for name, scripts := range map[string]string{
+		"outside the skill folder": "  - name: x\n    run: ../../etc/passwd\n",
+		"not found":                "  - name: x\n    run: scripts/missing.sh\n",
+		"must be 1-48":             "  - name: bad name\n    run: scripts/bump.sh\n",
+		"must be a duration":       "  - name: x\n    run: scripts/bump.sh\n    timeout: forever\n",
+		"duplicate script":         "  - name: x\n    run: scripts/bump.sh\n  - name: x\n    run: scripts/bump.sh\n",
+	} {
+		if _, err := parser(write(t, scripts)); err == nil || !strings.Contains(err.Error(), name) {
+			t.Errorf("error = %v, want %q", err, name)
+		}
+	}
+}
+
+// ---------- ResolveArgs ----------
+
+func TestResolveArgs(t *testing.T) {
+	s := &Skill{
+		Name: "demo",
+		Arguments: []Argument{
+			{Name: "since", Required: true},
+			{Name: "limit", Type: "number", Default: "10"},
+			{Name: "format", Enum: []string{"md", "json"}},
+		},
+	}
+
+	got, err := s.ResolveArgs(map[string]string{"since": "v1.0.0"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got["since"] != "v1.0.0" || got["limit"] != "10" {
+		t.Errorf("ResolveArgs() = %v", got)
+	}
+	if _, ok := got["format"]; ok {
+		t.Error("optional argument without default should be omitted")
+	}
+
+	for _, values := range []map[string]string{
+		{},
+		{"since": "v1", "limit": "ten"},
+		{"since": "v1", "format": "html"},
+		{"since": "v1", "unknown": "x"},
+	} {
+		if _, err := s.ResolveArgs(values); err == nil {
+			t.Errorf("ResolveArgs(%v) should fail", values)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package skill
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// * folders getSystemPrompt rewrites to absolute paths
+var scaffoldDirs = []string{"scripts", "templates", "assets"}
+
+const scaffoldTemplate = `---
+name: {{.Name}}
+description: 一句話說明此 Skill 的用途，以及使用者在什麼情況下會需要它
+version: 0.1.0
+---
+
+## 步驟
+
+1. 說明要執行的步驟
+2. 需要時引用 scripts/、templates/ 或 assets/ 內的檔案
+`
+
+// New scaffolds an empty skill folder with SKILL.md and the resource folders.
+func New(name string, target TargetData) (string, error) {
+	if err := checkName(name); err != nil {
+		return "", err
+	}
+
+	dest := filepath.Join(target.Dir, name)
+	if _, err := os.Stat(dest); err == nil {
+		return "", fmt.Errorf("skill %s already exists at %s", name, dest)
+	}
+
+	for _, dir := range scaffoldDirs {
+		if err := os.MkdirAll(filepath.Join(dest, dir), 0755); err != nil {
+			return "", fmt.Errorf("os.MkdirAll: %w", err)
+		}
+	}
+
+	content := strings.ReplaceAll(scaffoldTemplate, "{{.Name}}", name)
+	if err := os.WriteFile(filepath.Join(dest, "SKILL.md"), []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("os.WriteFile: %w", err)
+	}
+	return dest, nil
+}
@@ -5,8 +5,10 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
 type Scanner struct {
@@ -16,65 +18,101 @@ type Scanner struct {
 }
 
 type Skill struct {
+	Name         string
+	Description  string
+	AbsPath      string
+	Path         string
+	Content      string
+	Body         string
+	Hash         string
+	AllowedTools []string
+	Model        string
+	Arguments    []Argument
+	Version      string
+	Tags         []string
+	Scope        string
+	Scripts      []Script
+	Params       Params
+	Parts        []*Skill // set when composed from several skills, in order
+}
+
+const (
+	ScriptCwdWork  = "work"
+	ScriptCwdSkill = "skill"
+
+	DefaultScriptTimeout = 60 * time.Second
+	MaxScriptTimeout     = 10 * time.Minute
+)
+
+// * declared in frontmatter, exposed as a typed tool while the skill runs
+type Script struct {
 	Name        string
 	Description string
-	AbsPath     string
-	Path        string
-	Content     string
-	Body        string
-	Hash        string
+	Path        string // absolute, always inside Root
+	Root        string // folder of the SKILL.md declaring it
+	Interpreter string
+	Cwd         string // ScriptCwdWork or ScriptCwdSkill
+	Timeout     time.Duration
+	Arguments   []Argument
+}
+
+// * generation params applied over the model entry while the skill runs
+type Params struct {
+	Temperature *float64 `yaml:"temperature"`
+	TopP        *float64 `yaml:"top_p"`
+	MaxTokens   int      `yaml:"max_tokens"`
+	Seed        *int     `yaml:"seed"`
+	Stop        []string `yaml:"stop"`
+
+	ReasoningEffort string `yaml:"reasoning_effort"`
+	ReasoningBudget int    `yaml:"reasoning_budget"`
+}
+
+type Argument struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	Type        string   `yaml:"type"`
+	Required    bool     `yaml:"required"`
+	Default     string   `yaml:"default"`
+	Enum        []string `yaml:"enum"`
 }
 
 type SkillList struct {
 	ByName map[string]*Skill
 	ByPath map[string]*Skill
-	Paths  []string
+	// * every skill under "scope:name", shadowed ones included
+	Scoped   map[string]*Skill
+	Shadowed []*Skill
+	Invalid  map[string]string
+	Paths    []string
 }
 
 func NewScanner() *Scanner {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		home = "."
-	}
-
-	cwd, _ := os.Getwd()
-	paths := []string{
-		filepath.Join(cwd, ".claude", "skills"),
-		filepath.Join(cwd, ".skills"),
-		filepath.Join(home, ".claude", "skills"),
-		filepath.Join(home, ".opencode", "skills"),
-		filepath.Join(home, ".openai", "skills"),
-		filepath.Join(home, ".codex", "skills"),
-		"/mnt/skills/public",
-		"/mnt/skills/user",
-		"/mnt/skills/examples",
-	}
-
 	scanner := &Scanner{
-		paths: paths,
+		paths: getSkillPaths(),
 	}
 	scanner.Scan()
 
 	return scanner
 }
 
-func (s *Scanner) Scan() {
-	list := &SkillList{
-		ByName: make(map[string]*Skill),
-		ByPath: make(map[string]*Skill),
-		Paths:  s.paths,
-	}
+type scanResult struct {
+	skill *Skill
+	path  string
+	err   error
+}
 
+func (s *Scanner) Scan() {
 	// * concurrent scan path list
 	var wg sync.WaitGroup
-	skillChan := make(chan *Skill, 100)
+	resultChan := make(chan scanResult, 100)
 	errChan := make(chan error, len(s.paths))
 	for _, path := range s.paths {
 		wg.Add(1)
 
 		go func(dir string) {
 			defer wg.Done()
-			if err := s.scan(dir, skillChan); err != nil {
+			if err := s.scan(dir, resultChan); err != nil {
 				errChan <- fmt.Errorf("s.scan %s: %w", dir, err)
 			}
 		}(path)
@@ -82,16 +120,18 @@ func (s *Scanner) Scan() {
 
 	go func() {
 		wg.Wait()
-		close(skillChan)
+		close(resultChan)
 		close(errChan)
 	}()
 
-	for skill := range skillChan {
-		if _, ok := list.ByName[skill.Name]; ok {
+	byPath := make(map[string]*Skill)
+	invalid := make(map[string]string)
+	for result := range resultChan {
+		if result.err != nil {
+			invalid[result.path] = result.err.Error()
 			continue
 		}
-		list.ByName[skill.Name] = skill
-		list.ByPath[skill.AbsPath] = skill
+		byPath[result.skill.AbsPath] = result.skill
 	}
 
 	var errs []error
@@ -101,12 +141,37 @@ func (s *Scanner) Scan() {
 			slog.String("error", err.Error()))
 	}
 
+	list := newSkillList(s.paths, byPath, invalid)
+
 	s.mu.Lock()
 	s.Skills = list
 	s.mu.Unlock()
 }
 
-func (s *Scanner) scan(root string, skillChan chan<- *Skill) error {
+// * goroutine order is random, precedence comes from the path order only
+func newSkillList(paths []string, byPath map[string]*Skill, invalid map[string]string) *SkillList {
+	list := &SkillList{
+		ByName:  make(map[string]*Skill, len(byPath)),
+		ByPath:  byPath,
+		Scoped:  make(map[string]*Skill, len(byPath)),
+		Invalid: invalid,
+		Paths:   paths,
+	}
+	for _, skill := range sortByPath(paths, byPath) {
+		key := skill.Scope + ":" + skill.Name
+		if _, ok := list.Scoped[key]; !ok {
+			list.Scoped[key] = skill
+		}
+		if _, ok := list.ByName[skill.Name]; ok {
+			list.Shadowed = append(list.Shadowed, skill)
+			continue
+		}
+		list.ByName[skill.Name] = skill
+	}
+	return list
+}
+
+func (s *Scanner) scan(root string, resultChan chan<- scanResult) error {
 	// * path not exists
 	if _, err := os.Stat(root); os.IsNotExist(err) {
 		return nil
@@ -135,18 +200,66 @@ func (s *Scanner) scan(root string, skillChan chan<- *Skill) error {
 			slog.Warn("failed to parse skill",
 				slog.String("path", path),
 				slog.String("error", err.Error()))
+			resultChan <- scanResult{path: path, err: err}
 			continue
 		}
-		skillChan <- skill
+		skill.Scope = ScopeOf(root)
+		resultChan <- scanResult{skill: skill, path: path}
 	}
 
 	return nil
 }
 
+// Get looks up a skill by name or by "scope:name", safe for concurrent use.
+func (s *Scanner) Get(name string) (*Skill, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if skill, ok := s.Skills.ByName[name]; ok {
+		return skill, ok
+	}
+	skill, ok := s.Skills.Scoped[name]
+	return skill, ok
+}
+
+// Snapshot returns the current list, it is never mutated once a reload swaps it.
+func (s *Scanner) Snapshot() *SkillList {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Skills
+}
+
 func (s *Scanner) List() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	names := make([]string, 0, len(s.Skills.ByName))
 	for name := range s.Skills.ByName {
 		names = append(names, strings.TrimSpace(name))
 	}
 	return names
 }
+
+// * earlier scanner path wins, then folder order inside the same path
+func sortByPath(paths []string, byPath map[string]*Skill) []*Skill {
+	rank := make(map[string]int, len(paths))
+	for i, p := range paths {
+		if abs, err := filepath.Abs(p); err == nil {
+			rank[abs] = i
+		}
+	}
+
+	skills := make([]*Skill, 0, len(byPath))
+	for _, skill := range byPath {
+		skills = append(skills, skill)
+	}
+	sort.Slice(skills, func(i, j int) bool {
+		ri := rank[filepath.Dir(filepath.Dir(skills[i].AbsPath))]
+		rj := rank[filepath.Dir(filepath.Dir(skills[j].AbsPath))]
+		if ri != rj {
+			return ri < rj
+		}
+		return skills[i].AbsPath < skills[j].AbsPath
+	})
+	return skills
+}
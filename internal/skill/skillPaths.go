@@ -0,0 +1,148 @@
+package skill
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pardnchiu/agenvoy/internal/utils"
+)
+
+const (
+	ScopeProject = "project"
+	ScopeUser    = "user"
+	ScopeSystem  = "system"
+)
+
+var scopeRank = map[string]int{
+	ScopeProject: 0,
+	ScopeUser:    1,
+	ScopeSystem:  2,
+}
+
+type SkillConfigData struct {
+	Paths    []string `json:"paths"`
+	Defaults *bool    `json:"defaults"`
+}
+
+func defaultPaths(cwd, home string) []string {
+	return []string{
+		filepath.Join(cwd, ".claude", "skills"),
+		filepath.Join(cwd, ".skills"),
+		filepath.Join(home, ".claude", "skills"),
+		filepath.Join(home, ".opencode", "skills"),
+		filepath.Join(home, ".openai", "skills"),
+		filepath.Join(home, ".codex", "skills"),
+		"/mnt/skills/public",
+		"/mnt/skills/user",
+		"/mnt/skills/examples",
+	}
+}
+
+// * AGENVOY_SKILL_PATHS, then config.json, then built-in paths, stable sorted by scope
+func getSkillPaths() []string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
+	}
+	cwd, _ := os.Getwd()
+
+	var paths []string
+	if env := strings.TrimSpace(os.Getenv("AGENVOY_SKILL_PATHS")); env != "" {
+		paths = append(paths, filepath.SplitList(env)...)
+	}
+
+	cfg := getSkillConfig()
+	paths = append(paths, cfg.Paths...)
+	if cfg.Defaults == nil || *cfg.Defaults {
+		paths = append(paths, defaultPaths(cwd, home)...)
+	}
+
+	seen := make(map[string]bool, len(paths))
+	result := make([]string, 0, len(paths))
+	for _, p := range paths {
+		p = expandPath(p, cwd, home)
+		if p == "" || seen[p] {
+			continue
+		}
+		seen[p] = true
+		result = append(result, p)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return scopeRank[ScopeOf(result[i])] < scopeRank[ScopeOf(result[j])]
+	})
+	return result
+}
+
+// * paths from both config dirs are merged, the work dir decides defaults last
+func getSkillConfig() SkillConfigData {
+	var result SkillConfigData
+
+	configDir, err := utils.GetConfigDir()
+	if err != nil {
+		return result
+	}
+
+	for _, dir := range configDir.Dirs {
+		data, err := os.ReadFile(filepath.Join(dir, "config.json"))
+		if err != nil {
+			continue
+		}
+		var cfg struct {
+			Skills *SkillConfigData `json:"skills"`
+		}
+		if json.Unmarshal(data, &cfg) != nil || cfg.Skills == nil {
+			continue
+		}
+		result.Paths = append(result.Paths, cfg.Skills.Paths...)
+		if cfg.Skills.Defaults != nil {
+			result.Defaults = cfg.Skills.Defaults
+		}
+	}
+	return result
+}
+
+func expandPath(path, cwd, home string) string {
+	path = strings.TrimSpace(path)
+	switch {
+	case path == "":
+		return ""
+	case path == "~":
+		path = home
+	case strings.HasPrefix(path, "~/"):
+		path = filepath.Join(home, path[2:])
+	case !filepath.IsAbs(path):
+		path = filepath.Join(cwd, path)
+	}
+	return filepath.Clean(path)
+}
+
+// ScopeOf reports project under the working dir, user under home and system otherwise.
+func ScopeOf(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return ScopeSystem
+	}
+	home, _ := os.UserHomeDir()
+	cwd, _ := os.Getwd()
+
+	// * running from home or / would otherwise turn every path into project
+	if cwd != "" && cwd != home && cwd != filepath.Dir(cwd) && isWithin(cwd, abs) {
+		return ScopeProject
+	}
+	if home != "" && isWithin(home, abs) {
+		return ScopeUser
+	}
+	return ScopeSystem
+}
+
+func isWithin(base, path string) bool {
+	rel, err := filepath.Rel(base, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
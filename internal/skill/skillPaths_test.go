@@ -0,0 +1,75 @@
+package skill
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewScanner_Precedence(t *testing.T) {
+	home, work, extra := t.TempDir(), t.TempDir(), t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("AGENVOY_SKILL_PATHS", extra)
+	t.Chdir(work)
+
+	writeSkill(t, extra, "changelog", "system")
+	writeSkill(t, filepath.Join(home, ".claude", "skills"), "changelog", "user")
+	writeSkill(t, filepath.Join(home, ".codex", "skills"), "changelog", "user-codex")
+	writeSkill(t, filepath.Join(work, ".claude", "skills"), "changelog", "project")
+
+	// * goroutine order must not matter
+	for range 5 {
+		s := NewScanner()
+
+		if skill, _ := s.Get("changelog"); skill.Description != "project" {
+			t.Fatalf("changelog = %q, want project", skill.Description)
+		}
+		if skill, _ := s.Get("user:changelog"); skill == nil || skill.Description != "user" {
+			t.Fatalf("user:changelog = %+v, want user", skill)
+		}
+		if skill, _ := s.Get("system:changelog"); skill == nil || skill.Description != "system" {
+			t.Fatalf("system:changelog = %+v, want system", skill)
+		}
+		if names := s.List(); len(names) != 1 {
+			t.Fatalf("List = %v, want only changelog", names)
+		}
+
+		var shadowed []string
+		for _, skill := range s.Skills.Shadowed {
+			shadowed = append(shadowed, skill.Description)
+		}
+		if len(shadowed) != 3 || shadowed[0] != "user" || shadowed[1] != "user-codex" || shadowed[2] != "system" {
+			t.Fatalf("Shadowed = %v, want [user user-codex system]", shadowed)
+		}
+	}
+}
+
+func TestNewScanner_Config(t *testing.T) {
+	home, work := t.TempDir(), t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("AGENVOY_SKILL_PATHS", "")
+	t.Chdir(work)
+
+	dir := filepath.Join(work, ".config", "agenvoy")
+	os.MkdirAll(dir, 0755)
+	config := `{"skills": {"paths": ["./custom", "~/shared"], "defaults": false}}`
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeSkill(t, filepath.Join(work, "custom"), "alpha", "first")
+	os.MkdirAll(filepath.Join(work, "custom", "broken"), 0755)
+	os.WriteFile(filepath.Join(work, "custom", "broken", "SKILL.md"), []byte("---\nname: broken\nmodel: nope\n---\n"), 0644)
+
+	s := NewScanner()
+
+	want := []string{filepath.Join(work, "custom"), filepath.Join(home, "shared")}
+	if len(s.Skills.Paths) != len(want) || s.Skills.Paths[0] != want[0] || s.Skills.Paths[1] != want[1] {
+		t.Fatalf("Paths = %v, want %v", s.Skills.Paths, want)
+	}
+	if skill, ok := s.Get("project:alpha"); !ok || skill.Scope != ScopeProject {
+		t.Errorf("project:alpha = %+v, want project scope", skill)
+	}
+	if len(s.Skills.Invalid) != 1 {
+		t.Errorf("Invalid = %v, want broken skill reported", s.Skills.Invalid)
+	}
+}
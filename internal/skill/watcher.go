@@ -0,0 +1,160 @@
+package skill
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	DefaultWatchInterval = 2 * time.Second
+)
+
+type ReloadData struct {
+	Added   []string
+	Changed []string
+	Removed []string
+}
+
+type fileStamp struct {
+	root    string
+	modTime time.Time
+	size    int64
+}
+
+// Watch polls every scanner path and swaps Skills when a SKILL.md really changes.
+func (s *Scanner) Watch(ctx context.Context, interval time.Duration) <-chan ReloadData {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	ch := make(chan ReloadData, 1)
+	go func() {
+		defer close(ch)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		last := s.stat()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			current := s.stat()
+			if maps.Equal(last, current) {
+				continue
+			}
+
+			reload, ok := s.reload(last, current)
+			last = current
+			if !ok {
+				continue
+			}
+
+			select {
+			case ch <- reload:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// * stat only, parsing happens for the skill folders whose stamp moved
+func (s *Scanner) stat() map[string]fileStamp {
+	stamps := make(map[string]fileStamp)
+	for _, root := range s.paths {
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if !e.IsDir() || e.Name()[0] == '.' {
+				continue
+			}
+			path, err := filepath.Abs(filepath.Join(root, e.Name(), "SKILL.md"))
+			if err != nil {
+				continue
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			stamps[path] = fileStamp{root: root, modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return stamps
+}
+
+// * holds the write lock throughout, a concurrent Scan or reload never works from a stale list
+func (s *Scanner) reload(last, current map[string]fileStamp) (ReloadData, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old := s.Skills
+	byPath := make(map[string]*Skill, len(current))
+	invalid := make(map[string]string)
+	for path, stamp := range current {
+		prev, exist := old.ByPath[path]
+		if last[path] == stamp {
+			if exist {
+				byPath[path] = prev
+				continue
+			}
+			if msg, ok := old.Invalid[path]; ok {
+				invalid[path] = msg
+				continue
+			}
+		}
+
+		skill, err := parser(path)
+		if err != nil {
+			slog.Warn("failed to parse skill",
+				slog.String("path", path),
+				slog.String("error", err.Error()))
+			invalid[path] = err.Error()
+			continue
+		}
+		skill.Scope = ScopeOf(stamp.root)
+		// * touched but same content keeps the old pointer
+		if exist && prev.Hash == skill.Hash {
+			skill = prev
+		}
+		byPath[path] = skill
+	}
+	list := newSkillList(s.paths, byPath, invalid)
+
+	var reload ReloadData
+	for name, skill := range list.ByName {
+		prev, ok := old.ByName[name]
+		switch {
+		case !ok:
+			reload.Added = append(reload.Added, name)
+		case prev.Hash != skill.Hash || prev.AbsPath != skill.AbsPath:
+			reload.Changed = append(reload.Changed, name)
+		}
+	}
+	for name := range old.ByName {
+		if _, ok := list.ByName[name]; !ok {
+			reload.Removed = append(reload.Removed, name)
+		}
+	}
+
+	// * invalid entries may have moved even when no usable skill did
+	s.Skills = list
+	if len(reload.Added)+len(reload.Changed)+len(reload.Removed) == 0 {
+		return ReloadData{}, false
+	}
+	sort.Strings(reload.Added)
+	sort.Strings(reload.Changed)
+	sort.Strings(reload.Removed)
+	return reload, true
+}
@@ -0,0 +1,102 @@
+package skill
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func writeSkill(t *testing.T, root, name, desc string) string {
+	t.Helper()
+	dir := filepath.Join(root, name)
+	os.MkdirAll(dir, 0755)
+	path := filepath.Join(dir, "SKILL.md")
+	if err := os.WriteFile(path, []byte("---\nname: "+name+"\ndescription: "+desc+"\n---\nbody"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func nextReload(t *testing.T, ch <-chan ReloadData) ReloadData {
+	t.Helper()
+	select {
+	case r := <-ch:
+		return r
+	case <-time.After(2 * time.Second):
+		t.Fatal("no reload event")
+		return ReloadData{}
+	}
+}
+
+func TestScanner_Watch(t *testing.T) {
+	root := t.TempDir()
+	path := writeSkill(t, root, "alpha", "first")
+
+	s := &Scanner{paths: []string{root}}
+	s.Scan()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := s.Watch(ctx, 10*time.Millisecond)
+
+	// * readers keep going while the watcher swaps the list
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for ctx.Err() == nil {
+			s.Get("alpha")
+			s.List()
+		}
+	}()
+
+	// * same content with a new mtime is not a change
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(path, future, future)
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case r := <-ch:
+		t.Fatalf("touch should not reload, got %+v", r)
+	default:
+	}
+
+	writeSkill(t, root, "alpha", "second")
+	r := nextReload(t, ch)
+	if len(r.Changed) != 1 || r.Changed[0] != "alpha" {
+		t.Errorf("reload = %+v, want alpha changed", r)
+	}
+	if skill, _ := s.Get("alpha"); skill.Description != "second" || skill.Scope == "" {
+		t.Errorf("skill = %+v, want second with its scope", skill)
+	}
+
+	writeSkill(t, root, "beta", "new")
+	if r := nextReload(t, ch); len(r.Added) != 1 || r.Added[0] != "beta" {
+		t.Errorf("reload = %+v, want beta added", r)
+	}
+
+	// * a broken header removes the skill and is reported as invalid
+	broken := filepath.Join(root, "beta", "SKILL.md")
+	os.WriteFile(broken, []byte("---\nname: beta\ndescription: Use when: broken\n---\nbody"), 0644)
+	if r := nextReload(t, ch); len(r.Removed) != 1 || r.Removed[0] != "beta" {
+		t.Errorf("reload = %+v, want beta removed", r)
+	}
+	if _, ok := s.Snapshot().Invalid[broken]; !ok {
+		t.Errorf("Invalid = %v, want the broken skill", s.Snapshot().Invalid)
+	}
+
+	os.RemoveAll(filepath.Join(root, "alpha"))
+	if r := nextReload(t, ch); len(r.Removed) != 1 || r.Removed[0] != "alpha" {
+		t.Errorf("reload = %+v, want alpha removed", r)
+	}
+	if _, ok := s.Get("alpha"); ok {
+		t.Error("removed skill still listed")
+	}
+
+	cancel()
+	wg.Wait()
+	for range ch {
+	}
+}
@@ -3,12 +3,15 @@ package apiAdapter
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"strings"
 	"time"
+
+	"github.com/pardnchiu/agenvoy/internal/credential"
 )
 
 func (t *Translator) Execute(name string, params map[string]any) (string, error) {
@@ -111,9 +114,17 @@ func (t *Translator) insetAuth(req *http.Request, auth *APIDocumentAuthData) err
 		return fmt.Errorf("auth.env is required")
 	}
 
+	// * a secret saved with auth login under the same name stands in for the env var
 	value := os.Getenv(auth.Env)
 	if value == "" {
-		return fmt.Errorf("%q not set", auth.Env)
+		stored, err := credential.Get(auth.Env)
+		if errors.Is(err, credential.ErrNotFound) {
+			return fmt.Errorf("%q not set", auth.Env)
+		}
+		if err != nil {
+			return fmt.Errorf("credential.Get: %w", err)
+		}
+		value = stored
 	}
 
 	switch auth.Type {
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -118,9 +119,16 @@ func (t *Translator) IsExist(name string) bool {
 }
 
 func (t *Translator) GetTools() []map[string]any {
-	tools := make([]map[string]any, 0, len(t.apis))
-	for _, api := range t.apis {
-		tools = append(tools, api.translate())
+	// * map order changes between runs, a stable order keeps the prompt prefix cacheable
+	names := make([]string, 0, len(t.apis))
+	for name := range t.apis {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tools := make([]map[string]any, 0, len(names))
+	for _, name := range names {
+		tools = append(tools, t.apis[name].translate())
 	}
 	return tools
 }
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"strings"
 
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
 	"github.com/pardnchiu/agenvoy/internal/tools/apiAdapter"
 	"github.com/pardnchiu/agenvoy/internal/tools/apis"
 	"github.com/pardnchiu/agenvoy/internal/tools/apis/searchWeb"
@@ -87,8 +88,27 @@ func normalizeArgs(args json.RawMessage) json.RawMessage {
 	return args
 }
 
+// ExecuteMedia runs a tool like Execute, tools that load media also return it as a content part.
+func ExecuteMedia(ctx context.Context, e *toolTypes.Executor, name string, args json.RawMessage) (string, *agentTypes.ContentPart, error) {
+	if _, ok := e.Handlers[name]; name != "read_image" || ok {
+		result, err := Execute(ctx, e, name, args)
+		return result, nil, err
+	}
+
+	var params struct {
+		Path string `json:"path"`
+	}
+	if err := json.Unmarshal(normalizeArgs(args), &params); err != nil {
+		return "", nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+	return file.ReadImage(e, params.Path)
+}
+
 func Execute(ctx context.Context, e *toolTypes.Executor, name string, args json.RawMessage) (string, error) {
 	args = normalizeArgs(args)
+	if handler, ok := e.Handlers[name]; ok {
+		return handler(ctx, args)
+	}
 	// * get all api tools
 	if strings.HasPrefix(name, "api_") && e.APIToolbox != nil && e.APIToolbox.IsExist(name) {
 		var params map[string]any
@@ -99,7 +119,7 @@ func Execute(ctx context.Context, e *toolTypes.Executor, name string, args json.
 	}
 
 	switch name {
-	case "read_file", "list_files", "glob_files", "search_content", "search_history", "write_file", "patch_edit":
+	case "read_file", "read_image", "list_files", "glob_files", "search_content", "search_history", "write_file", "patch_edit":
 		return file.Routes(e, name, args)
 
 	case "send_http_request", "fetch_yahoo_finance", "fetch_google_rss", "fetch_weather":
@@ -0,0 +1,92 @@
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	toolTypes "github.com/pardnchiu/agenvoy/internal/tools/types"
+)
+
+// Network returns deterministic offline stand-ins for every tool that reaches the network.
+func Network() map[string]toolTypes.Handler {
+	return map[string]toolTypes.Handler{
+		"search_web": func(_ context.Context, args json.RawMessage) (string, error) {
+			var params struct {
+				Query string `json:"query"`
+			}
+			if err := json.Unmarshal(args, &params); err != nil {
+				return "", fmt.Errorf("json.Unmarshal: %w", err)
+			}
+			if strings.TrimSpace(params.Query) == "" {
+				return "", fmt.Errorf("query is required")
+			}
+			return fmt.Sprintf("1. %s - Example\n   URL: https://example.com/search?q=%s\n   Snippet: Fake search result for %s\n",
+				params.Query, url.QueryEscape(params.Query), params.Query), nil
+		},
+
+		"fetch_page": func(_ context.Context, args json.RawMessage) (string, error) {
+			var params struct {
+				URL string `json:"url"`
+			}
+			if err := json.Unmarshal(args, &params); err != nil {
+				return "", fmt.Errorf("json.Unmarshal: %w", err)
+			}
+			if _, err := url.ParseRequestURI(params.URL); err != nil {
+				return "", fmt.Errorf("url.ParseRequestURI: %w", err)
+			}
+			return fmt.Sprintf("# Example Page\n\nFake content of %s\n", params.URL), nil
+		},
+
+		"fetch_yahoo_finance": func(_ context.Context, args json.RawMessage) (string, error) {
+			var params struct {
+				Symbol string `json:"symbol"`
+			}
+			if err := json.Unmarshal(args, &params); err != nil {
+				return "", fmt.Errorf("json.Unmarshal: %w", err)
+			}
+			return fmt.Sprintf(`{"symbol":%q,"currency":"USD","price":100.00,"change":1.25,"change_percent":1.27}`, params.Symbol), nil
+		},
+
+		"fetch_google_rss": func(_ context.Context, args json.RawMessage) (string, error) {
+			var params struct {
+				Keyword string `json:"keyword"`
+			}
+			if err := json.Unmarshal(args, &params); err != nil {
+				return "", fmt.Errorf("json.Unmarshal: %w", err)
+			}
+			return fmt.Sprintf("1. %s headline - Example News\n   URL: https://example.com/news/1\n   Time: 2026-01-01 00:00\n", params.Keyword), nil
+		},
+
+		"fetch_weather": func(_ context.Context, args json.RawMessage) (string, error) {
+			var params struct {
+				City string `json:"city"`
+			}
+			if err := json.Unmarshal(args, &params); err != nil {
+				return "", fmt.Errorf("json.Unmarshal: %w", err)
+			}
+			city := params.City
+			if city == "" {
+				city = "Taipei"
+			}
+			return fmt.Sprintf(`{"city":%q,"condition":"Sunny","temperature_c":25,"humidity":60}`, city), nil
+		},
+
+		"send_http_request": func(_ context.Context, args json.RawMessage) (string, error) {
+			var params struct {
+				URL    string `json:"url"`
+				Method string `json:"method"`
+			}
+			if err := json.Unmarshal(args, &params); err != nil {
+				return "", fmt.Errorf("json.Unmarshal: %w", err)
+			}
+			method := strings.ToUpper(params.Method)
+			if method == "" {
+				method = "GET"
+			}
+			return fmt.Sprintf(`{"status":200,"method":%q,"url":%q,"body":{"ok":true}}`, method, params.URL), nil
+		},
+	}
+}
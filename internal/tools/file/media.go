@@ -0,0 +1,83 @@
+package file
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	agentTypes "github.com/pardnchiu/agenvoy/internal/agents/types"
+	toolTypes "github.com/pardnchiu/agenvoy/internal/tools/types"
+)
+
+// * providers reject larger inline media, base64 adds another third on top
+const maxMediaSize = 5 << 20
+
+var mediaTypes = map[string]string{
+	"image/png":       agentTypes.PartImage,
+	"image/jpeg":      agentTypes.PartImage,
+	"image/gif":       agentTypes.PartImage,
+	"image/webp":      agentTypes.PartImage,
+	"application/pdf": agentTypes.PartFile,
+}
+
+// LoadMedia reads an image or pdf into a content part, the type is sniffed from the data.
+func LoadMedia(path string) (*agentTypes.ContentPart, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.Stat: %w", err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+	if info.Size() > maxMediaSize {
+		return nil, fmt.Errorf("%s is %d bytes, over the %d bytes limit", path, info.Size(), maxMediaSize)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile: %w", err)
+	}
+	mediaType := http.DetectContentType(data)
+	partType, ok := mediaTypes[mediaType]
+	if !ok {
+		return nil, fmt.Errorf("%s is %s, only png, jpeg, gif, webp and pdf are supported", path, mediaType)
+	}
+
+	return &agentTypes.ContentPart{
+		Type:      partType,
+		MediaType: mediaType,
+		Data:      base64.StdEncoding.EncodeToString(data),
+		Name:      filepath.Base(path),
+	}, nil
+}
+
+// ReadImage loads an image for the model to look at, the text describes what was loaded.
+func ReadImage(e *toolTypes.Executor, path string) (string, *agentTypes.ContentPart, error) {
+	fullPath := getFullPath(e, path)
+
+	if isExclude(e, fullPath) {
+		return "", nil, fmt.Errorf("path is excluded: %s", path)
+	}
+
+	part, err := LoadMedia(fullPath)
+	if err != nil {
+		return "", nil, fmt.Errorf("LoadMedia: %w", err)
+	}
+	if part.Type != agentTypes.PartImage {
+		return "", nil, fmt.Errorf("%s is not an image", path)
+	}
+
+	data, _ := base64.StdEncoding.DecodeString(part.Data)
+	// * webp has no decoder in the standard library, its size stays unknown
+	if config, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		return fmt.Sprintf("已載入圖片 %s（%s，%dx%d，%d bytes）", path, part.MediaType, config.Width, config.Height, len(data)), part, nil
+	}
+	return fmt.Sprintf("已載入圖片 %s（%s，%d bytes）", path, part.MediaType, len(data)), part, nil
+}
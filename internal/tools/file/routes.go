@@ -18,6 +18,16 @@ func Routes(e *toolTypes.Executor, name string, args json.RawMessage) (string, e
 		}
 		return read(e, params.Path)
 
+	case "read_image":
+		var params struct {
+			Path string `json:"path"`
+		}
+		if err := json.Unmarshal(args, &params); err != nil {
+			return "", fmt.Errorf("json.Unmarshal: %w", err)
+		}
+		text, _, err := ReadImage(e, params.Path)
+		return text, err
+
 	case "list_files":
 		var params struct {
 			Path      string `json:"path"`
@@ -0,0 +1,35 @@
+package tools
+
+import (
+	"path"
+	"strings"
+
+	toolTypes "github.com/pardnchiu/agenvoy/internal/tools/types"
+)
+
+// * keep tools matching any pattern, patterns support globs like api_*
+func Filter(tools []toolTypes.Tool, patterns []string) []toolTypes.Tool {
+	if len(patterns) == 0 {
+		return tools
+	}
+
+	filtered := make([]toolTypes.Tool, 0, len(tools))
+	for _, t := range tools {
+		for _, p := range patterns {
+			if ok, _ := path.Match(strings.TrimSpace(p), t.Function.Name); ok {
+				filtered = append(filtered, t)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+func IsAvailable(e *toolTypes.Executor, name string) bool {
+	for _, t := range e.Tools {
+		if t.Function.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,203 @@
+package script
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pardnchiu/agenvoy/internal/skill"
+	toolTypes "github.com/pardnchiu/agenvoy/internal/tools/types"
+)
+
+const Prefix = "script_"
+
+// * scripts without an interpreter must be executable themselves
+var interpreters = map[string]string{
+	".sh":   "sh",
+	".bash": "bash",
+	".py":   "python3",
+	".js":   "node",
+	".mjs":  "node",
+	".rb":   "ruby",
+}
+
+// * only what a script needs, api keys in the parent env never reach it
+var passEnv = []string{"PATH", "HOME", "LANG", "LC_ALL", "TMPDIR", "TERM"}
+
+// Tools turns the scripts declared by a skill into tools and their handlers.
+func Tools(s *skill.Skill, workPath string) ([]toolTypes.Tool, map[string]toolTypes.Handler) {
+	if s == nil || len(s.Scripts) == 0 {
+		return nil, nil
+	}
+
+	tools := make([]toolTypes.Tool, 0, len(s.Scripts))
+	handlers := make(map[string]toolTypes.Handler, len(s.Scripts))
+	for _, sc := range s.Scripts {
+		name := Prefix + sc.Name
+		tools = append(tools, toolTypes.Tool{
+			Type: "function",
+			Function: toolTypes.ToolFunction{
+				Name:        name,
+				Description: description(s.Name, sc),
+				Parameters:  schema(sc.Arguments),
+			},
+		})
+
+		handlers[name] = func(ctx context.Context, args json.RawMessage) (string, error) {
+			values, err := toValues(args)
+			if err != nil {
+				return "", err
+			}
+			resolved, err := sc.ResolveArgs(values)
+			if err != nil {
+				return "", err
+			}
+			return run(ctx, sc, workPath, resolved)
+		}
+	}
+	return tools, handlers
+}
+
+func description(skillName string, sc skill.Script) string {
+	desc := sc.Description
+	if desc == "" {
+		desc = fmt.Sprintf("執行技能 %s 的腳本 %s", skillName, filepath.Base(sc.Path))
+	}
+	return fmt.Sprintf("%s（技能 %s 提供，逾時 %s）", desc, skillName, sc.Timeout)
+}
+
+func schema(arguments []skill.Argument) json.RawMessage {
+	properties := make(map[string]any, len(arguments))
+	required := []string{}
+	for _, a := range arguments {
+		t := a.Type
+		if t == "" {
+			t = "string"
+		}
+		prop := map[string]any{"type": t}
+		if a.Description != "" {
+			prop["description"] = a.Description
+		}
+		if len(a.Enum) > 0 {
+			prop["enum"] = a.Enum
+		}
+		if a.Default != "" {
+			prop["default"] = a.Default
+		}
+		properties[a.Name] = prop
+		if a.Required && a.Default == "" {
+			required = append(required, a.Name)
+		}
+	}
+
+	data, _ := json.Marshal(map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	})
+	return data
+}
+
+// * models send numbers and booleans as json values, arguments are checked as strings
+func toValues(args json.RawMessage) (map[string]string, error) {
+	values := make(map[string]string)
+	if len(strings.TrimSpace(string(args))) == 0 {
+		return values, nil
+	}
+
+	var raw map[string]any
+	if err := json.Unmarshal(args, &raw); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+	for k, v := range raw {
+		switch value := v.(type) {
+		case nil:
+		case string:
+			values[k] = value
+		case float64:
+			values[k] = strconv.FormatFloat(value, 'f', -1, 64)
+		case bool:
+			values[k] = strconv.FormatBool(value)
+		default:
+			data, _ := json.Marshal(value)
+			values[k] = string(data)
+		}
+	}
+	return values, nil
+}
+
+func run(ctx context.Context, sc skill.Script, workPath string, values map[string]string) (string, error) {
+	// * the path was checked at parse time, check again after symlinks in case the folder changed since
+	realDir, err := filepath.EvalSymlinks(sc.Root)
+	if err != nil {
+		return "", fmt.Errorf("filepath.EvalSymlinks: %w", err)
+	}
+	realPath, err := filepath.EvalSymlinks(sc.Path)
+	if err != nil {
+		return "", fmt.Errorf("filepath.EvalSymlinks: %w", err)
+	}
+	if rel, err := filepath.Rel(realDir, realPath); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("script %s resolves outside the skill folder", sc.Name)
+	}
+
+	interpreter := sc.Interpreter
+	if interpreter == "" {
+		interpreter = interpreters[strings.ToLower(filepath.Ext(realPath))]
+	}
+	binary, args := realPath, []string{}
+	if interpreter != "" {
+		binary, args = interpreter, []string{realPath}
+	}
+
+	dir := workPath
+	if sc.Cwd == skill.ScriptCwdSkill {
+		dir = realDir
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sc.Timeout)
+	defer cancel()
+
+	input, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(string(input))
+	cmd.Env = env(realDir, workPath, values)
+	// * children of a killed script may hold the pipes open, do not wait for them
+	cmd.WaitDelay = time.Second
+
+	output, err := cmd.CombinedOutput()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Sprintf("%s\nError: timed out after %s", string(output), sc.Timeout), nil
+	}
+	if err != nil {
+		return fmt.Sprintf("%s\nError: %s", string(output), err.Error()), nil
+	}
+	return string(output), nil
+}
+
+// * arguments arrive both as ARG_<NAME> and as a json object on stdin
+func env(skillDir, workPath string, values map[string]string) []string {
+	result := make([]string, 0, len(passEnv)+len(values)+2)
+	for _, key := range passEnv {
+		if value, ok := os.LookupEnv(key); ok {
+			result = append(result, key+"="+value)
+		}
+	}
+	result = append(result, "SKILL_PATH="+skillDir, "WORK_PATH="+workPath)
+	for name, value := range values {
+		key := "ARG_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		result = append(result, key+"="+value)
+	}
+	return result
+}
@@ -0,0 +1,103 @@
+package script
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pardnchiu/agenvoy/internal/skill"
+)
+
+func newSkill(t *testing.T) *skill.Skill {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "release")
+	os.MkdirAll(filepath.Join(dir, "scripts"), 0755)
+	os.WriteFile(filepath.Join(dir, "scripts", "bump.sh"),
+		[]byte("echo \"level=$ARG_LEVEL dry=$ARG_DRY_RUN key=$SECRET_KEY\"\npwd\ncat\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "scripts", "slow.sh"), []byte("sleep 5\n"), 0644)
+
+	return &skill.Skill{
+		Name:    "release",
+		AbsPath: filepath.Join(dir, "SKILL.md"),
+		Scripts: []skill.Script{
+			{
+				Name:    "bump",
+				Path:    filepath.Join(dir, "scripts", "bump.sh"),
+				Root:    dir,
+				Cwd:     skill.ScriptCwdWork,
+				Timeout: skill.DefaultScriptTimeout,
+				Arguments: []skill.Argument{
+					{Name: "level", Enum: []string{"major", "minor", "patch"}, Required: true},
+					{Name: "dry-run", Type: "boolean", Default: "false"},
+				},
+			},
+			{
+				Name:    "slow",
+				Path:    filepath.Join(dir, "scripts", "slow.sh"),
+				Root:    dir,
+				Cwd:     skill.ScriptCwdSkill,
+				Timeout: 100 * time.Millisecond,
+			},
+		},
+	}
+}
+
+func TestTools(t *testing.T) {
+	t.Setenv("SECRET_KEY", "leaked")
+	work := t.TempDir()
+	tools, handlers := Tools(newSkill(t), work)
+
+	if len(tools) != 2 || tools[0].Function.Name != "script_bump" {
+		t.Fatalf("tools = %+v", tools)
+	}
+	var schema struct {
+		Properties map[string]map[string]any `json:"properties"`
+		Required   []string                  `json:"required"`
+	}
+	json.Unmarshal(tools[0].Function.Parameters, &schema)
+	if schema.Properties["dry-run"]["type"] != "boolean" || len(schema.Required) != 1 || schema.Required[0] != "level" {
+		t.Errorf("schema = %s", tools[0].Function.Parameters)
+	}
+
+	ctx := context.Background()
+	out, err := handlers["script_bump"](ctx, json.RawMessage(`{"level":"minor","dry-run":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"level=minor dry=true key=\n", work + "\n", `"level":"minor"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output %q missing %q", out, want)
+		}
+	}
+
+	if _, err := handlers["script_bump"](ctx, json.RawMessage(`{"level":"huge"}`)); err == nil {
+		t.Error("enum violation should fail before running")
+	}
+	if _, err := handlers["script_bump"](ctx, json.RawMessage(`{}`)); err == nil {
+		t.Error("missing required argument should fail")
+	}
+
+	out, err = handlers["script_slow"](ctx, nil)
+	if err != nil || !strings.Contains(out, "timed out after 100ms") {
+		t.Errorf("slow = %q, %v", out, err)
+	}
+}
+
+func TestTools_Symlink(t *testing.T) {
+	s := newSkill(t)
+	outside := filepath.Join(t.TempDir(), "evil.sh")
+	os.WriteFile(outside, []byte("echo evil\n"), 0644)
+	os.Remove(s.Scripts[0].Path)
+	if err := os.Symlink(outside, s.Scripts[0].Path); err != nil {
+		t.Skip("symlink not supported")
+	}
+
+	_, handlers := Tools(s, t.TempDir())
+	if _, err := handlers["script_bump"](context.Background(), json.RawMessage(`{"level":"major"}`)); err == nil {
+		t.Error("script linked outside the skill folder should not run")
+	}
+}
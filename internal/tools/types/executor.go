@@ -1,6 +1,7 @@
 package toolTypes
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/pardnchiu/agenvoy/internal/tools/apiAdapter"
@@ -14,6 +15,7 @@ type Executor struct {
 	Exclude        []Exclude
 	Tools          []Tool
 	APIToolbox     *apiAdapter.Translator
+	Handlers       map[string]Handler // tools that only exist for this run or replace a built-in, e.g. skill scripts
 }
 
 type Exclude struct {
@@ -31,3 +33,6 @@ type ToolFunction struct {
 	Description string          `json:"description"`
 	Parameters  json.RawMessage `json:"parameters"`
 }
+
+// * runs one tool call, set in Executor.Handlers by skill scripts and offline test fakes
+type Handler func(ctx context.Context, args json.RawMessage) (string, error)